@@ -0,0 +1,142 @@
+// Package cloudflare purges cached content from the Cloudflare zone in
+// front of lcc.live
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBaseURL = "https://api.cloudflare.com/client/v4"
+	requestTimeout = 10 * time.Second
+)
+
+// Client purges cache entries from a single zone
+type Client struct {
+	zoneID   string
+	apiToken string
+	baseURL  string
+	client   *http.Client
+}
+
+// NewClient creates a client for the given zone
+func NewClient(zoneID, apiToken string) *Client {
+	return &Client{
+		zoneID:   zoneID,
+		apiToken: apiToken,
+		baseURL:  defaultBaseURL,
+		client:   &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// NewClientFromEnv creates a client from CLOUDFLARE_ZONE_ID and
+// CLOUDFLARE_API_TOKEN, returning nil if either is unset
+func NewClientFromEnv() *Client {
+	zoneID := os.Getenv("CLOUDFLARE_ZONE_ID")
+	apiToken := os.Getenv("CLOUDFLARE_API_TOKEN")
+	if zoneID == "" || apiToken == "" {
+		return nil
+	}
+	return NewClient(zoneID, apiToken)
+}
+
+// SetBaseURL points the client at a different API endpoint (for tests)
+func (c *Client) SetBaseURL(baseURL string) {
+	c.baseURL = baseURL
+}
+
+// ZoneID returns the zone the client purges
+func (c *Client) ZoneID() string {
+	return c.zoneID
+}
+
+// APIError is an error reported by the Cloudflare API
+type APIError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// PurgeResult is the Cloudflare API's response to a purge request
+type PurgeResult struct {
+	Success bool            `json:"success"`
+	Errors  []APIError      `json:"errors"`
+	Result  json.RawMessage `json:"result,omitempty"`
+}
+
+// PurgeEverything purges the whole zone
+func (c *Client) PurgeEverything(ctx context.Context) (*PurgeResult, error) {
+	return c.purge(ctx, map[string]any{"purge_everything": true})
+}
+
+// PurgeTags purges every response carrying one of the given Cache-Tag
+// values
+func (c *Client) PurgeTags(ctx context.Context, tags []string) (*PurgeResult, error) {
+	return c.purge(ctx, map[string]any{"tags": tags})
+}
+
+// CanyonTag is the Cache-Tag set on a canyon's pages and images, so they can
+// be purged without touching other canyons
+func CanyonTag(canyonID string) string {
+	return "canyon-" + strings.ToLower(canyonID)
+}
+
+// CameraTag is the Cache-Tag set on a camera's images and pages, so one
+// camera's objects can be purged alone. It uses the camera's slug rather
+// than its ID: IDs are case-sensitive base64, and Cloudflare matches tags
+// case-insensitively.
+func CameraTag(cameraSlug string) string {
+	return "camera-" + strings.ToLower(cameraSlug)
+}
+
+// PurgeURLs purges the given absolute URLs
+func (c *Client) PurgeURLs(ctx context.Context, urls []string) (*PurgeResult, error) {
+	return c.purge(ctx, map[string]any{"files": urls})
+}
+
+func (c *Client) purge(ctx context.Context, payload map[string]any) (*PurgeResult, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf("%s/zones/%s/purge_cache", c.baseURL, c.zoneID), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var result PurgeResult
+	if err := json.Unmarshal(responseBody, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response (status %d): %w", resp.StatusCode, err)
+	}
+	if !result.Success {
+		return &result, fmt.Errorf("cache purge failed: %v", result.Errors)
+	}
+	return &result, nil
+}
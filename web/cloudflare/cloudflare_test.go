@@ -0,0 +1,108 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_PurgeURLs(t *testing.T) {
+	var got map[string]any
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/zones/zone-1/purge_cache", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		got = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Write([]byte(`{"success":true,"errors":[],"result":{"id":"zone-1"}}`))
+	}))
+	defer api.Close()
+
+	client := NewClient("zone-1", "secret")
+	client.SetBaseURL(api.URL)
+
+	result, err := client.PurgeURLs(context.Background(), []string{"https://lcc.live/image/a", "https://lcc.live/camera/a"})
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.JSONEq(t, `{"id":"zone-1"}`, string(result.Result))
+	assert.Equal(t, map[string]any{"files": []any{"https://lcc.live/image/a", "https://lcc.live/camera/a"}}, got)
+
+	_, err = client.PurgeEverything(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"purge_everything": true}, got)
+}
+
+func TestClient_PurgeTags(t *testing.T) {
+	var got map[string]any
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/zones/zone-1/purge_cache", r.URL.Path)
+		got = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Write([]byte(`{"success":true,"errors":[],"result":{"id":"zone-1"}}`))
+	}))
+	defer api.Close()
+
+	client := NewClient("zone-1", "secret")
+	client.SetBaseURL(api.URL)
+
+	_, err := client.PurgeTags(context.Background(), []string{CanyonTag("LCC")})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"tags": []any{"canyon-lcc"}}, got)
+}
+
+func TestClient_PurgeFailure(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"success":false,"errors":[{"code":1012,"message":"Request must contain one of \"purge_everything\" or \"files\""}]}`))
+	}))
+	defer api.Close()
+
+	client := NewClient("zone-1", "secret")
+	client.SetBaseURL(api.URL)
+
+	result, err := client.PurgeURLs(context.Background(), nil)
+	require.Error(t, err)
+	require.NotNil(t, result)
+	assert.False(t, result.Success)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, 1012, result.Errors[0].Code)
+}
+
+func TestNewClientFromEnv(t *testing.T) {
+	t.Setenv("CLOUDFLARE_ZONE_ID", "")
+	t.Setenv("CLOUDFLARE_API_TOKEN", "secret")
+	assert.Nil(t, NewClientFromEnv())
+
+	t.Setenv("CLOUDFLARE_ZONE_ID", "zone-1")
+	client := NewClientFromEnv()
+	require.NotNil(t, client)
+	assert.Equal(t, "zone-1", client.ZoneID())
+}
+
+func TestIPRanges(t *testing.T) {
+	ranges := IPRanges()
+	require.Len(t, ranges, len(ipRanges))
+	contains := func(ip string) bool {
+		addr := netip.MustParseAddr(ip)
+		for _, prefix := range ranges {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+	assert.True(t, contains("104.16.1.1"))
+	assert.True(t, contains("2606:4700::1"))
+	assert.False(t, contains("203.0.113.7"))
+}
+
+func TestCacheTags(t *testing.T) {
+	assert.Equal(t, "canyon-lcc", CanyonTag("LCC"))
+	assert.Equal(t, "camera-alta-bypass", CameraTag("Alta-Bypass"))
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"text/tabwriter"
+	"time"
+
+	"github.com/stefanpenner/lcc-live/web/metrics"
+	"github.com/stefanpenner/lcc-live/web/server"
+	"github.com/stefanpenner/lcc-live/web/store"
+	"golang.org/x/sync/errgroup"
+)
+
+// doctorResult is one camera's row in the doctor report
+type doctorResult struct {
+	Camera      string
+	Canyon      string
+	Origin      string
+	Status      int // the origin's HTTP status; 0 if it never answered
+	ContentType string
+	Size        int
+	Latency     time.Duration
+	Err         error
+}
+
+// Reachable reports whether the origin answered at all
+func (r doctorResult) Reachable() bool {
+	return r.Status != 0
+}
+
+// runDoctor loads the cameras as the server would, fetches each one once and
+// writes a per-camera report to w, without starting the server. It returns
+// the exit code: 1 if the cameras can't be loaded, or if at least
+// DegradedThreshold of them failed (the share at which a running server
+// would report itself degraded).
+func runDoctor(ctx context.Context, config Config, w io.Writer) int {
+	s, err := loadStore(ctx, config)
+	if err != nil {
+		fmt.Fprintf(w, "failed to load cameras: %v\n", err)
+		return 1
+	}
+
+	results := probeCameras(ctx, s, config.StoreOptions.FetchConcurrency)
+	if len(results) == 0 {
+		fmt.Fprintln(w, "no fetchable cameras configured")
+		return 1
+	}
+	printDoctorReport(w, results)
+
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	threshold := config.DegradedThreshold
+	if threshold <= 0 {
+		threshold = server.DefaultDegradedThreshold
+	}
+	fmt.Fprintf(w, "\n%d of %d cameras ok\n", len(results)-failed, len(results))
+	if float64(failed)/float64(len(results)) >= threshold {
+		return 1
+	}
+	return 0
+}
+
+// probeCameras fetches every enabled, fetchable camera once, at most
+// concurrency at a time (zero uses the store default), returning the
+// results in configuration order. Cameras with an invalid URL aren't
+// fetched.
+func probeCameras(ctx context.Context, s *store.Store, concurrency int) []doctorResult {
+	if concurrency <= 0 {
+		concurrency = store.DefaultFetchConcurrency
+	}
+
+	var entries []store.EntrySnapshot
+	for _, entry := range s.Entries() {
+		if entry.Camera.IsFetchable() && entry.Camera.IsEnabled() {
+			entries = append(entries, entry)
+		}
+	}
+
+	results := make([]doctorResult, len(entries))
+	g := errgroup.Group{}
+	g.SetLimit(concurrency)
+	for i, entry := range entries {
+		results[i] = doctorResult{
+			Camera: entry.Camera.Alt,
+			Canyon: entry.Camera.Canyon,
+			Origin: metrics.ExtractOrigin(entry.Camera.Src),
+		}
+		if err := validateCameraURL(entry.Camera.Src); err != nil {
+			results[i].Err = err
+			continue
+		}
+		g.Go(func() error {
+			start := time.Now()
+			probe, err := s.Probe(ctx, entry.ID)
+			results[i].Latency = time.Since(start)
+			results[i].Status = probe.HTTPStatus
+			results[i].Err = err
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	// Fill in what was stored for the cameras that fetched. Entries, unlike
+	// Get, doesn't wait for a FetchImages that never runs here.
+	stored := make(map[string]store.EntrySnapshot)
+	for _, snapshot := range s.Entries() {
+		stored[snapshot.ID] = snapshot
+	}
+	for i, entry := range entries {
+		snapshot, ok := stored[entry.ID]
+		if results[i].Err != nil || !ok || snapshot.Image == nil {
+			continue
+		}
+		results[i].ContentType = snapshot.HTTPHeaders.ContentType
+		results[i].Size = len(snapshot.Image.Bytes)
+	}
+	return results
+}
+
+// validateCameraURL rejects camera URLs the fetcher could never use
+func validateCameraURL(src string) error {
+	u, err := url.Parse(src)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid URL %q: scheme must be http or https", src)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid URL %q: missing host", src)
+	}
+	return nil
+}
+
+// printDoctorReport writes results as an aligned table, one camera per row
+func printDoctorReport(w io.Writer, results []doctorResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESULT\tCANYON\tCAMERA\tORIGIN\tREACHABLE\tSTATUS\tCONTENT-TYPE\tSIZE\tLATENCY\tERROR")
+	for _, r := range results {
+		verdict, status, errText := "ok", "-", ""
+		if r.Err != nil {
+			verdict, errText = "FAIL", r.Err.Error()
+		}
+		if r.Status != 0 {
+			status = fmt.Sprint(r.Status)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%t\t%s\t%s\t%d\t%s\t%s\n",
+			verdict, r.Canyon, r.Camera, r.Origin, r.Reachable(), status,
+			r.ContentType, r.Size, r.Latency.Round(time.Millisecond), errText)
+	}
+	_ = tw.Flush()
+}
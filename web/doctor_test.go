@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunDoctor(t *testing.T) {
+	var frame bytes.Buffer
+	require.NoError(t, jpeg.Encode(&frame, image.NewGray(image.Rect(0, 0, 1, 1)), nil))
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down.jpg" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == http.MethodGet {
+			w.Write(frame.Bytes())
+		}
+	}))
+	t.Cleanup(origin.Close)
+
+	writeData := func(t *testing.T, srcs ...string) string {
+		cameras := ""
+		for i, src := range srcs {
+			if i > 0 {
+				cameras += ","
+			}
+			cameras += fmt.Sprintf(`{"kind": "img", "src": %q, "alt": "Cam %d"}`, src, i)
+		}
+		dataFile := filepath.Join(t.TempDir(), "data.json")
+		data := `{"lcc": {"id": "LCC", "name": "LCC", "cameras": [` + cameras + `]}}`
+		require.NoError(t, os.WriteFile(dataFile, []byte(data), 0o644))
+		return dataFile
+	}
+
+	t.Run("healthy", func(t *testing.T) {
+		dataFile := writeData(t, origin.URL+"/a.jpg", origin.URL+"/b.jpg", origin.URL+"/c.jpg", origin.URL+"/down.jpg")
+		var out bytes.Buffer
+		code := runDoctor(context.Background(), Config{DataFile: dataFile, DegradedThreshold: 0.5}, &out)
+
+		report := out.String()
+		assert.Equal(t, 0, code, report)
+		assert.Contains(t, report, "3 of 4 cameras ok")
+		assert.Regexp(t, `ok\s+LCC\s+Cam 0\s+\S+\s+true\s+200\s+image/jpeg\s+`+fmt.Sprint(frame.Len()), report)
+		assert.Regexp(t, `FAIL\s+LCC\s+Cam 3\s+\S+\s+true\s+502\s+0\s+\S+\s+.*status 502`, report)
+	})
+
+	t.Run("too many failures", func(t *testing.T) {
+		dataFile := writeData(t, origin.URL+"/a.jpg", origin.URL+"/down.jpg", "ftp://example.com/cam.jpg")
+		var out bytes.Buffer
+		code := runDoctor(context.Background(), Config{DataFile: dataFile, DegradedThreshold: 0.5}, &out)
+
+		report := out.String()
+		assert.Equal(t, 1, code, report)
+		assert.Contains(t, report, "1 of 3 cameras ok")
+		assert.Regexp(t, `FAIL\s+LCC\s+Cam 2\s+\S*\s+false\s+-.*scheme must be http or https`, report)
+	})
+
+	t.Run("unloadable config", func(t *testing.T) {
+		var out bytes.Buffer
+		code := runDoctor(context.Background(), Config{DataFile: filepath.Join(t.TempDir(), "missing.json")}, &out)
+		assert.Equal(t, 1, code)
+		assert.Contains(t, out.String(), "failed to load cameras")
+	})
+}
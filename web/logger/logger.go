@@ -3,8 +3,10 @@ package logger
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
@@ -167,12 +169,19 @@ func Section(title string) {
 // Log is the interface for sending logs (will be set by main if using UI)
 var Log func(string)
 
+// Tee, if set, also receives every message, wherever it was printed (e.g. to
+// stream logs from a headless deployment)
+var Tee func(string)
+
 func logOrPrint(msg string) {
 	if Log != nil && useUI {
 		Log(msg)
 	} else {
 		fmt.Println(msg)
 	}
+	if Tee != nil {
+		Tee(msg)
+	}
 }
 
 // Info prints an info message
@@ -294,6 +303,36 @@ func SetSentryCaptureException(fn func(error) interface{}) {
 	captureException = fn
 }
 
+// debugEnabled gates Debug; the default level is info
+var debugEnabled atomic.Bool
+
+// SetLevel sets the minimum level logged: "debug" or "info" (the default)
+func SetLevel(level string) error {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		debugEnabled.Store(true)
+	case "", "info":
+		debugEnabled.Store(false)
+	default:
+		return fmt.Errorf("unknown log level %q", level)
+	}
+	return nil
+}
+
+// DebugEnabled reports whether Debug messages are logged
+func DebugEnabled() bool {
+	return debugEnabled.Load()
+}
+
+// Debug prints a muted message, only when the level is debug
+func Debug(format string, args ...interface{}) {
+	if !debugEnabled.Load() {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	logOrPrint(mutedStyle.Render("  · " + msg))
+}
+
 // Muted prints a muted/debug message
 func Muted(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
@@ -389,3 +428,13 @@ func Shutdown() {
 func HTTPLogger() *log.Logger {
 	return httpLogger
 }
+
+// NewJSONLogger returns a logger that writes one JSON object per record, for
+// log pipelines that can't parse the styled output
+func NewJSONLogger(w io.Writer) *log.Logger {
+	return log.NewWithOptions(w, log.Options{
+		ReportTimestamp: true,
+		TimeFormat:      time.RFC3339Nano,
+		Formatter:       log.JSONFormatter,
+	})
+}
@@ -2,43 +2,121 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"io/fs"
 	"log"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/getsentry/sentry-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stefanpenner/lcc-live/web/cloudflare"
 	"github.com/stefanpenner/lcc-live/web/logger"
+	"github.com/stefanpenner/lcc-live/web/metrics"
 	"github.com/stefanpenner/lcc-live/web/server"
 	"github.com/stefanpenner/lcc-live/web/store"
 	"github.com/stefanpenner/lcc-live/web/udot"
 	"github.com/stefanpenner/lcc-live/web/ui"
+	"github.com/stefanpenner/lcc-live/web/webhook"
 	"golang.org/x/sync/errgroup"
 )
 
 const (
 	defaultSyncInterval      = 3 * time.Second
 	defaultUDOTFetchInterval = 75 * time.Second
+	defaultDataFile          = "data.json"
+	remoteConfigTimeout      = 10 * time.Second
+	// How long a camera must stay up/down before a webhook fires
+	defaultWebhookMinStateDuration = time.Minute
 )
 
 type Config struct {
-	Port         string
-	SyncInterval time.Duration
-	DevMode      bool
-	UDOTAPIKey   string
-	UDOTInterval time.Duration
+	Port          string
+	SyncInterval  time.Duration
+	DevMode       bool
+	UDOTAPIKey    string
+	UDOTInterval  time.Duration
+	ImageCache    bool
+	ImageCacheDir string
+	// Number of recent frames retained per camera (0 disables)
+	TimelapseFrames int
+	UserAgent       string
+	// Fail startup on template errors instead of serving fallback pages
+	StrictTemplates bool
+	// Per-request deadline for UDOT API calls (zero uses the client default)
+	UDOTTimeout time.Duration
+	// Camera up/down notifications (empty URL disables)
+	WebhookURL              string
+	WebhookMinStateDuration time.Duration
+	// Camera fetch timeouts, size cap and concurrency (zero values use store defaults)
+	StoreOptions store.StoreOptions
+	// Re-encode PNG camera frames as JPEG at this quality (0 disables)
+	PNGToJPEGQuality int
+	// Connection pooling for camera fetches (zero values use store defaults)
+	FetchTransport store.TransportOptions
+	// Bearer token for admin endpoints such as POST /_/purge (empty disables them)
+	AdminToken string
+	// Cache-Control lifetimes per route class
+	CachePolicy server.CachePolicy
+	// Client IP allow/deny rules (empty disables filtering)
+	IPFilter server.IPFilter
+	// Host that other hosts 301 to (empty disables the redirect)
+	CanonicalHost string
+	// Access log format: text (styled), json, or both
+	LogFormat string
+	// Minimum log level: debug or info
+	LogLevel string
+	// Canyon data file, relative to the base dir unless absolute
+	DataFile string
+	// How long a handler may run before responding 503
+	RequestTimeout time.Duration
+	// Deadline for the whole shutdown sequence
+	ShutdownTimeout time.Duration
+	// How long /healthcheck reuses its page-render smoke test
+	HealthCheckSmokeTTL time.Duration
+	// How long an unchanged camera image goes before it's reported stale
+	StaleCameraThreshold time.Duration
+
+	// Add Server-Timing headers to responses
+	ServerTiming bool
+
+	// Start in maintenance mode
+	Maintenance bool
+
+	// Largest request body accepted on mutating requests, in bytes
+	BodyLimit int64
+
+	// Fraction of cameras failing a sync that marks the service degraded
+	DegradedThreshold float64
+
+	// Log 1 in N successful requests (0 or 1 logs all)
+	AccessLogSampleRate int
+
+	// Remote camera config, preferred over DataFile when it loads
+	DataURL string
+	// How often to refetch DataURL and reload the cameras (0 disables)
+	DataURLRefresh time.Duration
+
+	// Peers whose X-Forwarded-For is believed (empty believes anyone's)
+	TrustedProxies []netip.Prefix
+	// Write a metrics snapshot to MetricsDumpDir on shutdown
+	MetricsDump    bool
+	MetricsDumpDir string
+	// Which UDOT feeds to poll (all by default)
+	UDOTRoadConditions  bool
+	UDOTWeatherStations bool
+	UDOTEvents          bool
 }
 
 // keepCamerasInSync keeps the local store in-sync with image origins
@@ -58,6 +136,85 @@ func keepCamerasInSync(ctx context.Context, store *store.Store, interval time.Du
 	}
 }
 
+// remoteConfigClient fetches DataURL
+var remoteConfigClient = &http.Client{Timeout: remoteConfigTimeout}
+
+// loadStore builds the store from DataURL if set, falling back to the
+// bundled DataFile if the remote config can't be fetched or is invalid
+func loadStore(ctx context.Context, config Config) (*store.Store, error) {
+	if config.DataURL != "" {
+		canyons, err := store.LoadCanyonsURL(ctx, remoteConfigClient, config.DataURL)
+		if err == nil {
+			var s *store.Store
+			if s, err = store.NewStoreWithError(canyons, config.StoreOptions); err == nil {
+				logger.Info("Loaded cameras from %s", config.DataURL)
+				return s, nil
+			}
+		}
+		logger.Warn("Failed to load cameras from %s, using %s instead: %v", config.DataURL, config.DataFile, err)
+	}
+
+	dataFS, dataFile, err := loadDataFile(config.DataFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load data directory: %w", err)
+	}
+	s, err := store.NewStoreFromFile(dataFS, dataFile, config.StoreOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new store from file %s: %w", config.DataFile, err)
+	}
+	return s, nil
+}
+
+// udotFeed is a UDOT poller main may launch
+type udotFeed struct {
+	name  string
+	start func(ctx context.Context) error
+}
+
+// pollersToStart returns the UDOT pollers enabled in config, in launch order
+func pollersToStart(config Config, p *udot.Poller) []udotFeed {
+	var feeds []udotFeed
+	if config.UDOTRoadConditions {
+		feeds = append(feeds, udotFeed{"road conditions", p.StartRoadConditions})
+	}
+	if config.UDOTWeatherStations {
+		feeds = append(feeds, udotFeed{"weather stations", p.StartWeatherStations})
+	}
+	if config.UDOTEvents {
+		feeds = append(feeds, udotFeed{"events", p.StartEvents})
+	}
+	return feeds
+}
+
+// keepCamerasConfigured refetches the remote camera config every interval
+// and reloads the store when it changes. A failed fetch or an invalid
+// config keeps the current cameras.
+func keepCamerasConfigured(ctx context.Context, s *store.Store, url string, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			canyons, err := store.LoadCanyonsURL(ctx, remoteConfigClient, url)
+			same := false
+			if err == nil {
+				same, err = s.SameConfig(canyons)
+			}
+			if err == nil && !same {
+				if err = s.Reload(canyons); err == nil {
+					logger.Info("Reloaded cameras from %s", url)
+				}
+			}
+			if err != nil && ctx.Err() == nil {
+				logger.Warn("Keeping current cameras, failed to reload from %s: %v", url, err)
+			}
+		}
+	}
+}
+
 func loadConfig() Config {
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -80,18 +237,228 @@ func loadConfig() Config {
 		}
 	}
 
+	var udotTimeout time.Duration
+	if d, err := time.ParseDuration(os.Getenv("UDOT_TIMEOUT")); err == nil && d > 0 {
+		udotTimeout = d
+	}
+
+	// Each UDOT feed can be switched off, e.g. to save API quota
+	udotFeedEnabled := func(name string) bool {
+		value := os.Getenv(name)
+		return value != "0" && value != "false"
+	}
+
 	// Enable dev mode for hot reloading
 	devMode := os.Getenv("DEV_MODE") == "1" || os.Getenv("DEV_MODE") == "true"
 
 	// Get UDOT API key from environment only
 	udotAPIKey := os.Getenv("UDOT_API_KEY")
 
+	// Persist images to disk across restarts
+	imageCache := os.Getenv("IMAGE_CACHE") == "1" || os.Getenv("IMAGE_CACHE") == "true"
+	imageCacheDir := os.Getenv("IMAGE_CACHE_DIR")
+	if imageCacheDir == "" {
+		imageCacheDir = filepath.Join(os.TempDir(), "lcc-live-image-cache")
+	}
+
+	// Snapshot metrics on shutdown for post-mortems
+	metricsDump := os.Getenv("METRICS_DUMP") == "1" || os.Getenv("METRICS_DUMP") == "true"
+	metricsDumpDir := os.Getenv("METRICS_DUMP_DIR")
+	if metricsDumpDir == "" {
+		metricsDumpDir = filepath.Join(os.TempDir(), "lcc-live-metrics")
+	}
+
+	timelapseFrames := 0
+	if n, err := strconv.Atoi(os.Getenv("TIMELAPSE_FRAMES")); err == nil && n > 0 {
+		timelapseFrames = n
+	}
+
+	// Identify ourselves honestly to camera origins and UDOT
+	userAgent := os.Getenv("USER_AGENT")
+	if userAgent == "" {
+		userAgent = "lcc.live/" + server.Version + " (+https://lcc.live)"
+	}
+
+	webhookMinStateDuration := defaultWebhookMinStateDuration
+	if d, err := time.ParseDuration(os.Getenv("WEBHOOK_MIN_STATE_DURATION")); err == nil {
+		webhookMinStateDuration = d
+	}
+
+	storeOptions := store.DefaultStoreOptions()
+	if n, err := strconv.Atoi(os.Getenv("FETCH_CONCURRENCY")); err == nil && n > 0 {
+		storeOptions.FetchConcurrency = n
+	}
+	if d, err := time.ParseDuration(os.Getenv("FETCH_CLIENT_TIMEOUT")); err == nil && d > 0 {
+		storeOptions.HTTPClientTimeout = d
+	}
+	if d, err := time.ParseDuration(os.Getenv("FETCH_HEAD_TIMEOUT")); err == nil && d > 0 {
+		storeOptions.HeadRequestTimeout = d
+	}
+	if d, err := time.ParseDuration(os.Getenv("FETCH_GET_TIMEOUT")); err == nil && d > 0 {
+		storeOptions.GetRequestTimeout = d
+	}
+	if n, err := strconv.ParseInt(os.Getenv("FETCH_MAX_IMAGE_SIZE"), 10, 64); err == nil && n > 0 {
+		storeOptions.MaxImageSize = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("READY_MIN_CAMERAS_PERCENT")); err == nil && n > 0 && n <= 100 {
+		storeOptions.ReadyMinPercent = n
+	}
+	if d, err := time.ParseDuration(os.Getenv("READY_MAX_WAIT")); err == nil && d > 0 {
+		storeOptions.ReadyMaxWait = d
+	}
+	if d, err := time.ParseDuration(os.Getenv("INITIAL_FETCH_TIMEOUT")); err == nil && d > 0 {
+		storeOptions.InitialFetchTimeout = d
+	}
+	storeOptions.VersionedETags = os.Getenv("IMAGE_ETAG_VERSIONS") == "1" || os.Getenv("IMAGE_ETAG_VERSIONS") == "true"
+
+	pngToJPEGQuality := 0
+	if n, err := strconv.Atoi(os.Getenv("PNG_TO_JPEG_QUALITY")); err == nil && n > 0 && n <= 100 {
+		pngToJPEGQuality = n
+	}
+
+	var fetchTransport store.TransportOptions
+	if n, err := strconv.Atoi(os.Getenv("FETCH_MAX_IDLE_CONNS_PER_HOST")); err == nil && n > 0 {
+		fetchTransport.MaxIdleConnsPerHost = n
+	}
+	if d, err := time.ParseDuration(os.Getenv("FETCH_IDLE_CONN_TIMEOUT")); err == nil && d > 0 {
+		fetchTransport.IdleConnTimeout = d
+	}
+	fetchTransport.VerifyTLS = os.Getenv("FETCH_VERIFY_TLS") == "1" || os.Getenv("FETCH_VERIFY_TLS") == "true"
+
+	cachePolicy := server.DefaultCachePolicy()
+	for prefix, ttl := range map[string]*server.CacheTTL{
+		"CACHE_IMAGE": &cachePolicy.Image,
+		"CACHE_PAGE":  &cachePolicy.Page,
+		"CACHE_API":   &cachePolicy.API,
+	} {
+		if d, err := time.ParseDuration(os.Getenv(prefix + "_MAX_AGE")); err == nil && d >= 0 {
+			ttl.MaxAge = d
+		}
+		if d, err := time.ParseDuration(os.Getenv(prefix + "_SWR")); err == nil && d >= 0 {
+			ttl.StaleWhileRevalidate = d
+		}
+	}
+
+	requestTimeout := server.DefaultRequestTimeout
+	if d, err := time.ParseDuration(os.Getenv("REQUEST_TIMEOUT")); err == nil && d > 0 {
+		requestTimeout = d
+	}
+
+	shutdownTimeout := defaultShutdownTimeout
+	if d, err := time.ParseDuration(os.Getenv("SHUTDOWN_TIMEOUT")); err == nil && d > 0 {
+		shutdownTimeout = d
+	}
+
+	healthCheckSmokeTTL := server.DefaultHealthCheckSmokeTTL
+	if d, err := time.ParseDuration(os.Getenv("HEALTHCHECK_SMOKE_TTL")); err == nil && d > 0 {
+		healthCheckSmokeTTL = d
+	}
+
+	staleCameraThreshold := server.DefaultStaleCameraThreshold
+	if d, err := time.ParseDuration(os.Getenv("STALE_CAMERA_THRESHOLD")); err == nil && d > 0 {
+		staleCameraThreshold = d
+	}
+
+	var dataURLRefresh time.Duration
+	if d, err := time.ParseDuration(os.Getenv("DATA_URL_REFRESH")); err == nil && d > 0 {
+		dataURLRefresh = d
+	}
+
+	accessLogSampleRate := 0
+	if n, err := strconv.Atoi(os.Getenv("ACCESS_LOG_SAMPLE_RATE")); err == nil && n > 0 {
+		accessLogSampleRate = n
+	}
+
+	degradedThreshold := server.DefaultDegradedThreshold
+	if f, err := strconv.ParseFloat(os.Getenv("DEGRADED_THRESHOLD"), 64); err == nil && f > 0 && f <= 1 {
+		degradedThreshold = f
+	}
+
+	var bodyLimit int64 = server.DefaultBodyLimit
+	if n, err := strconv.ParseInt(os.Getenv("BODY_LIMIT"), 10, 64); err == nil && n > 0 {
+		bodyLimit = n
+	}
+
+	dataFile := os.Getenv("DATA_FILE")
+	if dataFile == "" {
+		dataFile = defaultDataFile
+	}
+
+	logFormat := os.Getenv("LOG_FORMAT")
+	switch logFormat {
+	case server.AccessLogJSON, server.AccessLogBoth:
+	default:
+		logFormat = server.AccessLogText
+	}
+
+	// Fail loudly on a bad list rather than silently not blocking anyone
+	var ipFilter server.IPFilter
+	var err error
+	if ipFilter.Allow, err = server.ParseIPPrefixes(os.Getenv("IP_ALLOWLIST")); err != nil {
+		logger.Fatal(err, "invalid IP_ALLOWLIST: %v", err)
+	}
+	if ipFilter.Deny, err = server.ParseIPPrefixes(os.Getenv("IP_DENYLIST")); err != nil {
+		logger.Fatal(err, "invalid IP_DENYLIST: %v", err)
+	}
+	trustedProxies, err := server.ParseIPPrefixes(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		logger.Fatal(err, "invalid TRUSTED_PROXIES: %v", err)
+	}
+	if os.Getenv("TRUST_CLOUDFLARE") == "1" || os.Getenv("TRUST_CLOUDFLARE") == "true" {
+		trustedProxies = append(trustedProxies, cloudflare.IPRanges()...)
+	}
+
 	return Config{
-		Port:         port,
-		SyncInterval: syncInterval,
-		DevMode:      devMode,
-		UDOTAPIKey:   udotAPIKey,
-		UDOTInterval: udotInterval,
+		Port:            port,
+		SyncInterval:    syncInterval,
+		DevMode:         devMode,
+		UDOTAPIKey:      udotAPIKey,
+		UDOTInterval:    udotInterval,
+		UDOTTimeout:     udotTimeout,
+		ImageCache:      imageCache,
+		ImageCacheDir:   imageCacheDir,
+		TimelapseFrames: timelapseFrames,
+		UserAgent:       userAgent,
+		StrictTemplates: os.Getenv("STRICT_TEMPLATES") == "1" || os.Getenv("STRICT_TEMPLATES") == "true",
+
+		WebhookURL:              os.Getenv("WEBHOOK_URL"),
+		WebhookMinStateDuration: webhookMinStateDuration,
+		StoreOptions:            storeOptions,
+		PNGToJPEGQuality:        pngToJPEGQuality,
+		FetchTransport:          fetchTransport,
+		AdminToken:              os.Getenv("ADMIN_TOKEN"),
+		CachePolicy:             cachePolicy,
+		IPFilter:                ipFilter,
+		RequestTimeout:          requestTimeout,
+		CanonicalHost:           os.Getenv("CANONICAL_HOST"),
+		LogFormat:               logFormat,
+		LogLevel:                os.Getenv("LOG_LEVEL"),
+		DataFile:                dataFile,
+		ShutdownTimeout:         shutdownTimeout,
+		HealthCheckSmokeTTL:     healthCheckSmokeTTL,
+		StaleCameraThreshold:    staleCameraThreshold,
+
+		ServerTiming: os.Getenv("SERVER_TIMING") == "1" || os.Getenv("SERVER_TIMING") == "true",
+
+		Maintenance: os.Getenv("MAINTENANCE_MODE") == "1" || os.Getenv("MAINTENANCE_MODE") == "true",
+
+		BodyLimit: bodyLimit,
+
+		DegradedThreshold: degradedThreshold,
+
+		AccessLogSampleRate: accessLogSampleRate,
+
+		DataURL:        os.Getenv("DATA_URL"),
+		DataURLRefresh: dataURLRefresh,
+
+		TrustedProxies: trustedProxies,
+
+		MetricsDump:    metricsDump,
+		MetricsDumpDir: metricsDumpDir,
+
+		UDOTRoadConditions:  udotFeedEnabled("UDOT_ROAD_CONDITIONS"),
+		UDOTWeatherStations: udotFeedEnabled("UDOT_WEATHER_STATIONS"),
+		UDOTEvents:          udotFeedEnabled("UDOT_EVENTS"),
 	}
 }
 
@@ -141,6 +508,20 @@ func getBaseDir() (string, error) {
 	return os.Getwd()
 }
 
+// loadDataFile returns the filesystem and name to load canyon data from. An
+// absolute path is read from its own directory; anything else is relative to
+// the base dir.
+func loadDataFile(path string) (fs.FS, string, error) {
+	if filepath.IsAbs(path) {
+		return os.DirFS(filepath.Dir(path)), filepath.Base(path), nil
+	}
+	dataFS, err := loadFilesystem(".")
+	if err != nil {
+		return nil, "", err
+	}
+	return dataFS, filepath.ToSlash(filepath.Clean(path)), nil
+}
+
 // loadFilesystem loads files from disk (dev mode) or from bundled files (production)
 func loadFilesystem(subdir string) (fs.FS, error) {
 	baseDir, err := getBaseDir()
@@ -152,70 +533,34 @@ func loadFilesystem(subdir string) (fs.FS, error) {
 	return os.DirFS(path), nil
 }
 
-// purgeCloudflareCache purges the Cloudflare cache for the configured zone
-func purgeCloudflareCache() error {
-	zoneID := os.Getenv("CLOUDFLARE_ZONE_ID")
-	apiToken := os.Getenv("CLOUDFLARE_API_TOKEN")
-
-	if zoneID == "" || apiToken == "" {
+// purgeCloudflareCache purges the Cloudflare cache for the configured zone:
+// just the given canyons' pages and images (by Cache-Tag), or everything if
+// none are given
+func purgeCloudflareCache(client *cloudflare.Client, canyons []string) error {
+	if client == nil {
 		logger.Warn("CLOUDFLARE_ZONE_ID or CLOUDFLARE_API_TOKEN not set. Skipping cache purge.")
 		return nil
 	}
 
-	logger.Info("Purging Cloudflare cache for zone: %s", zoneID)
-
-	// Prepare request body
-	body := bytes.NewBufferString(`{"purge_everything":true}`)
-
-	// Create request with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(ctx, "POST",
-		fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/purge_cache", zoneID),
-		body)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+apiToken)
-	req.Header.Set("Content-Type", "application/json")
-
-	// Make request
-	client := &http.Client{
-		Timeout: 15 * time.Second,
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to make request: %w", err)
-	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-
-	// Read response
-	responseBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response: %w", err)
-	}
-
-	// Parse response
-	var result struct {
-		Success bool     `json:"success"`
-		Errors  []string `json:"errors"`
-	}
-
-	if err := json.Unmarshal(responseBody, &result); err != nil {
-		return fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	if result.Success {
+	if len(canyons) == 0 {
+		logger.Info("Purging Cloudflare cache for zone: %s", client.ZoneID())
+		if _, err := client.PurgeEverything(context.Background()); err != nil {
+			return err
+		}
 		logger.Success("Cloudflare cache purged successfully")
 		return nil
 	}
 
-	return fmt.Errorf("cache purge failed: %v", result.Errors)
+	tags := make([]string, len(canyons))
+	for i, canyon := range canyons {
+		tags[i] = cloudflare.CanyonTag(canyon)
+	}
+	logger.Info("Purging Cloudflare cache for zone %s, tags: %s", client.ZoneID(), strings.Join(tags, ", "))
+	if _, err := client.PurgeTags(context.Background(), tags); err != nil {
+		return err
+	}
+	logger.Success("Cloudflare cache purged for %s", strings.Join(canyons, ", "))
+	return nil
 }
 
 // initSentry initializes Sentry if DSN is provided and not in dev mode
@@ -259,16 +604,22 @@ func main() {
 	if len(os.Args) > 1 {
 		switch os.Args[1] {
 		case "purge-cache":
-			if err := purgeCloudflareCache(); err != nil {
+			if err := purgeCloudflareCache(cloudflare.NewClientFromEnv(), os.Args[2:]); err != nil {
 				logger.Fatal(err)
 			}
 			os.Exit(0)
+		case "doctor", "check":
+			os.Exit(runDoctor(context.Background(), loadConfig(), os.Stdout))
 		case "help", "--help", "-h":
 			fmt.Println("LCC Live Camera Service")
 			fmt.Println("")
 			fmt.Println("Usage:")
 			fmt.Println("  lcc-live              Start the web server (default)")
 			fmt.Println("  lcc-live purge-cache  Purge Cloudflare cache")
+			fmt.Println("  lcc-live purge-cache <canyon>...")
+			fmt.Println("                        Purge only those canyons' pages and images")
+			fmt.Println("  lcc-live doctor       Fetch every camera once and report, without serving")
+			fmt.Println("                        (exits 1 if DEGRADED_THRESHOLD of them fail)")
 			fmt.Println("  lcc-live help         Show this help message")
 			return
 		}
@@ -283,6 +634,9 @@ func main() {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	config := loadConfig()
+	if err := logger.SetLevel(config.LogLevel); err != nil {
+		logger.Warn("ignoring LOG_LEVEL: %v", err)
+	}
 
 	// Setup filesystem - load from disk instead of embed
 	staticFS, err := loadFilesystem("web/static")
@@ -295,23 +649,18 @@ func main() {
 		logger.Fatal(err, "failed to load templates: %v", err)
 	}
 
-	dataFS, err := loadFilesystem(".")
-	if err != nil {
-		logger.Fatal(err, "failed to load data directory: %v", err)
-	}
-
-	store, err := store.NewStoreFromFile(dataFS, "data.json")
+	store, err := loadStore(ctx, config)
 	if err != nil {
-		logger.Fatal(err, "failed to create new store from file %s - %v", "data.json", err)
+		logger.Fatal(err, "failed to load cameras: %v", err)
 	}
 
 	// Count cameras
-	cameraCount := len(store.Canyon("LCC").Cameras) + len(store.Canyon("BCC").Cameras)
-	if store.Canyon("LCC").Status.Src != "" {
-		cameraCount++
-	}
-	if store.Canyon("BCC").Status.Src != "" {
-		cameraCount++
+	cameraCount := 0
+	for _, canyon := range store.Canyons() {
+		cameraCount += len(canyon.Cameras)
+		if canyon.Status.Src != "" {
+			cameraCount++
+		}
 	}
 
 	// Initialize TUI with HUD (before any logging)
@@ -323,6 +672,22 @@ func main() {
 		logger.PrintBanner(server.Version, server.BuildTime)
 	}
 
+	// Configure server to use UI logger
+	server.LogWriter = ui.AddLog
+
+	// Keep recent log lines for the admin log stream. Tee is set before
+	// any background goroutine starts logging, so it's never written while
+	// read and the history includes startup.
+	var logHub *server.LogHub
+	if config.AdminToken != "" {
+		logHub = server.NewLogHub(server.DefaultLogHistory)
+		logger.Tee = logHub.Publish
+		server.LogWriter = func(msg string) {
+			ui.AddLog(msg)
+			logHub.Publish(msg)
+		}
+	}
+
 	// Log startup info
 	if config.DevMode {
 		logger.Info("🔥 DEV MODE: Hot reload enabled - files served from disk")
@@ -374,6 +739,27 @@ func main() {
 		})
 	})
 
+	store.EnableTimelapse(config.TimelapseFrames)
+	store.SetUserAgent(config.UserAgent)
+	store.SetPNGToJPEGQuality(config.PNGToJPEGQuality)
+	store.SetTransportOptions(config.FetchTransport)
+
+	if config.WebhookURL != "" {
+		notifier := webhook.NewNotifier(config.WebhookURL, config.WebhookMinStateDuration)
+		store.OnSync(notifier.Observe)
+		logger.Info("Camera state webhooks enabled (min state duration %s)", config.WebhookMinStateDuration)
+	}
+
+	// Restore images from the previous run so we can serve immediately
+	if config.ImageCache {
+		loaded, err := store.LoadImageCache(config.ImageCacheDir)
+		if err != nil {
+			logger.Warn("Failed to load image cache from %s: %v", config.ImageCacheDir, err)
+		} else if loaded > 0 {
+			logger.Info("Restored %d cached images from %s", loaded, config.ImageCacheDir)
+		}
+	}
+
 	// Fetch initial images and start background sync
 	logger.Info("Fetching initial camera images...")
 	g, gCtx := errgroup.WithContext(ctx)
@@ -385,16 +771,20 @@ func main() {
 	g.Go(func() error {
 		return keepCamerasInSync(gCtx, store, config.SyncInterval, &totalSyncs)
 	})
+	if config.DataURL != "" && config.DataURLRefresh > 0 {
+		g.Go(func() error {
+			return keepCamerasConfigured(gCtx, store, config.DataURL, config.DataURLRefresh)
+		})
+	}
 
 	// Start UDOT API fetchers
 	udotClient := udot.NewClient(config.UDOTAPIKey)
+	udotClient.SetUserAgent(config.UserAgent)
+	udotClient.SetTimeout(config.UDOTTimeout)
 	udotPoller := udot.NewPoller(udotClient, store, config.UDOTInterval)
-	g.Go(func() error { return udotPoller.StartRoadConditions(gCtx) })
-	g.Go(func() error { return udotPoller.StartWeatherStations(gCtx) })
-	g.Go(func() error { return udotPoller.StartEvents(gCtx) })
-
-	// Configure server to use UI logger
-	server.LogWriter = ui.AddLog
+	for _, poller := range pollersToStart(config, udotPoller) {
+		g.Go(func() error { return poller.start(gCtx) })
+	}
 
 	// Start server
 	server.RequestCounter = &requestCount
@@ -405,6 +795,26 @@ func main() {
 		TemplateFS:    tmplFS,
 		DevMode:       config.DevMode,
 		SentryEnabled: sentryEnabled,
+
+		LenientTemplates: !config.StrictTemplates,
+		AdminToken:       config.AdminToken,
+		Cloudflare:       cloudflare.NewClientFromEnv(),
+		LogHub:           logHub,
+		CachePolicy:      config.CachePolicy,
+		IPFilter:         config.IPFilter,
+		RequestTimeout:   config.RequestTimeout,
+		Context:          gCtx,
+		CanonicalHost:    config.CanonicalHost,
+		AccessLogFormat:  config.LogFormat,
+
+		HealthCheckSmokeTTL:  config.HealthCheckSmokeTTL,
+		StaleCameraThreshold: config.StaleCameraThreshold,
+		ServerTiming:         config.ServerTiming,
+		Maintenance:          server.NewMaintenance(config.Maintenance),
+		BodyLimit:            config.BodyLimit,
+		DegradedThreshold:    config.DegradedThreshold,
+		AccessLogSampleRate:  config.AccessLogSampleRate,
+		TrustedProxies:       config.TrustedProxies,
 	})
 	if err != nil {
 		logger.Fatal(err)
@@ -426,29 +836,69 @@ func main() {
 		}
 	}()
 
-	// Wait for shutdown signal
-	<-sigChan
-	cancel()
+	// Wait for shutdown signal (or a fatal server error, which cancels ctx)
+	select {
+	case <-sigChan:
+	case <-ctx.Done():
+	}
 
+	// Stop in dependency order: stop taking requests, let in-flight fetches
+	// and pollers drain, persist images and metrics and flush logs, then tear
+	// down the UI last so shutdown messages stay visible
 	logger.Info("Shutting down gracefully...")
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 4*time.Second)
-	defer shutdownCancel()
-	if err := app.Shutdown(shutdownCtx); err != nil {
+	errs := runShutdown(config.ShutdownTimeout, []shutdownStep{
+		{name: "http server", stop: app.Shutdown},
+		{name: "background tasks", stop: func(ctx context.Context) error {
+			cancel()
+			err := waitContext(ctx, g.Wait)
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+			return err
+		}},
+		{name: "image cache", stop: func(ctx context.Context) error {
+			if !config.ImageCache {
+				return nil
+			}
+			saved, err := store.SaveImageCache(config.ImageCacheDir)
+			if err == nil {
+				logger.Info("Saved %d images to %s", saved, config.ImageCacheDir)
+			}
+			return err
+		}},
+		{name: "metrics snapshot", stop: func(ctx context.Context) error {
+			if !config.MetricsDump {
+				return nil
+			}
+			path, err := metrics.WriteSnapshot(prometheus.DefaultGatherer, config.MetricsDumpDir, time.Now())
+			if path != "" {
+				logger.Info("Saved metrics snapshot to %s", path)
+			}
+			return err
+		}},
+		{name: "error log", stop: func(ctx context.Context) error {
+			return server.CloseErrorLogger()
+		}},
+		{name: "sentry", stop: func(ctx context.Context) error {
+			// Flush whatever time is left, but always give events a moment
+			timeout := 100 * time.Millisecond
+			if deadline, ok := ctx.Deadline(); ok {
+				timeout = max(timeout, time.Until(deadline))
+			}
+			if sentryEnabled && !sentry.Flush(timeout) {
+				return errors.New("timed out flushing events")
+			}
+			return nil
+		}},
+		{name: "ui", stop: func(ctx context.Context) error {
+			ui.Shutdown()
+			return nil
+		}},
+	})
+	for _, err := range errs {
 		logger.Error(err, "error during shutdown: %v", err)
 	}
 
-	// Wait for background goroutines to finish
-	if err := g.Wait(); err != nil && !errors.Is(err, context.Canceled) {
-		logger.Error(err, "background task error: %v", err)
-	}
-
-	ui.Shutdown()
-	server.CloseErrorLogger()
-	time.Sleep(100 * time.Millisecond)
-
-	// Flush Sentry before exiting
-	sentry.Flush(2 * time.Second)
-
 	logger.Success("Goodbye!")
 	fmt.Println()
 }
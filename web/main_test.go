@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"io/fs"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/stefanpenner/lcc-live/web/cloudflare"
 	"github.com/stefanpenner/lcc-live/web/server"
 	"github.com/stefanpenner/lcc-live/web/store"
 	"github.com/stefanpenner/lcc-live/web/udot"
@@ -105,6 +110,39 @@ func TestLoadConfig(t *testing.T) {
 	})
 }
 
+func TestLoadConfig_CachePolicy(t *testing.T) {
+	t.Setenv("CACHE_IMAGE_MAX_AGE", "10s")
+	t.Setenv("CACHE_PAGE_SWR", "5m")
+	t.Setenv("CACHE_API_MAX_AGE", "invalid")
+
+	policy := loadConfig().CachePolicy
+	defaults := server.DefaultCachePolicy()
+	assert.Equal(t, 10*time.Second, policy.Image.MaxAge)
+	assert.Equal(t, defaults.Image.StaleWhileRevalidate, policy.Image.StaleWhileRevalidate)
+	assert.Equal(t, defaults.Page.MaxAge, policy.Page.MaxAge)
+	assert.Equal(t, 5*time.Minute, policy.Page.StaleWhileRevalidate)
+	assert.Equal(t, defaults.API, policy.API, "invalid values fall back to the default")
+}
+
+func TestPollersToStart(t *testing.T) {
+	poller := udot.NewPoller(udot.NewClient(""), store.NewStore(&store.Canyons{{ID: "LCC", Name: "LCC"}}), time.Minute)
+	names := func(config Config) []string {
+		var names []string
+		for _, feed := range pollersToStart(config, poller) {
+			names = append(names, feed.name)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"road conditions", "weather stations", "events"}, names(loadConfig()), "all feeds are polled by default")
+
+	t.Setenv("UDOT_EVENTS", "0")
+	t.Setenv("UDOT_WEATHER_STATIONS", "false")
+	assert.Equal(t, []string{"road conditions"}, names(loadConfig()))
+
+	assert.Empty(t, names(Config{}))
+}
+
 func TestDefaultSyncInterval(t *testing.T) {
 	assert.Equal(t, 3*time.Second, defaultSyncInterval)
 }
@@ -197,13 +235,15 @@ func TestApplicationStartup(t *testing.T) {
 		dataFS, err := loadFilesystem(".")
 		require.NoError(t, err)
 
-		testStore, err := store.NewStoreFromFile(dataFS, "data.json")
+		testStore, err := store.NewStoreFromFile(dataFS, "data.json", store.StoreOptions{})
 		require.NoError(t, err)
 		assert.NotNil(t, testStore)
 
 		// Verify canyons are loaded
-		lcc := testStore.Canyon("LCC")
-		bcc := testStore.Canyon("BCC")
+		lcc, ok := testStore.Canyon("LCC")
+		require.True(t, ok)
+		bcc, ok := testStore.Canyon("BCC")
+		require.True(t, ok)
 		assert.NotEmpty(t, lcc.Name)
 		assert.NotEmpty(t, bcc.Name)
 	})
@@ -221,16 +261,15 @@ func TestApplicationStartup(t *testing.T) {
 		require.NoError(t, err)
 
 		// Create store
-		testStore, err := store.NewStoreFromFile(dataFS, "data.json")
+		testStore, err := store.NewStoreFromFile(dataFS, "data.json", store.StoreOptions{})
 		require.NoError(t, err)
 
 		// Start server
 		app, err := server.Start(server.ServerConfig{
-			Store:         testStore,
-			StaticFS:      staticFS,
-			TemplateFS:    tmplFS,
-			DevMode:       false,
-			SentryEnabled: false,
+			Store:      testStore,
+			StaticFS:   staticFS,
+			TemplateFS: tmplFS,
+			DevMode:    false,
 		})
 		require.NoError(t, err)
 		assert.NotNil(t, app)
@@ -249,16 +288,15 @@ func TestApplicationStartup(t *testing.T) {
 		require.NoError(t, err)
 
 		// Create and initialize store
-		testStore, err := store.NewStoreFromFile(dataFS, "data.json")
+		testStore, err := store.NewStoreFromFile(dataFS, "data.json", store.StoreOptions{})
 		require.NoError(t, err)
 
 		// Start server
 		app, err := server.Start(server.ServerConfig{
-			Store:         testStore,
-			StaticFS:      staticFS,
-			TemplateFS:    tmplFS,
-			DevMode:       false,
-			SentryEnabled: false,
+			Store:      testStore,
+			StaticFS:   staticFS,
+			TemplateFS: tmplFS,
+			DevMode:    false,
 		})
 		require.NoError(t, err)
 
@@ -271,6 +309,37 @@ func TestApplicationStartup(t *testing.T) {
 		// Should be 503 since we haven't fetched images yet
 		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
 	})
+
+	t.Run("Empty canyon renders an empty state", func(t *testing.T) {
+		staticFS, err := loadFilesystem("web/static")
+		require.NoError(t, err)
+
+		tmplFS, err := loadFilesystem("web/templates")
+		require.NoError(t, err)
+
+		testStore := store.NewStore(&store.Canyons{
+			{ID: "LCC", Name: "Little Cottonwood Canyon", Cameras: []store.Camera{
+				{Kind: "img", Src: "http://127.0.0.1:0/a.jpg", Alt: "Camera A"},
+			}},
+			{ID: "BCC", Name: "Big Cottonwood Canyon"},
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		testStore.FetchImages(ctx)
+
+		app, err := server.Start(server.ServerConfig{
+			Store:      testStore,
+			StaticFS:   staticFS,
+			TemplateFS: tmplFS,
+		})
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest("GET", "/bcc", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `class="empty-state"`)
+		assert.NotContains(t, rec.Body.String(), "<camera-feed")
+	})
 }
 
 func TestFilterEventsByCanyon(t *testing.T) {
@@ -377,3 +446,88 @@ func TestFilterEventsByCanyon(t *testing.T) {
 		})
 	}
 }
+
+func TestPurgeCloudflareCache(t *testing.T) {
+	var payloads []map[string]any
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		payloads = append(payloads, payload)
+		w.Write([]byte(`{"success":true,"errors":[]}`))
+	}))
+	defer api.Close()
+
+	client := cloudflare.NewClient("zone-1", "secret")
+	client.SetBaseURL(api.URL)
+
+	require.NoError(t, purgeCloudflareCache(client, []string{"LCC"}))
+	require.NoError(t, purgeCloudflareCache(client, []string{"lcc", "bcc"}))
+	require.NoError(t, purgeCloudflareCache(client, nil))
+	assert.Equal(t, []map[string]any{
+		{"tags": []any{"canyon-lcc"}},
+		{"tags": []any{"canyon-lcc", "canyon-bcc"}},
+		{"purge_everything": true},
+	}, payloads)
+
+	assert.NoError(t, purgeCloudflareCache(nil, []string{"LCC"}), "unconfigured purges are skipped")
+}
+
+func TestLoadStore_DataURL(t *testing.T) {
+	dataFile := filepath.Join(t.TempDir(), "data.json")
+	require.NoError(t, os.WriteFile(dataFile, []byte(`{"lcc": {"id": "LCC", "name": "Bundled", "cameras": []}}`), 0o644))
+
+	remote := `{"lcc": {"id": "LCC", "name": "Remote", "cameras": []}}`
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(remote))
+	}))
+	defer api.Close()
+
+	load := func(t *testing.T, dataURL string) string {
+		s, err := loadStore(context.Background(), Config{DataFile: dataFile, DataURL: dataURL})
+		require.NoError(t, err)
+		require.Len(t, s.Canyons(), 1)
+		return s.Canyons()[0].Name
+	}
+
+	assert.Equal(t, "Remote", load(t, api.URL))
+	assert.Equal(t, "Bundled", load(t, ""), "without DATA_URL the bundled file is used")
+	assert.Equal(t, "Bundled", load(t, api.URL+"/unreachable\x00"), "a bad URL falls back")
+
+	remote = `{"lcc": `
+	assert.Equal(t, "Bundled", load(t, api.URL), "invalid JSON falls back")
+
+	remote = `{"lcc": {"id": "LCC", "name": "Remote", "cameras": [
+		{"kind": "img", "src": "https://example.com/a.jpg", "alt": "A"},
+		{"kind": "img", "src": "https://example.com/a.jpg", "alt": "B"}
+	]}}`
+	assert.Equal(t, "Bundled", load(t, api.URL), "an invalid config falls back")
+}
+
+func TestKeepCamerasConfigured(t *testing.T) {
+	var mu sync.Mutex
+	remote := `{"lcc": {"id": "LCC", "name": "Before", "cameras": []}}`
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Write([]byte(remote))
+	}))
+	defer api.Close()
+	setRemote := func(body string) {
+		mu.Lock()
+		defer mu.Unlock()
+		remote = body
+	}
+
+	s, err := loadStore(context.Background(), Config{DataURL: api.URL})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go keepCamerasConfigured(ctx, s, api.URL, 10*time.Millisecond)
+
+	setRemote(`{"lcc": {"id": "LCC", "name": "After", "cameras": []}}`)
+	assert.Eventually(t, func() bool { return s.Canyons()[0].Name == "After" }, 2*time.Second, 10*time.Millisecond)
+
+	setRemote(`not json`)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, "After", s.Canyons()[0].Name, "a bad refetch keeps the current cameras")
+}
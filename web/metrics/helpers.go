@@ -4,6 +4,8 @@ package metrics
 import (
 	"net/url"
 	"runtime"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // ExtractOrigin extracts the domain from a URL for origin tracking
@@ -15,6 +17,28 @@ func ExtractOrigin(urlStr string) string {
 	return parsed.Host
 }
 
+// DeleteCameraSeries removes every per-camera series for a camera, so
+// cameras dropped or renamed by a config reload don't leave stale series.
+// It returns the number of series deleted.
+func DeleteCameraSeries(camera, canyon string) int {
+	labels := prometheus.Labels{"camera": camera, "canyon": canyon}
+	deleted := 0
+	for _, vec := range []interface {
+		DeletePartialMatch(prometheus.Labels) int
+	}{
+		CameraFetchTotal,
+		CameraFetchDuration,
+		CameraAvailability,
+		StatusCameraAvailability,
+		CameraLastSuccessTimestamp,
+		CameraImageSizeBytes,
+		ImageViewsTotal,
+	} {
+		deleted += vec.DeletePartialMatch(labels)
+	}
+	return deleted
+}
+
 // RecordMemoryUsage updates memory usage metrics
 func RecordMemoryUsage() {
 	var m runtime.MemStats
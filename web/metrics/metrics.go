@@ -75,6 +75,15 @@ var (
 		},
 	)
 
+	// ServiceDegraded is 1 while /healthcheck reports the service degraded
+	// (too many cameras failed the latest sync)
+	ServiceDegraded = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "lcc_service_degraded",
+			Help: "Whether too many cameras failed the latest sync (0=false, 1=true)",
+		},
+	)
+
 	// HTTPRequestDuration measures HTTP request latency by path
 	HTTPRequestDuration = promauto.NewHistogramVec(
 		prometheus.HistogramOpts{
@@ -111,6 +120,18 @@ var (
 		[]string{"path"},
 	)
 
+	// ImageServeDuration measures how long /image/:id takes to answer,
+	// split into cheap revalidations ("not_modified") and full transfers
+	// ("full")
+	ImageServeDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "lcc_image_serve_duration_seconds",
+			Help:    "Time to serve a camera image, by result (not_modified or full)",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 8), // 100µs to ~1.6s
+		},
+		[]string{"result"},
+	)
+
 	// ResponseSizeBytes measures HTTP response sizes
 	ResponseSizeBytes = promauto.NewHistogramVec(
 		prometheus.HistogramOpts{
@@ -160,6 +181,26 @@ var (
 		[]string{"camera", "canyon"},
 	)
 
+	// StatusCameraAvailability mirrors CameraAvailability for canyon status
+	// cameras only, so the status feed can be alerted on by itself
+	StatusCameraAvailability = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "lcc_status_camera_availability",
+			Help: "Canyon status camera availability (1=up, 0=down)",
+		},
+		[]string{"camera", "canyon"},
+	)
+
+	// CanyonAvailabilityRatio is the fraction of a canyon's cameras that
+	// were up in the latest fetch cycle
+	CanyonAvailabilityRatio = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "lcc_canyon_availability_ratio",
+			Help: "Fraction of a canyon's cameras up in the latest fetch cycle (0-1)",
+		},
+		[]string{"canyon"},
+	)
+
 	// CameraLastSuccessTimestamp records when camera was last successfully fetched
 	CameraLastSuccessTimestamp = promauto.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -208,6 +249,37 @@ var (
 		[]string{"origin", "error_type"}, // origin, error_type (timeout, connection, bad_status, etc.)
 	)
 
+	// === UDOT API Metrics ===
+
+	// UDOTFetchTotal tracks UDOT API polls per endpoint
+	UDOTFetchTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "lcc_udot_fetch_total",
+			Help: "Total number of UDOT API fetches per endpoint",
+		},
+		[]string{"endpoint", "status"}, // endpoint, status (success/unchanged/error)
+	)
+
+	// UDOTLastSuccessTimestamp records when each UDOT endpoint last answered
+	// (including 304s), so stale road data can be alerted on
+	UDOTLastSuccessTimestamp = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "lcc_udot_last_success_timestamp_seconds",
+			Help: "Unix timestamp of last successful UDOT API fetch per endpoint",
+		},
+		[]string{"endpoint"},
+	)
+
+	// UDOTFetchDuration tracks UDOT API latency per endpoint
+	UDOTFetchDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "lcc_udot_fetch_duration_seconds",
+			Help:    "Time to fetch from a UDOT API endpoint",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"endpoint"},
+	)
+
 	// === Usage & Traffic Metrics ===
 
 	// PageViewsTotal tracks page views by canyon
@@ -268,11 +340,30 @@ var (
 		},
 	)
 
-	// ConcurrentFetches tracks number of concurrent image fetches
+	// ConcurrentFetches tracks number of concurrent image fetches, which
+	// never exceeds the store's fetch concurrency
 	ConcurrentFetches = promauto.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "lcc_concurrent_fetches",
-			Help: "Number of concurrent image fetches in progress",
+			Help: "Number of concurrent image fetches in progress (capped by FETCH_CONCURRENCY)",
+		},
+	)
+
+	// FetchQueueDepth counts cameras waiting for a fetch worker in the
+	// current FetchImages cycle
+	FetchQueueDepth = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "lcc_fetch_queue_depth",
+			Help: "Number of cameras waiting for a fetch worker in the current cycle",
+		},
+	)
+
+	// FetchWorkersBusy counts fetch workers currently fetching a camera;
+	// at FETCH_CONCURRENCY the pool is saturated
+	FetchWorkersBusy = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "lcc_fetch_workers_busy",
+			Help: "Number of fetch workers currently fetching a camera (capped by FETCH_CONCURRENCY)",
 		},
 	)
 
@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+// WriteSnapshot writes every metric family gathered from g, in the
+// Prometheus text format, to a timestamped file in dir (created if needed)
+// and returns its path. The file is written under a temporary name and
+// renamed into place, so a dump cut short never leaves a truncated
+// snapshot. Families gathered despite a gathering error are still written.
+func WriteSnapshot(g prometheus.Gatherer, dir string, now time.Time) (string, error) {
+	families, gatherErr := g.Gather()
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "metrics-"+now.UTC().Format("20060102T150405Z")+".prom")
+	tmp, err := os.CreateTemp(dir, ".metrics-*.prom")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	for _, family := range families {
+		if _, err := expfmt.MetricFamilyToText(tmp, family); err != nil {
+			_ = tmp.Close()
+			return "", fmt.Errorf("failed to write %s: %w", family.GetName(), err)
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	if gatherErr != nil {
+		return path, errors.Join(errors.New("some metrics could not be gathered"), gatherErr)
+	}
+	return path, nil
+}
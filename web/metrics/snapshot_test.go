@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSnapshot(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	fetches := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "lcc_test_fetches_total", Help: "Test fetches"}, []string{"camera"})
+	registry.MustRegister(fetches)
+	fetches.WithLabelValues("alta").Add(3)
+
+	dir := filepath.Join(t.TempDir(), "dumps")
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	path, err := WriteSnapshot(registry, dir, now)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "metrics-20260102T030405Z.prom"), path)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# TYPE lcc_test_fetches_total counter")
+	assert.Contains(t, string(data), `lcc_test_fetches_total{camera="alta"} 3`)
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1, "no temporary files are left behind")
+}
+
+func TestWriteSnapshot_DefaultGatherer(t *testing.T) {
+	CameraAvailability.WithLabelValues("snapshot-camera", "LCC").Set(1)
+
+	path, err := WriteSnapshot(prometheus.DefaultGatherer, t.TempDir(), time.Now())
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "lcc_camera_availability")
+	assert.Contains(t, string(data), "go_goroutines")
+}
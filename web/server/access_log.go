@@ -0,0 +1,77 @@
+package server
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stefanpenner/lcc-live/web/logger"
+)
+
+// Access log formats for ServerConfig.AccessLogFormat
+const (
+	// AccessLogText is the styled, human-friendly line sent to LogWriter
+	AccessLogText = "text"
+	// AccessLogJSON replaces the styled line with one JSON record per request
+	AccessLogJSON = "json"
+	// AccessLogBoth emits both
+	AccessLogBoth = "both"
+)
+
+// jsonAccessLog writes a structured record for every request to w, so
+// latency and error dashboards can be built from logs alone
+func jsonAccessLog(w io.Writer) echo.MiddlewareFunc {
+	l := logger.NewJSONLogger(w)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// Resolve the error's status now rather than after we've
+				// logged; echo skips its handler for committed responses
+				c.Error(err)
+			}
+
+			if !shouldLogAccess(c, nil) {
+				return err
+			}
+			req, res := c.Request(), c.Response()
+			l.Info("request",
+				"method", req.Method,
+				"path", req.URL.Path,
+				"status", res.Status,
+				"duration_ms", float64(time.Since(start).Microseconds())/1000,
+				"bytes", res.Size,
+				"ip", c.RealIP(),
+				"request_id", c.Get("request_id"),
+			)
+			return err
+		}
+	}
+}
+
+// accessLogSampling marks 1 in every n requests as sampled; the access logs
+// skip 2xx responses that aren't. n <= 1 samples every request.
+func accessLogSampling(n int) echo.MiddlewareFunc {
+	var count atomic.Uint64
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("_access_log_sampled", n <= 1 || (count.Add(1)-1)%uint64(n) == 0)
+			return next(c)
+		}
+	}
+}
+
+// shouldLogAccess reports whether a request belongs in the access logs:
+// every failed or non-2xx request, and the sampled 2xx ones
+func shouldLogAccess(c echo.Context, err error) bool {
+	if err != nil {
+		return true
+	}
+	if status := c.Response().Status; status < 200 || status >= 300 {
+		return true
+	}
+	sampled, ok := c.Get("_access_log_sampled").(bool)
+	return !ok || sampled
+}
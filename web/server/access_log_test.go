@@ -0,0 +1,89 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStart_JSONAccessLog(t *testing.T) {
+	var out bytes.Buffer
+	app, err := Start(ServerConfig{
+		Store:           store.NewStore(&store.Canyons{{ID: "LCC", Name: "Little Cottonwood Canyon"}}),
+		StaticFS:        fstest.MapFS{},
+		TemplateFS:      fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+		AccessLogFormat: AccessLogJSON,
+		AccessLogOutput: &out,
+	})
+	require.NoError(t, err)
+
+	for _, path := range []string{"/robots.txt", "/does-not-exist"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Forwarded-For", "198.51.100.7")
+		app.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	var records []map[string]any
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record), scanner.Text())
+		records = append(records, record)
+	}
+	require.Len(t, records, 2)
+
+	ok := records[0]
+	assert.Equal(t, "request", ok["msg"])
+	assert.Equal(t, "GET", ok["method"])
+	assert.Equal(t, "/robots.txt", ok["path"])
+	assert.EqualValues(t, http.StatusOK, ok["status"])
+	assert.IsType(t, float64(0), ok["duration_ms"])
+	assert.Greater(t, ok["bytes"], float64(0))
+	assert.Equal(t, "198.51.100.7", ok["ip"])
+	assert.NotEmpty(t, ok["request_id"])
+	assert.NotEmpty(t, ok["time"])
+
+	assert.EqualValues(t, http.StatusNotFound, records[1]["status"], "errors are logged with their final status")
+	assert.NotEqual(t, ok["request_id"], records[1]["request_id"])
+}
+
+func TestStart_AccessLogSampling(t *testing.T) {
+	var out bytes.Buffer
+	app, err := Start(ServerConfig{
+		Store:               store.NewStore(&store.Canyons{{ID: "LCC", Name: "Little Cottonwood Canyon"}}),
+		StaticFS:            fstest.MapFS{},
+		TemplateFS:          fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+		AccessLogFormat:     AccessLogJSON,
+		AccessLogOutput:     &out,
+		AccessLogSampleRate: 4,
+	})
+	require.NoError(t, err)
+	app.GET("/boom", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	})
+
+	for range 8 {
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/robots.txt", nil))
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/boom", nil))
+	}
+
+	statuses := map[float64]int{}
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record), scanner.Text())
+		statuses[record["status"].(float64)]++
+	}
+	assert.Equal(t, 8, statuses[http.StatusInternalServerError], "every error is logged")
+	assert.Less(t, statuses[http.StatusOK], 8, "2xx requests are sampled")
+	assert.Positive(t, statuses[http.StatusOK])
+}
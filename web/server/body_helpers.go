@@ -0,0 +1,40 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// renderJSON encodes v exactly as c.JSON would, so the result can be measured
+// before it's sent
+func renderJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderTemplate renders a template to bytes instead of the response
+func renderTemplate(c echo.Context, name string, data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.Echo().Renderer.Render(&buf, name, data, c); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// sendBody writes a pre-rendered body. HEAD requests get the same headers,
+// including the Content-Length a GET would have, but no body.
+func sendBody(c echo.Context, contentType string, body []byte) error {
+	if c.Request().Method == http.MethodHead {
+		c.Response().Header().Set("Content-Type", contentType)
+		c.Response().Header().Set("Content-Length", strconv.Itoa(len(body)))
+		return c.NoContent(http.StatusOK)
+	}
+	return c.Blob(http.StatusOK, contentType, body)
+}
@@ -2,9 +2,13 @@ package server
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/stefanpenner/lcc-live/web/cloudflare"
 )
 
 // ETagger is an interface for types that have their own ETag
@@ -12,6 +16,63 @@ type ETagger interface {
 	GetETag() string
 }
 
+// CacheTTL is how long browsers and the CDN may cache one class of response
+type CacheTTL struct {
+	MaxAge               time.Duration
+	StaleWhileRevalidate time.Duration
+}
+
+// CacheControl formats the TTL as a public Cache-Control value
+func (t CacheTTL) CacheControl() string {
+	return fmt.Sprintf("public, max-age=%d, stale-while-revalidate=%d",
+		int(t.MaxAge.Seconds()), int(t.StaleWhileRevalidate.Seconds()))
+}
+
+// CachePolicy holds the Cache-Control lifetimes for each class of route, so
+// CDN behavior can be tuned (e.g. on storm days) without a code change
+type CachePolicy struct {
+	// Image covers live camera images and timelapse listings
+	Image CacheTTL
+	// Page covers HTML canyon and camera pages
+	Page CacheTTL
+	// API covers JSON canyon, camera and UDOT responses
+	API CacheTTL
+}
+
+// DefaultCachePolicy returns the lifetimes we've tuned for normal traffic;
+// see web/docs/caching.md
+func DefaultCachePolicy() CachePolicy {
+	return CachePolicy{
+		Image: CacheTTL{MaxAge: 3 * time.Second, StaleWhileRevalidate: 120 * time.Second},
+		Page:  CacheTTL{MaxAge: 30 * time.Second, StaleWhileRevalidate: 120 * time.Second},
+		API:   CacheTTL{MaxAge: 30 * time.Second, StaleWhileRevalidate: 120 * time.Second},
+	}
+}
+
+// withDefaults fills in any route class left unset
+func (p CachePolicy) withDefaults() CachePolicy {
+	defaults := DefaultCachePolicy()
+	if p.Image == (CacheTTL{}) {
+		p.Image = defaults.Image
+	}
+	if p.Page == (CacheTTL{}) {
+		p.Page = defaults.Page
+	}
+	if p.API == (CacheTTL{}) {
+		p.API = defaults.API
+	}
+	return p
+}
+
+// cachePolicy returns the server's cache policy for a request, falling back
+// to the defaults for handlers run outside Start (e.g. in tests)
+func cachePolicy(c echo.Context) CachePolicy {
+	if policy, ok := c.Get("_cache_policy").(CachePolicy); ok {
+		return policy
+	}
+	return DefaultCachePolicy()
+}
+
 // CacheConfig holds configuration for cache headers and ETag generation
 type CacheConfig struct {
 	// Components are all the data components to include in the ETag
@@ -22,6 +83,63 @@ type CacheConfig struct {
 
 	// DevMode disables caching when true
 	DevMode bool
+
+	// LastModified is when any component last changed. When set, responses
+	// carry Last-Modified and honor If-Modified-Since; zero disables both.
+	LastModified time.Time
+}
+
+// effectiveLastModified clamps t to the process start, so a deploy (which
+// may change the rendered page without touching the data) also moves the
+// date validator forward, just as it changes the ETag
+func effectiveLastModified(t time.Time) time.Time {
+	if t.Before(startTime) {
+		t = startTime
+	}
+	return t.UTC().Truncate(time.Second)
+}
+
+// notModifiedSince reports whether the request's If-Modified-Since covers
+// lastModified. ETags take precedence: a request carrying If-None-Match is
+// never answered from its date (RFC 9110, section 13.2.2).
+func notModifiedSince(c echo.Context, lastModified time.Time) bool {
+	req := c.Request()
+	if req.Header.Get("If-None-Match") != "" {
+		return false
+	}
+	since, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !effectiveLastModified(lastModified).After(since)
+}
+
+// etagMatches reports whether an If-None-Match value matches etag, using the
+// weak comparison RFC 9110 (section 13.1.2) requires for it: a W/ prefix on
+// either side is ignored, any tag in a comma-separated list may match, and
+// "*" matches any current representation.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified reports whether the request's If-None-Match matches etag
+func notModified(c echo.Context, etag string) bool {
+	return etagMatches(c.Request().Header.Get("If-None-Match"), etag)
+}
+
+// setLastModified sets the Last-Modified header for lastModified
+func setLastModified(c echo.Context, lastModified time.Time) {
+	c.Response().Header().Set("Last-Modified", effectiveLastModified(lastModified).Format(http.TimeFormat))
 }
 
 // SetCacheHeaders sets consistent cache headers and ETag based on the config
@@ -33,8 +151,10 @@ func SetCacheHeaders(c echo.Context, config CacheConfig) (string, bool, error) {
 		return "", false, errors.New("Content-Type must be set before calling SetCacheHeaders")
 	}
 
-	// Determine format from request path
-	isJSON := strings.HasSuffix(c.Request().URL.Path, ".json")
+	// Determine format from the response's Content-Type; structured
+	// syntaxes like application/geo+json count as JSON
+	contentType := c.Response().Header().Get("Content-Type")
+	isJSON := strings.HasPrefix(contentType, "application/json") || strings.Contains(contentType, "+json")
 	formatSuffix := "html"
 	if isJSON {
 		formatSuffix = "json"
@@ -52,15 +172,25 @@ func SetCacheHeaders(c echo.Context, config CacheConfig) (string, bool, error) {
 		return etag, false, nil
 	}
 
-	// Set standard cache headers
-	c.Response().Header().Set("Cache-Control", "public, max-age=30, stale-while-revalidate=120, must-revalidate")
+	// Set standard cache headers, using the API lifetimes for JSON bodies
+	ttl := cachePolicy(c).Page
+	if isJSON {
+		ttl = cachePolicy(c).API
+	}
+	c.Response().Header().Set("Cache-Control", ttl.CacheControl()+", must-revalidate")
 	c.Response().Header().Set("ETag", etag)
 	c.Response().Header().Set("Vary", "Accept")
 
 	// Check if client has matching ETag
-	if ifNoneMatch := c.Request().Header.Get("If-None-Match"); ifNoneMatch != "" {
-		if ifNoneMatch == etag {
-			return etag, true, nil // Return 304 Not Modified
+	if notModified(c, etag) {
+		return etag, true, nil // Return 304 Not Modified
+	}
+
+	// Fall back to the date validator for clients that only send that
+	if !config.LastModified.IsZero() {
+		setLastModified(c, config.LastModified)
+		if notModifiedSince(c, config.LastModified) {
+			return etag, true, nil
 		}
 	}
 
@@ -109,4 +239,19 @@ func buildCompositeETag(config CacheConfig, formatSuffix string) string {
 	return "\"" + strings.Join(parts, "-") + "\""
 }
 
-
+// setCacheTag tags a response with its canyon, so the canyon's pages and
+// images can be purged from Cloudflare together (purge-cache <canyon>), and
+// with its camera (empty for canyon-wide responses), so a single camera's
+// images and pages can be purged alone
+func setCacheTag(c echo.Context, canyonID, cameraSlug string) {
+	var tags []string
+	if cameraSlug != "" {
+		tags = append(tags, cloudflare.CameraTag(cameraSlug))
+	}
+	if canyonID != "" {
+		tags = append(tags, cloudflare.CanyonTag(canyonID))
+	}
+	if len(tags) > 0 {
+		c.Response().Header().Set("Cache-Tag", strings.Join(tags, ","))
+	}
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheTTL_CacheControl(t *testing.T) {
+	ttl := CacheTTL{MaxAge: 90 * time.Second, StaleWhileRevalidate: 5 * time.Minute}
+	assert.Equal(t, "public, max-age=90, stale-while-revalidate=300", ttl.CacheControl())
+}
+
+func TestStart_CachePolicy(t *testing.T) {
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write(testImage("policy"))
+	}))
+	t.Cleanup(imageServer.Close)
+
+	testStore := store.NewStore(&store.Canyons{{
+		ID:      "LCC",
+		Name:    "Little Cottonwood Canyon",
+		Cameras: []store.Camera{{Kind: "img", Src: imageServer.URL + "/cam.jpg", Alt: "Policy Camera"}},
+	}})
+	testStore.FetchImages(context.Background())
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+		CachePolicy: CachePolicy{
+			Image: CacheTTL{MaxAge: 7 * time.Second, StaleWhileRevalidate: 11 * time.Second},
+			API:   CacheTTL{MaxAge: 5 * time.Minute, StaleWhileRevalidate: 10 * time.Minute},
+			// Page is left unset and keeps the default
+		},
+	})
+	require.NoError(t, err)
+
+	for path, want := range map[string]string{
+		"/image/policy-camera":       "public, max-age=7, stale-while-revalidate=11",
+		"/":                          "public, max-age=30, stale-while-revalidate=120, must-revalidate",
+		"/lcc.json":                  "public, max-age=300, stale-while-revalidate=600, must-revalidate",
+		"/camera/policy-camera.json": "public, max-age=300, stale-while-revalidate=600, must-revalidate",
+	} {
+		t.Run(path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			require.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, want, rec.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		etag        string
+		want        bool
+	}{
+		{"strong match", `"abc"`, `"abc"`, true},
+		{"weak header, strong etag", `W/"abc"`, `"abc"`, true},
+		{"strong header, weak etag", `"abc"`, `W/"abc"`, true},
+		{"both weak", `W/"abc"`, `W/"abc"`, true},
+		{"mismatch", `"abc"`, `"def"`, false},
+		{"weak mismatch", `W/"abc"`, `"def"`, false},
+		{"list match", `"x", W/"abc" ,"y"`, `"abc"`, true},
+		{"list mismatch", `"x", "y"`, `"abc"`, false},
+		{"wildcard", `*`, `"abc"`, true},
+		{"empty header", ``, `"abc"`, false},
+		{"no etag", `*`, ``, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, etagMatches(tt.ifNoneMatch, tt.etag))
+		})
+	}
+}
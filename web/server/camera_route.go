@@ -3,6 +3,7 @@ package server
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stefanpenner/lcc-live/web/metrics"
@@ -12,11 +13,65 @@ import (
 type CameraPageData struct {
 	Camera         store.Camera
 	CanyonName     string
+	CanyonTitle    string
 	CanyonPath     string
 	ImageURL       string
+	OGImageURL     string
 	WeatherStation *store.WeatherStation
 }
 
+// CameraJSON is the camera object in camera page JSON. It's decoupled from
+// store.Camera so config-only or internal fields (like browserUserAgent)
+// can change without changing the wire format the apps decode, or churning
+// the ETags of anything that hashes it. Keys appear in field order.
+type CameraJSON struct {
+	ID               string `json:"id"`
+	Kind             string `json:"kind"`
+	Src              string `json:"src"`
+	Alt              string `json:"alt"`
+	Slug             string `json:"slug,omitempty"`
+	Canyon           string `json:"canyon"`
+	WeatherStationId *int   `json:"weatherStationId,omitempty"`
+	Width            int    `json:"width,omitempty"`
+	Height           int    `json:"height,omitempty"`
+}
+
+// CameraPageJSON is the /camera/:slug.json response. Its keys keep the
+// names the iOS and Android apps already decode.
+type CameraPageJSON struct {
+	Camera         CameraJSON            `json:"Camera"`
+	CanyonName     string                `json:"CanyonName"`
+	CanyonTitle    string                `json:"CanyonTitle"`
+	CanyonPath     string                `json:"CanyonPath"`
+	ImageURL       string                `json:"ImageURL"`
+	OGImageURL     string                `json:"OGImageURL"`
+	WeatherStation *store.WeatherStation `json:"WeatherStation"`
+}
+
+// cameraPageJSON converts page data to its JSON wire format
+func cameraPageJSON(data CameraPageData) CameraPageJSON {
+	camera := data.Camera
+	return CameraPageJSON{
+		Camera: CameraJSON{
+			ID:               camera.ID,
+			Kind:             camera.Kind,
+			Src:              camera.Src,
+			Alt:              camera.Alt,
+			Slug:             camera.Slug,
+			Canyon:           camera.Canyon,
+			WeatherStationId: camera.WeatherStationId,
+			Width:            camera.Width,
+			Height:           camera.Height,
+		},
+		CanyonName:     data.CanyonName,
+		CanyonTitle:    data.CanyonTitle,
+		CanyonPath:     data.CanyonPath,
+		ImageURL:       data.ImageURL,
+		OGImageURL:     data.OGImageURL,
+		WeatherStation: data.WeatherStation,
+	}
+}
+
 func CameraRoute(store *store.Store) func(c echo.Context) error {
 	return func(c echo.Context) error {
 		// Get the wildcard parameter (everything after /camera/)
@@ -27,19 +82,19 @@ func CameraRoute(store *store.Store) func(c echo.Context) error {
 
 		entry, exists := store.Get(slugOrID)
 
-		if !exists {
-			return c.String(http.StatusNotFound, "Camera not found")
+		if !exists || !entry.Camera.IsEnabled() {
+			return errorResponse(c, http.StatusNotFound, "Camera not found")
 		}
 
 		// Check if Camera is nil (defensive programming)
 		if entry.Camera == nil {
-			return c.String(http.StatusInternalServerError, "Camera data is invalid")
+			return errorResponse(c, http.StatusInternalServerError, "Camera data is invalid")
 		}
 
 		// If accessed via ID, redirect to slug-based URL for canonical URLs
 		// Check if this was accessed via ID (not slug) and redirect to slug if available
-		if entry.Camera.Alt != "" {
-			expectedSlug := slugify(entry.Camera.Alt)
+		if entry.Camera.Alt != "" || entry.Camera.Slug != "" {
+			expectedSlug := entry.Camera.GetSlug()
 			// Only redirect if:
 			// 1. The path doesn't match the expected slug (i.e., it's an ID or wrong slug)
 			// 2. The path matches this camera's ID (confirming it was accessed via ID)
@@ -63,10 +118,11 @@ func CameraRoute(store *store.Store) func(c echo.Context) error {
 
 		// Determine canyon name and path
 		canyonName := entry.Camera.Canyon
-		canyonPath := "/"
-		if strings.ToUpper(canyonName) == "BCC" {
-			canyonPath = "/bcc"
+		canyonTitle := canyonName
+		if canyon, ok := store.Canyon(canyonName); ok {
+			canyonTitle = canyon.GetTitle()
 		}
+		canyonPath := canyonPagePath(store, canyonName)
 
 		// Get weather station for this camera
 		weatherStation := store.GetWeatherStation(entry.Camera.ID)
@@ -74,11 +130,15 @@ func CameraRoute(store *store.Store) func(c echo.Context) error {
 		// Build the data for the template
 		// Use the actual camera ID for image URL, not the path parameter (which might be a slug)
 		// For iframe cameras, ImageURL is not used but we set it anyway for consistency
+		camera := *entry.Camera
+		camera.Width, camera.Height = entry.Image.Width, entry.Image.Height
 		data := CameraPageData{
-			Camera:         *entry.Camera,
+			Camera:         camera,
 			CanyonName:     canyonName,
+			CanyonTitle:    canyonTitle,
 			CanyonPath:     canyonPath,
 			ImageURL:       "/image/" + entry.Camera.ID,
+			OGImageURL:     ogImageURL(entry.Camera),
 			WeatherStation: weatherStation,
 		}
 
@@ -104,28 +164,55 @@ func CameraRoute(store *store.Store) func(c echo.Context) error {
 
 		// Use max-age with stale-while-revalidate for better performance
 		// When version changes, ETag changes automatically, so no manual purge needed
-		c.Response().Header().Set("Cache-Control", "public, max-age=30, stale-while-revalidate=120, must-revalidate")
+		ttl := cachePolicy(c).Page
+		if isJSON {
+			ttl = cachePolicy(c).API
+		}
+		c.Response().Header().Set("Cache-Control", ttl.CacheControl()+", must-revalidate")
 		c.Response().Header().Set("ETag", etag)
+		setCacheTag(c, entry.Camera.Canyon, entry.Camera.GetSlug())
 
 		// Add Vary header to ensure Cloudflare caches by Content-Type
 		c.Response().Header().Set("Vary", "Accept")
 
 		// Check if client has matching ETag
-		if ifNoneMatch := c.Request().Header.Get("If-None-Match"); ifNoneMatch != "" {
-			if ifNoneMatch == etag {
-				return c.NoContent(http.StatusNotModified)
-			}
+		if notModified(c, etag) {
+			return c.NoContent(http.StatusNotModified)
 		}
 
-		if c.Request().Method == http.MethodHead {
-			return c.NoContent(http.StatusOK)
+		// Date validator for clients that don't send ETags
+		lastModified := entry.FetchedAt
+		if weatherStation != nil && time.Unix(weatherStation.LastUpdated, 0).After(lastModified) {
+			lastModified = time.Unix(weatherStation.LastUpdated, 0)
+		}
+		setLastModified(c, lastModified)
+		if notModifiedSince(c, lastModified) {
+			return c.NoContent(http.StatusNotModified)
 		}
 
-		// Return appropriate response format
+		// Render the body even for HEAD so Content-Length matches GET
 		if isJSON {
-			return c.JSON(http.StatusOK, data)
+			body, err := renderJSON(cameraPageJSON(data))
+			if err != nil {
+				return err
+			}
+			return sendBody(c, echo.MIMEApplicationJSONCharsetUTF8, body)
 		}
 
-		return c.Render(http.StatusOK, "camera.html.tmpl", data)
+		body, err := renderTemplate(c, "camera.html.tmpl", data)
+		if err != nil {
+			return err
+		}
+		return sendBody(c, echo.MIMETextHTMLCharsetUTF8, body)
+	}
+}
+
+// ogImageURL returns the path of a camera's social preview image. The slug
+// is preferred since base64 IDs may contain '/'.
+func ogImageURL(camera *store.Camera) string {
+	key := camera.GetSlug()
+	if key == "" {
+		key = camera.ID
 	}
+	return "/camera/" + key + "/og.jpg"
 }
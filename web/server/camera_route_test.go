@@ -0,0 +1,86 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// objectKeys returns the keys of a JSON object in document order, and each
+// nested object's raw value by key
+func objectKeys(t *testing.T, data []byte) ([]string, map[string]json.RawMessage) {
+	t.Helper()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	require.NoError(t, err)
+	require.Equal(t, json.Delim('{'), tok)
+
+	var keys []string
+	values := map[string]json.RawMessage{}
+	for dec.More() {
+		tok, err := dec.Token()
+		require.NoError(t, err)
+		key := tok.(string)
+		var value json.RawMessage
+		require.NoError(t, dec.Decode(&value))
+		keys = append(keys, key)
+		values[key] = value
+	}
+	return keys, values
+}
+
+func TestCameraRoute_JSONFields(t *testing.T) {
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("camera"))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	stationID := 42
+	enabled := true
+	testStore := store.NewStore(&store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "LCC",
+			Cameras: []store.Camera{{
+				Kind:             "webcam",
+				Src:              imageServer.URL + "/cam.jpg",
+				Alt:              "Camera A",
+				Slug:             "cam-a",
+				WeatherStationId: &stationID,
+				Enabled:          &enabled,
+				BrowserUserAgent: true,
+			}},
+		},
+		{ID: "BCC", Name: "BCC"},
+	})
+	testStore.FetchImages(context.Background())
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/camera/cam-a.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	keys, values := objectKeys(t, rec.Body.Bytes())
+	assert.Equal(t, []string{"Camera", "CanyonName", "CanyonTitle", "CanyonPath", "ImageURL", "OGImageURL", "WeatherStation"}, keys)
+
+	cameraKeys, _ := objectKeys(t, values["Camera"])
+	assert.Equal(t, []string{"id", "kind", "src", "alt", "slug", "canyon", "weatherStationId", "width", "height"}, cameraKeys,
+		"config-only fields like enabled and browserUserAgent stay off the wire")
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stefanpenner/lcc-live/web/store"
+)
+
+// Camera statuses reported in canyon JSON
+const (
+	CameraLive  = "live"
+	CameraStale = "stale"
+	CameraDown  = "down"
+)
+
+// DefaultStaleCameraThreshold is how long a camera's image may go unchanged
+// before it's reported stale. Most origins refresh every minute or two.
+const DefaultStaleCameraThreshold = 15 * time.Minute
+
+// staleCameraThreshold returns the server's staleness threshold for a
+// request, falling back to the default for handlers run outside Start
+func staleCameraThreshold(c echo.Context) time.Duration {
+	if threshold, ok := c.Get("_stale_camera_threshold").(time.Duration); ok {
+		return threshold
+	}
+	return DefaultStaleCameraThreshold
+}
+
+// cameraStatus classifies a camera: down if its latest fetch failed or it
+// has never had an image, stale if its image hasn't changed within
+// staleAfter, otherwise live
+func cameraStatus(entry store.EntrySnapshot, history []store.AvailabilityTransition, staleAfter time.Duration, now time.Time) string {
+	if n := len(history); n > 0 && !history[n-1].Available {
+		return CameraDown
+	}
+	if entry.Image == nil || entry.Image.ETag == "" {
+		return CameraDown
+	}
+	if now.Sub(entry.FetchedAt) > staleAfter {
+		return CameraStale
+	}
+	return CameraLive
+}
+
+// cameraStatusSince returns when a camera took on the status cameraStatus
+// gives it: its latest availability transition, or when its image went
+// stale if that's later. It's zero for a camera down since it was added.
+func cameraStatusSince(entry store.EntrySnapshot, history []store.AvailabilityTransition, staleAfter time.Duration, now time.Time) time.Time {
+	var since time.Time
+	if n := len(history); n > 0 {
+		since = history[n-1].Timestamp
+	}
+	if cameraStatus(entry, history, staleAfter, now) == CameraStale {
+		if stale := entry.FetchedAt.Add(staleAfter); stale.After(since) {
+			since = stale
+		}
+	}
+	return since
+}
+
+// withCameraStatuses fills in each fetched camera's Status, returning the
+// cameras and the latest time any of them took on its status. Iframe
+// cameras aren't fetched, so they're left without one.
+func withCameraStatuses(s *store.Store, cameras []store.Camera, staleAfter time.Duration, now time.Time) ([]store.Camera, time.Time) {
+	entries := make(map[string]store.EntrySnapshot)
+	for _, entry := range s.Entries() {
+		entries[entry.ID] = entry
+	}
+	var changed time.Time
+	for i := range cameras {
+		entry, ok := entries[cameras[i].ID]
+		if !ok || !cameras[i].IsFetchable() {
+			continue
+		}
+		history, _ := s.History(cameras[i].ID)
+		cameras[i].Status = cameraStatus(entry, history, staleAfter, now)
+		if since := cameraStatusSince(entry, history, staleAfter, now); since.After(changed) {
+			changed = since
+		}
+	}
+	return cameras, changed
+}
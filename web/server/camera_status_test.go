@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCameraStatus(t *testing.T) {
+	now := time.Now()
+	image := &store.Image{ETag: `"1"`}
+
+	assert.Equal(t, CameraLive, cameraStatus(store.EntrySnapshot{Image: image, FetchedAt: now.Add(-time.Minute)}, nil, 15*time.Minute, now))
+	assert.Equal(t, CameraStale, cameraStatus(store.EntrySnapshot{Image: image, FetchedAt: now.Add(-time.Hour)}, nil, 15*time.Minute, now))
+	assert.Equal(t, CameraDown, cameraStatus(store.EntrySnapshot{Image: &store.Image{}}, nil, 15*time.Minute, now), "never had an image")
+
+	wentDown := []store.AvailabilityTransition{{Timestamp: now, Available: true}, {Timestamp: now, Available: false}}
+	assert.Equal(t, CameraDown, cameraStatus(store.EntrySnapshot{Image: image, FetchedAt: now}, wentDown, 15*time.Minute, now), "latest fetch failed")
+}
+
+func TestCameraStatusSince(t *testing.T) {
+	now := time.Now()
+	image := &store.Image{ETag: `"1"`}
+	fetched := now.Add(-time.Hour)
+	cameUp := []store.AvailabilityTransition{{Timestamp: now.Add(-2 * time.Hour), Available: true}}
+	wentDown := append(cameUp, store.AvailabilityTransition{Timestamp: now.Add(-time.Minute), Available: false})
+
+	assert.Equal(t, fetched.Add(15*time.Minute), cameraStatusSince(store.EntrySnapshot{Image: image, FetchedAt: fetched}, cameUp, 15*time.Minute, now), "stale once the image aged out")
+	assert.Equal(t, cameUp[0].Timestamp, cameraStatusSince(store.EntrySnapshot{Image: image, FetchedAt: fetched}, cameUp, 2*time.Hour, now), "live since it came up")
+	assert.Equal(t, wentDown[1].Timestamp, cameraStatusSince(store.EntrySnapshot{Image: image, FetchedAt: fetched}, wentDown, 15*time.Minute, now), "down since its fetch failed")
+	assert.True(t, cameraStatusSince(store.EntrySnapshot{Image: &store.Image{}}, nil, 15*time.Minute, now).IsZero(), "never had an image")
+}
+
+func TestCanyonRoute_CameraStatus(t *testing.T) {
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down.jpg" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("status"))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	testStore := store.NewStore(&store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "Little Cottonwood Canyon",
+			Cameras: []store.Camera{
+				{Kind: "img", Src: imageServer.URL + "/cam.jpg", Alt: "Status Camera"},
+				{Kind: "img", Src: imageServer.URL + "/down.jpg", Alt: "Down Camera"},
+				{Kind: "iframe", Src: imageServer.URL + "/embed.html", Alt: "Embedded"},
+			},
+		},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	})
+	testStore.FetchImages(context.Background())
+
+	statuses := func(threshold time.Duration) map[string]string {
+		app, err := Start(ServerConfig{
+			Store:                testStore,
+			StaticFS:             fstest.MapFS{},
+			TemplateFS:           fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+			StaleCameraThreshold: threshold,
+		})
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.json", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var canyon store.Canyon
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &canyon))
+		result := map[string]string{}
+		for _, camera := range canyon.Cameras {
+			result[camera.Alt] = camera.Status
+		}
+		return result
+	}
+
+	assert.Equal(t, map[string]string{
+		"Status Camera": CameraLive,
+		"Down Camera":   CameraDown,
+		"Embedded":      "",
+	}, statuses(time.Hour))
+
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, CameraStale, statuses(time.Millisecond)["Status Camera"], "not updated within the window")
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stefanpenner/lcc-live/web/store"
+)
+
+// CameraListItem summarizes a configured camera for admin tooling
+type CameraListItem struct {
+	ID      string `json:"id"`
+	Slug    string `json:"slug"`
+	Name    string `json:"name"`
+	Kind    string `json:"kind"`
+	Canyon  string `json:"canyon"`
+	Enabled bool   `json:"enabled"`
+}
+
+const (
+	// maxCameraSearchResults bounds a filtered /_/cameras response
+	maxCameraSearchResults = 50
+	// maxCameraQueryLength caps the q parameter, in bytes, before it's
+	// normalized
+	maxCameraQueryLength = 100
+)
+
+// CamerasRoute lists every configured camera, including disabled ones.
+// ?q= keeps cameras whose name or slug contains the query (compared as
+// slugs, so "Alta Bypass" matches "alta-bypass") and ?canyon= those in that
+// canyon; a filtered list returns at most maxCameraSearchResults cameras.
+func CamerasRoute(s *store.Store) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		query := c.QueryParam("q")
+		if len(query) > maxCameraQueryLength {
+			query = query[:maxCameraQueryLength]
+		}
+		query = slugify(query)
+		canyon := strings.TrimSpace(c.QueryParam("canyon"))
+		filtered := query != "" || canyon != ""
+
+		entries := s.Entries()
+		cameras := make([]CameraListItem, 0, len(entries))
+		for _, entry := range entries {
+			if canyon != "" && !strings.EqualFold(entry.Camera.Canyon, canyon) {
+				continue
+			}
+			slug := entry.Camera.GetSlug()
+			if query != "" && !strings.Contains(slug, query) && !strings.Contains(slugify(entry.Camera.Alt), query) {
+				continue
+			}
+			if filtered && len(cameras) == maxCameraSearchResults {
+				break
+			}
+			cameras = append(cameras, CameraListItem{
+				ID:      entry.Camera.ID,
+				Slug:    slug,
+				Name:    entry.Camera.Alt,
+				Kind:    entry.Camera.Kind,
+				Canyon:  entry.Camera.Canyon,
+				Enabled: entry.Camera.IsEnabled(),
+			})
+		}
+		return c.JSON(http.StatusOK, cameras)
+	}
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisabledCamera(t *testing.T) {
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("test image"))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	disabled := false
+	canyons := &store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "Little Cottonwood Canyon",
+			Cameras: []store.Camera{
+				{Kind: "img", Src: imageServer.URL + "/live.jpg", Alt: "Live Camera"},
+				{Kind: "img", Src: imageServer.URL + "/broken.jpg", Alt: "Broken Camera", Enabled: &disabled},
+			},
+		},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	}
+	testStore := store.NewStore(canyons)
+	testStore.FetchImages(context.Background())
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+
+	disabledID := (*canyons)[0].Cameras[1].ID
+
+	t.Run("image returns 404", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/image/"+disabledID, nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("camera page returns 404", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/camera/broken-camera", nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("excluded from canyon JSON", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/lcc.json", nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var canyon store.Canyon
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &canyon))
+		require.Len(t, canyon.Cameras, 1)
+		assert.Equal(t, "Live Camera", canyon.Cameras[0].Alt)
+	})
+
+	t.Run("listed in /_/cameras as disabled", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/_/cameras", nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var cameras []CameraListItem
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &cameras))
+		require.Len(t, cameras, 2)
+		assert.True(t, cameras[0].Enabled)
+		assert.Equal(t, "broken-camera", cameras[1].Slug)
+		assert.False(t, cameras[1].Enabled)
+	})
+}
+
+func TestCamerasRoute_Search(t *testing.T) {
+	many := make([]store.Camera, 0, maxCameraSearchResults+10)
+	for i := range maxCameraSearchResults + 10 {
+		many = append(many, store.Camera{Kind: "img", Src: fmt.Sprintf("https://example.com/road-%d.jpg", i), Alt: fmt.Sprintf("Road %d", i)})
+	}
+	app, err := Start(ServerConfig{
+		Store: store.NewStore(&store.Canyons{
+			{
+				ID:   "LCC",
+				Name: "Little Cottonwood Canyon",
+				Cameras: []store.Camera{
+					{Kind: "img", Src: "https://example.com/alta.jpg", Alt: "Alta Bypass"},
+					{Kind: "img", Src: "https://example.com/snowbird.jpg", Alt: "Snowbird Entry 1", Slug: "entry-one"},
+				},
+			},
+			{
+				ID:   "BCC",
+				Name: "Big Cottonwood Canyon",
+				Cameras: append([]store.Camera{
+					{Kind: "img", Src: "https://example.com/brighton.jpg", Alt: "Brighton Alta View"},
+				}, many...),
+			},
+		}),
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+
+	search := func(t *testing.T, query string) []string {
+		req := httptest.NewRequest(http.MethodGet, "/_/cameras"+query, nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var cameras []CameraListItem
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &cameras))
+		names := make([]string, len(cameras))
+		for i, camera := range cameras {
+			names[i] = camera.Name
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"Alta Bypass", "Brighton Alta View"}, search(t, "?q=alta"))
+	assert.Equal(t, []string{"Alta Bypass"}, search(t, "?q=ALTA&canyon=lcc"))
+	assert.Equal(t, []string{"Alta Bypass"}, search(t, "?q=Alta%20Bypass"), "queries are compared as slugs")
+	assert.Equal(t, []string{"Snowbird Entry 1"}, search(t, "?q=entry-one"), "custom slugs match too")
+	assert.Equal(t, []string{"Alta Bypass", "Snowbird Entry 1"}, search(t, "?canyon=LCC"))
+	assert.Empty(t, search(t, "?q=solitude"))
+	assert.Empty(t, search(t, "?canyon=nope"))
+
+	assert.Len(t, search(t, "?canyon=BCC"), maxCameraSearchResults, "filtered results are bounded")
+	assert.Len(t, search(t, ""), maxCameraSearchResults+13, "the unfiltered list is complete")
+}
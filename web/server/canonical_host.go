@@ -0,0 +1,35 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// CanonicalHostMiddleware 301s requests for any host other than canonical
+// (e.g. the bare IP, a staging domain or www.) to the same path and query on
+// the canonical host. canonical is a host, defaulting to https, or a
+// scheme://host URL. Health probes and /_/ endpoints are never redirected.
+func CanonicalHostMiddleware(canonical string) (echo.MiddlewareFunc, error) {
+	if !strings.Contains(canonical, "://") {
+		canonical = "https://" + canonical
+	}
+	target, err := url.Parse(canonical)
+	if err != nil || target.Host == "" || (target.Scheme != "http" && target.Scheme != "https") {
+		return nil, fmt.Errorf("invalid canonical host %q", canonical)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			path := req.URL.Path
+			if strings.EqualFold(req.Host, target.Host) || probePaths[path] || strings.HasPrefix(path, "/_/") {
+				return next(c)
+			}
+			return c.Redirect(http.StatusMovedPermanently, target.Scheme+"://"+target.Host+req.URL.RequestURI())
+		}
+	}, nil
+}
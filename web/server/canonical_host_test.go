@@ -0,0 +1,60 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStart_CanonicalHost(t *testing.T) {
+	app, err := Start(ServerConfig{
+		Store:         store.NewStore(&store.Canyons{{ID: "LCC", Name: "Little Cottonwood Canyon"}}),
+		StaticFS:      fstest.MapFS{},
+		TemplateFS:    fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+		CanonicalHost: "lcc.live",
+	})
+	require.NoError(t, err)
+
+	request := func(host, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("mismatched host redirects", func(t *testing.T) {
+		for _, host := range []string{"www.lcc.live", "203.0.113.1:3000", "staging.example.com"} {
+			rec := request(host, "/robots.txt?a=1&b=2")
+			assert.Equal(t, http.StatusMovedPermanently, rec.Code, host)
+			assert.Equal(t, "https://lcc.live/robots.txt?a=1&b=2", rec.Header().Get("Location"), host)
+		}
+	})
+
+	t.Run("canonical host passes through", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("lcc.live", "/robots.txt").Code)
+		assert.Equal(t, http.StatusOK, request("LCC.live", "/robots.txt").Code)
+	})
+
+	t.Run("exempt paths pass through", func(t *testing.T) {
+		for _, path := range []string{"/healthcheck", "/livez", "/_/version"} {
+			assert.NotEqual(t, http.StatusMovedPermanently, request("10.0.0.5:3000", path).Code, path)
+		}
+	})
+}
+
+func TestCanonicalHostMiddleware_Config(t *testing.T) {
+	for _, valid := range []string{"lcc.live", "https://lcc.live", "http://localhost:3000"} {
+		_, err := CanonicalHostMiddleware(valid)
+		assert.NoError(t, err, valid)
+	}
+	for _, invalid := range []string{"ftp://lcc.live", "https://", "://lcc.live"} {
+		_, err := CanonicalHostMiddleware(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stefanpenner/lcc-live/web/store"
+)
+
+// CanyonFeed is everything a client needs to render a canyon, in one document
+type CanyonFeed struct {
+	ID              string                           `json:"id"`
+	Name            string                           `json:"name"`
+	Title           string                           `json:"title"`
+	Status          store.Camera                     `json:"status"`
+	Cameras         []store.Camera                   `json:"cameras"`
+	RoadConditions  []store.RoadCondition            `json:"roadConditions"`
+	Events          []store.Event                    `json:"events"`
+	WeatherStations map[string]*store.WeatherStation `json:"weatherStations"`
+}
+
+// CanyonFeedRoute serves a canyon's cameras, road conditions, events and
+// weather stations as one JSON document (GET /api/v1/:canyon), so clients
+// need a single round trip and always see a consistent snapshot. The ETag
+// covers every section.
+func CanyonFeedRoute(s *store.Store) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		// Accept the lowercase form used in page paths (e.g. /api/v1/lcc)
+		canyonID := c.Param("canyon")
+		canyon, ok := s.Canyon(strings.ToUpper(canyonID))
+		if !ok {
+			return errorResponse(c, http.StatusBadRequest, "Invalid canyon: "+canyonID)
+		}
+
+		visible := *canyon
+		visible.Cameras = withImageDimensions(s, canyon.EnabledCameras())
+		roadConditions := SortRoadConditions(FilterRoadConditions(s.GetRoadConditions(canyon.ID)))
+		events := SortEvents(s.GetEvents(canyon.ID))
+		weatherStations := s.GetWeatherStationsForCanyon(&visible)
+
+		c.Response().Header().Set("Content-Type", echo.MIMEApplicationJSONCharsetUTF8)
+		_, shouldReturn304, err := SetCacheHeaders(c, CacheConfig{
+			Components: []interface{}{
+				&visible,
+				roadConditions,
+				events,
+				weatherStations,
+			},
+			DevMode: c.Get("_dev_mode") != nil,
+		})
+		if err != nil {
+			return err
+		}
+		if shouldReturn304 {
+			return c.NoContent(http.StatusNotModified)
+		}
+
+		body, err := renderJSON(CanyonFeed{
+			ID:              canyon.ID,
+			Name:            canyon.Name,
+			Title:           canyon.GetTitle(),
+			Status:          canyon.Status,
+			Cameras:         proxyCameraSrcs(c, visible.Cameras),
+			RoadConditions:  roadConditions,
+			Events:          events,
+			WeatherStations: weatherStations,
+		})
+		if err != nil {
+			return err
+		}
+		return sendBody(c, echo.MIMEApplicationJSONCharsetUTF8, body)
+	}
+}
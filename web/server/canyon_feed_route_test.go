@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanyonFeedRoute(t *testing.T) {
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write(testImage("feed"))
+	}))
+	t.Cleanup(imageServer.Close)
+
+	stationID := 7
+	testStore := store.NewStore(&store.Canyons{
+		{
+			ID:    "LCC",
+			Name:  "LCC",
+			Title: "Little Cottonwood Canyon",
+			Cameras: []store.Camera{
+				{Kind: "img", Src: imageServer.URL + "/cam.jpg", Alt: "Feed Camera", WeatherStationId: &stationID},
+			},
+		},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	})
+	testStore.FetchImages(context.Background())
+	testStore.UpdateRoadConditions("LCC", []store.RoadCondition{
+		{Id: 2, RoadwayName: "SR-210 Alta to Snowbird"},
+		{Id: 1, RoadwayName: "SR-210 Mouth of Little Cottonwood to SR-190"}, // filtered out
+	})
+	testStore.UpdateEvents("LCC", []store.Event{{ID: "event-1", Description: "Avalanche control"}})
+	testStore.StoreWeatherStationsById([]store.WeatherStation{{Id: stationID, StationName: "Alta"}})
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+
+	get := func(path, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/api/v1/lcc", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json; charset=UTF-8", rec.Header().Get("Content-Type"))
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	var feed CanyonFeed
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &feed))
+	assert.Equal(t, "LCC", feed.ID)
+	assert.Equal(t, "Little Cottonwood Canyon", feed.Title)
+	require.Len(t, feed.Cameras, 1)
+	assert.Equal(t, "http://example.com/image/"+feed.Cameras[0].ID, feed.Cameras[0].Src)
+	assert.Equal(t, 1, feed.Cameras[0].Width)
+	require.Len(t, feed.RoadConditions, 1)
+	assert.Equal(t, 2, feed.RoadConditions[0].Id)
+	require.Len(t, feed.Events, 1)
+	assert.Equal(t, "event-1", feed.Events[0].ID)
+	require.Contains(t, feed.WeatherStations, feed.Cameras[0].ID)
+	assert.Equal(t, "Alta", feed.WeatherStations[feed.Cameras[0].ID].StationName)
+
+	t.Run("ETag is stable", func(t *testing.T) {
+		assert.Equal(t, etag, get("/api/v1/lcc", "").Header().Get("ETag"))
+		assert.Equal(t, etag, get("/api/v1/LCC", "").Header().Get("ETag"))
+	})
+
+	t.Run("matching If-None-Match returns 304", func(t *testing.T) {
+		rec := get("/api/v1/lcc", etag)
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+		assert.Empty(t, rec.Body.String())
+	})
+
+	t.Run("ETag changes with any section", func(t *testing.T) {
+		testStore.UpdateEvents("LCC", nil)
+		rec := get("/api/v1/lcc", etag)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+	})
+
+	t.Run("invalid canyon", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get("/api/v1/nope", "").Code)
+	})
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stefanpenner/lcc-live/web/metrics"
@@ -13,7 +14,78 @@ type CanyonPageData struct {
 	*store.Canyon
 	RoadConditions  []store.RoadCondition
 	Events          []store.Event
-	WeatherStations map[string]*store.WeatherStation
+	WeatherStations map[string]*store.WeatherStation // camera ID -> its station; cameras without one are absent
+	ImageURLs       map[string]string                // camera ID -> content-addressed image URL
+	PagePath        string                           // canonical path of this page, "/" for the default canyon
+	Nav             []CanyonNavItem
+}
+
+// CanyonJSON is the canyon JSON response: the canyon itself plus a map
+// section locating its cameras
+type CanyonJSON struct {
+	*store.Canyon
+	Map []CameraMapPoint `json:"map"`
+}
+
+// CameraMapPoint locates one camera for map views. Latitude and Longitude
+// are always present, and null until the camera's position is known.
+type CameraMapPoint struct {
+	ID        string   `json:"id"`
+	Slug      string   `json:"slug"`
+	Canyon    string   `json:"canyon"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+}
+
+// cameraMapPoints returns the map section for cameras, in order
+func cameraMapPoints(cameras []store.Camera) []CameraMapPoint {
+	points := make([]CameraMapPoint, len(cameras))
+	for i, camera := range cameras {
+		points[i] = CameraMapPoint{
+			ID:        camera.ID,
+			Slug:      camera.GetSlug(),
+			Canyon:    camera.Canyon,
+			Latitude:  camera.Latitude,
+			Longitude: camera.Longitude,
+		}
+	}
+	return points
+}
+
+// CanyonNavItem is one entry in the canyon switcher
+type CanyonNavItem struct {
+	ID     string
+	Title  string
+	Path   string
+	Active bool
+}
+
+// canyonPagePath returns the canonical page path for a canyon: "/" for the
+// first (default) canyon, otherwise its own path (e.g. "/bcc")
+func canyonPagePath(s *store.Store, canyonID string) string {
+	canyons := s.Canyons()
+	if len(canyons) > 0 && canyons[0].ID == canyonID {
+		return "/"
+	}
+	if canyon, ok := canyons.Get(canyonID); ok {
+		return canyon.Path()
+	}
+	return "/"
+}
+
+// canyonNav builds the canyon switcher, marking activeID as current
+func canyonNav(s *store.Store, activeID string) []CanyonNavItem {
+	canyons := s.Canyons()
+	nav := make([]CanyonNavItem, len(canyons))
+	for i := range canyons {
+		nav[i] = CanyonNavItem{
+			ID:     canyons[i].ID,
+			Title:  canyons[i].GetTitle(),
+			Path:   canyonPagePath(s, canyons[i].ID),
+			Active: canyons[i].ID == activeID,
+		}
+	}
+	return nav
 }
 
 func CanyonRoute(s *store.Store, canyonID string) func(c echo.Context) error {
@@ -21,7 +93,20 @@ func CanyonRoute(s *store.Store, canyonID string) func(c echo.Context) error {
 		// Track page view
 		metrics.PageViewsTotal.WithLabelValues(canyonID).Inc()
 
-		canyon := s.Canyon(canyonID)
+		storeStart := time.Now()
+		canyon, ok := s.Canyon(canyonID)
+		if !ok {
+			if s.IsReloading() {
+				return reloadingResponse(c)
+			}
+			// Removed by a reload since routes were registered
+			return echo.ErrNotFound
+		}
+		setCacheTag(c, canyon.ID, "")
+		// Hide disabled cameras; the canyon ETag already reflects their state
+		visible := *canyon
+		visible.Cameras = withImageDimensions(s, canyon.EnabledCameras())
+		canyon = &visible
 		roadConditions := s.GetRoadConditions(canyonID)
 		// Filter out unwanted road conditions
 		roadConditions = FilterRoadConditions(roadConditions)
@@ -29,6 +114,7 @@ func CanyonRoute(s *store.Store, canyonID string) func(c echo.Context) error {
 
 		// Get weather stations for all cameras (single lock acquisition)
 		weatherStations := s.GetWeatherStationsForCanyon(canyon)
+		timePhase(c, "store", storeStart)
 
 		// Determine response format
 		isJSON := strings.HasSuffix(c.Request().URL.Path, ".json")
@@ -44,13 +130,31 @@ func CanyonRoute(s *store.Store, canyonID string) func(c echo.Context) error {
 		devMode := c.Get("_dev_mode") != nil
 
 		// Build cache config - include all components that affect the response
+		components := []interface{}{
+			canyon,          // Canyon data (cameras, etc.) - uses ETag() method
+			roadConditions,  // Road conditions - hashed with StableJSONHash
+			weatherStations, // Weather stations - hashed with StableJSONHash
+		}
+		lastModified := canyonLastModified(s, canyon, roadConditions, events, weatherStations)
+		if isJSON {
+			// Statuses drift with time rather than with the canyon data, so
+			// they need their own ETag component, and a flip advances
+			// Last-Modified for clients revalidating by date alone
+			var statusChanged time.Time
+			canyon.Cameras, statusChanged = withCameraStatuses(s, canyon.Cameras, staleCameraThreshold(c), time.Now())
+			statuses := make(map[string]string, len(canyon.Cameras))
+			for _, camera := range canyon.Cameras {
+				statuses[camera.ID] = camera.Status
+			}
+			components = append(components, statuses)
+			if statusChanged.After(lastModified) {
+				lastModified = statusChanged
+			}
+		}
 		config := CacheConfig{
-			Components: []interface{}{
-				canyon,          // Canyon data (cameras, etc.) - uses ETag() method
-				roadConditions,  // Road conditions - hashed with StableJSONHash
-				weatherStations, // Weather stations - hashed with StableJSONHash
-			},
-			DevMode: devMode,
+			Components:   components,
+			DevMode:      devMode,
+			LastModified: lastModified,
 		}
 
 		// Set cache headers and check for 304
@@ -62,24 +166,17 @@ func CanyonRoute(s *store.Store, canyonID string) func(c echo.Context) error {
 			return c.NoContent(http.StatusNotModified)
 		}
 
-		if c.Request().Method == http.MethodHead {
-			return c.NoContent(http.StatusOK)
-		}
-
-		// Return appropriate response format
+		// Render the body even for HEAD so Content-Length matches GET
+		renderStart := time.Now()
 		if isJSON {
-			// Rewrite camera Src to proxy URLs so clients (e.g. iOS)
-			// don't hit upstream sources directly (UDOT blocks non-US IPs).
-			scheme := c.Scheme()
 			proxied := *canyon
-			proxied.Cameras = make([]store.Camera, len(canyon.Cameras))
-			for i, cam := range canyon.Cameras {
-				if cam.Kind == "img" {
-					cam.Src = scheme + "://" + c.Request().Host + "/image/" + cam.ID
-				}
-				proxied.Cameras[i] = cam
+			proxied.Cameras = proxyCameraSrcs(c, canyon.Cameras)
+			body, err := renderJSON(CanyonJSON{Canyon: &proxied, Map: cameraMapPoints(canyon.Cameras)})
+			if err != nil {
+				return err
 			}
-			return c.JSON(http.StatusOK, &proxied)
+			timePhase(c, "render", renderStart)
+			return sendBody(c, echo.MIMEApplicationJSONCharsetUTF8, body)
 		}
 
 		pageData := CanyonPageData{
@@ -87,7 +184,83 @@ func CanyonRoute(s *store.Store, canyonID string) func(c echo.Context) error {
 			RoadConditions:  roadConditions,
 			Events:          events,
 			WeatherStations: weatherStations,
+			ImageURLs:       contentImageURLs(s),
+			PagePath:        canyonPagePath(s, canyonID),
+			Nav:             canyonNav(s, canyonID),
+		}
+		body, err := renderTemplate(c, "canyon.html.tmpl", pageData)
+		if err != nil {
+			return err
+		}
+		timePhase(c, "render", renderStart)
+		return sendBody(c, echo.MIMETextHTMLCharsetUTF8, body)
+	}
+}
+
+// canyonLastModified returns the newest change to anything a canyon page
+// shows: its configuration, camera images, road conditions, events and
+// weather stations
+func canyonLastModified(s *store.Store, canyon *store.Canyon, roadConditions []store.RoadCondition, events []store.Event, weatherStations map[string]*store.WeatherStation) time.Time {
+	latest := canyon.LastModified
+	newer := func(t time.Time) {
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	for _, entry := range s.Entries() {
+		if entry.Camera.Canyon == canyon.ID {
+			newer(entry.FetchedAt)
+		}
+	}
+	for _, rc := range roadConditions {
+		newer(time.Unix(rc.LastUpdated, 0))
+	}
+	for _, event := range events {
+		newer(time.Unix(event.LastUpdated, 0))
+	}
+	for _, station := range weatherStations {
+		if station != nil {
+			newer(time.Unix(station.LastUpdated, 0))
 		}
-		return c.Render(http.StatusOK, "canyon.html.tmpl", pageData)
 	}
+	return latest
+}
+
+// withImageDimensions fills in each camera's Width and Height from its
+// latest image, so pages can reserve layout space. cameras must be a copy.
+func withImageDimensions(s *store.Store, cameras []store.Camera) []store.Camera {
+	images := make(map[string]*store.Image)
+	for _, entry := range s.Entries() {
+		images[entry.ID] = entry.Image
+	}
+	for i := range cameras {
+		if image := images[cameras[i].ID]; image != nil {
+			cameras[i].Width, cameras[i].Height = image.Width, image.Height
+		}
+	}
+	return cameras
+}
+
+// proxyCameraSrcs returns a copy of cameras with image Srcs rewritten to our
+// /image/:id proxy, so clients (e.g. iOS) don't hit upstream sources directly
+// (UDOT blocks non-US IPs)
+func proxyCameraSrcs(c echo.Context, cameras []store.Camera) []store.Camera {
+	proxied := make([]store.Camera, len(cameras))
+	for i, cam := range cameras {
+		if cam.Kind == "img" || cam.Kind == "mjpeg" {
+			cam.Src = c.Scheme() + "://" + c.Request().Host + "/image/" + cam.ID
+		}
+		proxied[i] = cam
+	}
+	return proxied
+}
+
+// contentImageURLs maps every camera ID to its content-addressed image URL
+func contentImageURLs(s *store.Store) map[string]string {
+	entries := s.Entries()
+	urls := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		urls[entry.ID] = ContentImageURL(entry)
+	}
+	return urls
 }
@@ -0,0 +1,390 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanyonRoute_HEADContentLength(t *testing.T) {
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("test image"))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	canyons := &store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "Little Cottonwood Canyon",
+			Cameras: []store.Camera{
+				{Kind: "img", Src: imageServer.URL + "/a.jpg", Alt: "Camera A"},
+				{Kind: "img", Src: imageServer.URL + "/b.jpg", Alt: "Camera B"},
+			},
+		},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	}
+	testStore := store.NewStore(canyons)
+	testStore.FetchImages(context.Background())
+
+	app, err := Start(ServerConfig{
+		Store:    testStore,
+		StaticFS: fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{
+			Data: []byte(`<h1>{{.Name}}</h1>{{range .Cameras}}<img alt="{{.Alt}}">{{end}}`),
+		}},
+	})
+	require.NoError(t, err)
+
+	for _, path := range []string{"/", "/.json"} {
+		t.Run(path, func(t *testing.T) {
+			get := httptest.NewRecorder()
+			app.ServeHTTP(get, httptest.NewRequest(http.MethodGet, path, nil))
+			require.Equal(t, http.StatusOK, get.Code)
+
+			head := httptest.NewRecorder()
+			app.ServeHTTP(head, httptest.NewRequest(http.MethodHead, path, nil))
+			require.Equal(t, http.StatusOK, head.Code)
+
+			assert.Empty(t, head.Body.Bytes())
+			assert.Equal(t, strconv.Itoa(get.Body.Len()), head.Header().Get("Content-Length"))
+			assert.Equal(t, get.Header().Get("Content-Type"), head.Header().Get("Content-Type"))
+			assert.Equal(t, get.Header().Get("ETag"), head.Header().Get("ETag"))
+			assert.Equal(t, get.Header().Get("Cache-Control"), head.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func TestCanyonAndCameraRoutes_IfModifiedSince(t *testing.T) {
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("test image"))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	testStore := store.NewStore(&store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "Little Cottonwood Canyon",
+			Cameras: []store.Camera{
+				{Kind: "img", Src: imageServer.URL + "/a.jpg", Alt: "Camera A"},
+			},
+		},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	})
+	testStore.FetchImages(context.Background())
+
+	app, err := Start(ServerConfig{
+		Store:    testStore,
+		StaticFS: fstest.MapFS{},
+		TemplateFS: fstest.MapFS{
+			"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)},
+			"camera.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Camera.Alt}}`)},
+		},
+	})
+	require.NoError(t, err)
+
+	get := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	past := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+
+	for _, path := range []string{"/", "/camera/camera-a"} {
+		t.Run(path, func(t *testing.T) {
+			rec := get(path, nil)
+			require.Equal(t, http.StatusOK, rec.Code)
+			lastModified, err := http.ParseTime(rec.Header().Get("Last-Modified"))
+			require.NoError(t, err)
+			assert.False(t, lastModified.After(time.Now()))
+
+			assert.Equal(t, http.StatusNotModified, get(path, map[string]string{"If-Modified-Since": future}).Code)
+			assert.Equal(t, http.StatusNotModified, get(path, map[string]string{"If-Modified-Since": rec.Header().Get("Last-Modified")}).Code)
+			assert.Equal(t, http.StatusOK, get(path, map[string]string{"If-Modified-Since": past}).Code)
+			assert.Equal(t, http.StatusOK, get(path, map[string]string{"If-Modified-Since": "not a date"}).Code)
+
+			// The ETag stays the primary validator
+			assert.Equal(t, http.StatusOK, get(path, map[string]string{
+				"If-Modified-Since": future,
+				"If-None-Match":     `"stale"`,
+			}).Code)
+		})
+	}
+}
+
+func TestStart_LenientTemplates(t *testing.T) {
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("test image"))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	canyons := &store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "Little Cottonwood Canyon",
+			Cameras: []store.Camera{
+				{Kind: "img", Src: imageServer.URL + "/a.jpg", Alt: "Camera A"},
+			},
+		},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	}
+	testStore := store.NewStore(canyons)
+	testStore.FetchImages(context.Background())
+
+	broken := fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name`)}}
+
+	_, err := Start(ServerConfig{Store: testStore, StaticFS: fstest.MapFS{}, TemplateFS: broken})
+	require.Error(t, err, "strict mode fails on a broken template")
+
+	app, err := Start(ServerConfig{Store: testStore, StaticFS: fstest.MapFS{}, TemplateFS: broken, LenientTemplates: true})
+	require.NoError(t, err)
+
+	for path, want := range map[string]string{
+		"/":                "Little Cottonwood Canyon",
+		"/camera/camera-a": "Camera A",
+		"/image/camera-a":  "test image",
+	} {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+		assert.Contains(t, rec.Body.String(), want, path)
+	}
+}
+
+func TestCanyonRoutes_ThreeCanyons(t *testing.T) {
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("image " + r.URL.Path))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	canyons := &store.Canyons{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"lcc": {"name": "LCC", "title": "Little Cottonwood Canyon", "cameras": [{"kind": "img", "src": "`+imageServer.URL+`/lcc.jpg", "alt": "LCC Cam"}]},
+		"bcc": {"name": "BCC", "title": "Big Cottonwood Canyon", "cameras": [{"kind": "img", "src": "`+imageServer.URL+`/bcc.jpg", "alt": "BCC Cam"}]},
+		"afc": {"name": "AFC", "title": "American Fork Canyon", "cameras": [{"kind": "img", "src": "`+imageServer.URL+`/afc.jpg", "alt": "AFC Cam"}]}
+	}`), canyons))
+	testStore := store.NewStore(canyons)
+	testStore.FetchImages(context.Background())
+
+	app, err := Start(ServerConfig{
+		Store:    testStore,
+		StaticFS: fstest.MapFS{},
+		TemplateFS: fstest.MapFS{
+			"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.GetTitle}}|{{.PagePath}}|{{range .Nav}}{{.ID}}{{if .Active}}*{{end}} {{end}}`)},
+		},
+	})
+	require.NoError(t, err)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	for path, want := range map[string]string{
+		"/":    "Little Cottonwood Canyon|/|LCC* BCC AFC ",
+		"/lcc": "Little Cottonwood Canyon|/|LCC* BCC AFC ",
+		"/bcc": "Big Cottonwood Canyon|/bcc|LCC BCC* AFC ",
+		"/afc": "American Fork Canyon|/afc|LCC BCC AFC* ",
+	} {
+		rec := get(path)
+		require.Equal(t, http.StatusOK, rec.Code, path)
+		assert.Equal(t, want, rec.Body.String(), path)
+	}
+
+	rec := get("/afc.json")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var canyon store.Canyon
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &canyon))
+	assert.Equal(t, "AFC", canyon.Name)
+	require.Len(t, canyon.Cameras, 1)
+	assert.Equal(t, "AFC Cam", canyon.Cameras[0].Alt)
+	assert.Equal(t, "AFC", canyon.Cameras[0].Canyon)
+
+	assert.Equal(t, http.StatusOK, get("/api/canyon/AFC/udot").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/api/canyon/PC/udot").Code)
+	assert.Equal(t, http.StatusNotFound, get("/pc").Code)
+
+	sitemap := get("/sitemap.xml").Body.String()
+	assert.Contains(t, sitemap, "/afc</loc>")
+	assert.Contains(t, sitemap, "/camera/afc-cam</loc>")
+}
+
+func TestCanyonAndCameraJSON_ImageDimensions(t *testing.T) {
+	var img bytes.Buffer
+	require.NoError(t, png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 40, 30))))
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(img.Bytes())
+	}))
+	t.Cleanup(imageServer.Close)
+
+	canyons := &store.Canyons{
+		{
+			ID:      "LCC",
+			Name:    "Little Cottonwood Canyon",
+			Cameras: []store.Camera{{Kind: "img", Src: imageServer.URL + "/a.png", Alt: "Camera A"}},
+		},
+	}
+	testStore := store.NewStore(canyons)
+	testStore.FetchImages(context.Background())
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{range .Cameras}}{{.Width}}x{{.Height}}{{end}}`)}},
+	})
+	require.NoError(t, err)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, rec.Code, path)
+		return rec
+	}
+
+	assert.Equal(t, "40x30", get("/").Body.String())
+
+	var canyon store.Canyon
+	require.NoError(t, json.Unmarshal(get("/lcc.json").Body.Bytes(), &canyon))
+	require.Len(t, canyon.Cameras, 1)
+	assert.Equal(t, 40, canyon.Cameras[0].Width)
+	assert.Equal(t, 30, canyon.Cameras[0].Height)
+
+	var page CameraPageJSON
+	require.NoError(t, json.Unmarshal(get("/camera/camera-a.json").Body.Bytes(), &page))
+	assert.Equal(t, 40, page.Camera.Width)
+	assert.Equal(t, 30, page.Camera.Height)
+
+	// The store's configuration isn't mutated
+	assert.Zero(t, mustCanyon(t, testStore, "LCC").Cameras[0].Width)
+}
+
+func TestCanyonRoute_JSONMap(t *testing.T) {
+	stationID := 7
+	testStore := store.NewStore(&store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "LCC",
+			Cameras: []store.Camera{
+				{Kind: "iframe", Src: "https://example.com/a", Alt: "Camera A", WeatherStationId: &stationID},
+				{Kind: "iframe", Src: "https://example.com/b", Alt: "Camera B"},
+			},
+		},
+		{ID: "BCC", Name: "BCC"},
+	})
+	testStore.FetchImages(context.Background())
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+
+	getMap := func(t *testing.T) (string, []map[string]any) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.json", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		var body struct {
+			Name string           `json:"name"`
+			Map  []map[string]any `json:"map"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, "LCC", body.Name, "the canyon's own fields are unchanged")
+		return rec.Header().Get("ETag"), body.Map
+	}
+
+	etag, points := getMap(t)
+	require.Len(t, points, 2)
+	assert.Equal(t, map[string]any{
+		"id": points[0]["id"], "slug": "camera-a", "canyon": "LCC", "latitude": nil, "longitude": nil,
+	}, points[0], "unknown positions are null, not omitted")
+
+	latitude, longitude := 40.57, -111.65
+	testStore.StoreWeatherStationsById([]store.WeatherStation{{Id: stationID, Latitude: &latitude, Longitude: &longitude}})
+
+	newETag, points := getMap(t)
+	assert.NotEqual(t, etag, newETag, "moved cameras refresh cached canyon JSON")
+	assert.Equal(t, latitude, points[0]["latitude"])
+	assert.Equal(t, longitude, points[0]["longitude"])
+	assert.Nil(t, points[1]["latitude"])
+}
+
+func TestCanyonRoute_WeatherStations(t *testing.T) {
+	stationID := 7
+	testStore := store.NewStore(&store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "LCC",
+			Cameras: []store.Camera{
+				{Kind: "iframe", Src: "https://example.com/a", Alt: "Camera A", WeatherStationId: &stationID},
+				{Kind: "iframe", Src: "https://example.com/b", Alt: "Camera B"},
+			},
+		},
+		{ID: "BCC", Name: "BCC"},
+	})
+	testStore.FetchImages(context.Background())
+	airTemp := "30.6"
+	testStore.StoreWeatherStationsById([]store.WeatherStation{{Id: stationID, AirTemperature: &airTemp}})
+
+	page := `{{range .Cameras}}{{.Alt}}: {{with index $.WeatherStations .ID}}{{formatTemp .AirTemperature}}{{else}}none{{end}}; {{end}}`
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(page)}},
+	})
+	require.NoError(t, err)
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "Camera A: 31°F; Camera B: none; ", rec.Body.String(), "cameras without a station render without weather")
+	etag := rec.Header().Get("ETag")
+	assert.Equal(t, http.StatusNotModified, get(etag).Code)
+
+	colder := "25"
+	testStore.StoreWeatherStationsById([]store.WeatherStation{{Id: stationID, AirTemperature: &colder}})
+
+	rec = get(etag)
+	require.Equal(t, http.StatusOK, rec.Code, "a weather update refreshes cached pages")
+	assert.Equal(t, "Camera A: 25°F; Camera B: none; ", rec.Body.String())
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDevModeTestStore(t *testing.T) *store.Store {
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("test image"))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	testStore := store.NewStore(&store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "Little Cottonwood Canyon",
+			Cameras: []store.Camera{
+				{Kind: "img", Src: imageServer.URL + "/a.jpg", Alt: "Camera A"},
+			},
+		},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	})
+	testStore.FetchImages(context.Background())
+	return testStore
+}
+
+func TestDevMode_TemplateHotReload(t *testing.T) {
+	for _, devMode := range []bool{true, false} {
+		name := "production parses once"
+		if devMode {
+			name = "dev mode re-parses"
+		}
+		t.Run(name, func(t *testing.T) {
+			templates := fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`before {{.Name}}`)}}
+			app, err := Start(ServerConfig{
+				Store:      newDevModeTestStore(t),
+				StaticFS:   fstest.MapFS{},
+				TemplateFS: templates,
+				DevMode:    devMode,
+			})
+			require.NoError(t, err)
+
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			require.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "before Little Cottonwood Canyon", rec.Body.String())
+
+			// Edit the template on "disk"
+			templates["canyon.html.tmpl"] = &fstest.MapFile{Data: []byte(`after {{.Name}}`)}
+
+			rec = httptest.NewRecorder()
+			app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			require.Equal(t, http.StatusOK, rec.Code)
+			if devMode {
+				assert.Equal(t, "after Little Cottonwood Canyon", rec.Body.String())
+			} else {
+				assert.Equal(t, "before Little Cottonwood Canyon", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestDevMode_CanyonResponsesUncacheable(t *testing.T) {
+	testStore := newDevModeTestStore(t)
+	templates := fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}}
+
+	devApp, err := Start(ServerConfig{Store: testStore, StaticFS: fstest.MapFS{}, TemplateFS: templates, DevMode: true})
+	require.NoError(t, err)
+	prodApp, err := Start(ServerConfig{Store: testStore, StaticFS: fstest.MapFS{}, TemplateFS: templates})
+	require.NoError(t, err)
+
+	for _, path := range []string{"/", "/bcc", "/lcc.json", "/bcc.json"} {
+		t.Run(path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			devApp.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			require.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "no-cache, no-store, must-revalidate, private", rec.Header().Get("Cache-Control"))
+			assert.Equal(t, "no-cache", rec.Header().Get("Pragma"))
+			assert.Empty(t, rec.Header().Get("ETag"))
+
+			rec = httptest.NewRecorder()
+			prodApp.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			require.Equal(t, http.StatusOK, rec.Code)
+			assert.Contains(t, rec.Header().Get("Cache-Control"), "public")
+			assert.NotEmpty(t, rec.Header().Get("ETag"))
+		})
+	}
+}
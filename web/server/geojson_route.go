@@ -0,0 +1,119 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stefanpenner/lcc-live/web/store"
+)
+
+const mimeGeoJSON = "application/geo+json"
+
+// GeoJSONGeometry is a Point or LineString geometry
+type GeoJSONGeometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// GeoJSONFeature is a single map feature
+type GeoJSONFeature struct {
+	Type       string          `json:"type"`
+	Geometry   GeoJSONGeometry `json:"geometry"`
+	Properties map[string]any  `json:"properties"`
+}
+
+// GeoJSONFeatureCollection is the document served by ConditionsGeoJSONRoute
+type GeoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []GeoJSONFeature `json:"features"`
+}
+
+// polylineGeometry decodes an encoded polyline into a LineString, reporting
+// false when it is empty or invalid
+func polylineGeometry(encoded string) (GeoJSONGeometry, bool) {
+	coordinates, err := store.DecodePolyline(encoded)
+	if err != nil || len(coordinates) < 2 {
+		return GeoJSONGeometry{}, false
+	}
+	return GeoJSONGeometry{Type: "LineString", Coordinates: coordinates}, true
+}
+
+// conditionsGeoJSON converts road conditions and events into features.
+// Conditions without a usable polyline are dropped; events fall back to
+// their reported point.
+func conditionsGeoJSON(roadConditions []store.RoadCondition, events []store.Event) GeoJSONFeatureCollection {
+	collection := GeoJSONFeatureCollection{Type: "FeatureCollection", Features: []GeoJSONFeature{}}
+
+	for _, rc := range roadConditions {
+		geometry, ok := polylineGeometry(rc.EncodedPolyline)
+		if !ok {
+			continue
+		}
+		collection.Features = append(collection.Features, GeoJSONFeature{
+			Type:     "Feature",
+			Geometry: geometry,
+			Properties: map[string]any{
+				"kind":             "roadCondition",
+				"id":               rc.Id,
+				"roadwayName":      rc.RoadwayName,
+				"roadCondition":    rc.RoadCondition,
+				"weatherCondition": rc.WeatherCondition,
+				"restriction":      rc.Restriction,
+				"lastUpdated":      rc.LastUpdated,
+			},
+		})
+	}
+
+	for _, event := range events {
+		geometry, ok := polylineGeometry(event.EncodedPolyline)
+		if !ok {
+			if event.Latitude == 0 && event.Longitude == 0 {
+				continue
+			}
+			geometry = GeoJSONGeometry{Type: "Point", Coordinates: [2]float64{event.Longitude, event.Latitude}}
+		}
+		collection.Features = append(collection.Features, GeoJSONFeature{
+			Type:     "Feature",
+			Geometry: geometry,
+			Properties: map[string]any{
+				"kind":          "event",
+				"id":            event.ID,
+				"roadwayName":   event.RoadwayName,
+				"eventType":     event.EventType,
+				"description":   event.Description,
+				"isFullClosure": event.IsFullClosure,
+				"lastUpdated":   event.LastUpdated,
+			},
+		})
+	}
+
+	return collection
+}
+
+// ConditionsGeoJSONRoute serves a canyon's road conditions and events as a
+// GeoJSON FeatureCollection (GET /:canyon/conditions.geojson), ready for
+// Leaflet or Mapbox without client-side polyline decoding
+func ConditionsGeoJSONRoute(s *store.Store, canyonID string) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		roadConditions := SortRoadConditions(FilterRoadConditions(s.GetRoadConditions(canyonID)))
+		events := SortEvents(s.GetEvents(canyonID))
+
+		c.Response().Header().Set("Content-Type", mimeGeoJSON)
+		_, shouldReturn304, err := SetCacheHeaders(c, CacheConfig{
+			Components: []interface{}{roadConditions, events},
+			DevMode:    c.Get("_dev_mode") != nil,
+		})
+		if err != nil {
+			return err
+		}
+		if shouldReturn304 {
+			return c.NoContent(http.StatusNotModified)
+		}
+
+		body, err := renderJSON(conditionsGeoJSON(roadConditions, events))
+		if err != nil {
+			return err
+		}
+		return sendBody(c, mimeGeoJSON, body)
+	}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditionsGeoJSON(t *testing.T) {
+	collection := conditionsGeoJSON(
+		[]store.RoadCondition{
+			{Id: 2, RoadwayName: "SR-210 Alta to Snowbird", RoadCondition: "Wet", EncodedPolyline: "_p~iF~ps|U_ulLnnqC"},
+			{Id: 3, RoadwayName: "No geometry"},
+			{Id: 4, RoadwayName: "Bad geometry", EncodedPolyline: "_p~iF"},
+		},
+		[]store.Event{
+			{ID: "event-1", Description: "Avalanche control", Latitude: 40.58, Longitude: -111.65},
+			{ID: "event-2", Description: "Nowhere"},
+		},
+	)
+
+	assert.Equal(t, "FeatureCollection", collection.Type)
+	require.Len(t, collection.Features, 2)
+
+	condition := collection.Features[0]
+	assert.Equal(t, "Feature", condition.Type)
+	assert.Equal(t, "LineString", condition.Geometry.Type)
+	assert.Equal(t, [][2]float64{{-120.2, 38.5}, {-120.95, 40.7}}, condition.Geometry.Coordinates)
+	assert.Equal(t, "roadCondition", condition.Properties["kind"])
+	assert.Equal(t, "Wet", condition.Properties["roadCondition"])
+
+	event := collection.Features[1]
+	assert.Equal(t, "Point", event.Geometry.Type)
+	assert.Equal(t, [2]float64{-111.65, 40.58}, event.Geometry.Coordinates)
+	assert.Equal(t, "event-1", event.Properties["id"])
+}
+
+func TestConditionsGeoJSONRoute(t *testing.T) {
+	testStore := store.NewStore(&store.Canyons{
+		{ID: "LCC", Name: "Little Cottonwood Canyon"},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	})
+	testStore.UpdateRoadConditions("LCC", []store.RoadCondition{
+		{Id: 2, RoadwayName: "SR-210 Alta to Snowbird", EncodedPolyline: "_p~iF~ps|U_ulLnnqC"},
+	})
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/lcc/conditions.geojson", nil)
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/geo+json", rec.Header().Get("Content-Type"))
+	assert.NotEmpty(t, rec.Header().Get("ETag"))
+
+	var collection struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Geometry struct {
+				Type        string       `json:"type"`
+				Coordinates [][2]float64 `json:"coordinates"`
+			} `json:"geometry"`
+		} `json:"features"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &collection))
+	assert.Equal(t, "FeatureCollection", collection.Type)
+	require.Len(t, collection.Features, 1)
+	assert.Equal(t, "LineString", collection.Features[0].Geometry.Type)
+	assert.Len(t, collection.Features[0].Geometry.Coordinates, 2)
+
+	t.Run("conditional request", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/lcc/conditions.geojson", nil)
+		req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+		rec2 := httptest.NewRecorder()
+		app.ServeHTTP(rec2, req)
+		assert.Equal(t, http.StatusNotModified, rec2.Code)
+	})
+
+	t.Run("empty canyon", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/bcc/conditions.geojson", nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"type":"FeatureCollection","features":[]}`, rec.Body.String())
+	})
+}
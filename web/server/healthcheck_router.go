@@ -5,12 +5,100 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/stefanpenner/lcc-live/web/metrics"
 	"github.com/stefanpenner/lcc-live/web/store"
 )
 
-func HealthCheckRoute(store *store.Store) func(c echo.Context) error {
+// DefaultHealthCheckSmokeTTL is how long a smoke-test verdict is reused, so
+// probes every second or two don't each render every canyon page
+const DefaultHealthCheckSmokeTTL = 5 * time.Second
+
+// DefaultDegradedThreshold is the fraction of cameras that may fail a sync
+// before /healthcheck reports the service degraded
+const DefaultDegradedThreshold = 0.25
+
+// HealthStatus is the structured /healthcheck body, sent when the service is
+// degraded or the client asks for JSON
+type HealthStatus struct {
+	Status   string `json:"status"` // "ok" or "degraded"
+	Degraded bool   `json:"degraded"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// degradedTracker decides from each sync whether too many cameras are down.
+// A degraded service still passes the probe; it just says so.
+type degradedTracker struct {
+	threshold float64
+	mu        sync.Mutex
+	reason    string // empty unless the latest sync was degraded
+}
+
+func (d *degradedTracker) observe(result store.SyncResult) {
+	reason := ""
+	fetched := result.Changed + result.Unchanged + result.Errors
+	if result.Errors > 0 && float64(result.Errors) >= d.threshold*float64(fetched) {
+		reason = fmt.Sprintf("%d of %d cameras failed the last sync", result.Errors, fetched)
+	}
+
+	d.mu.Lock()
+	d.reason = reason
+	d.mu.Unlock()
+
+	if reason != "" {
+		metrics.ServiceDegraded.Set(1)
+	} else {
+		metrics.ServiceDegraded.Set(0)
+	}
+}
+
+func (d *degradedTracker) degradedReason() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.reason
+}
+
+// smokeTestCache remembers the last smoke-test verdict for ttl. The lock is
+// held while a test runs, so concurrent probes share a single render.
+type smokeTestCache struct {
+	ttl       time.Duration
+	mu        sync.Mutex
+	checkedAt time.Time
+	err       error
+}
+
+// check returns the cached verdict if it's fresh, otherwise runs test
+func (s *smokeTestCache) check(test func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checkedAt.IsZero() && time.Since(s.checkedAt) < s.ttl {
+		return s.err
+	}
+	s.err = test()
+	s.checkedAt = time.Now()
+	return s.err
+}
+
+// HealthCheckRoute reports readiness and, at most once per smokeTTL (zero
+// uses DefaultHealthCheckSmokeTTL), smoke-tests that every canyon page
+// renders. The readiness checks run on every call. If at least
+// degradedThreshold of the cameras (zero uses DefaultDegradedThreshold)
+// failed the latest sync, the probe still passes but the body reports the
+// service degraded.
+func HealthCheckRoute(store *store.Store, smokeTTL time.Duration, degradedThreshold float64) func(c echo.Context) error {
+	if smokeTTL <= 0 {
+		smokeTTL = DefaultHealthCheckSmokeTTL
+	}
+	if degradedThreshold <= 0 {
+		degradedThreshold = DefaultDegradedThreshold
+	}
+	smoke := &smokeTestCache{ttl: smokeTTL}
+	degraded := &degradedTracker{threshold: degradedThreshold}
+	store.OnSync(degraded.observe)
+
 	return func(c echo.Context) error {
 		// Verify that the store is initialized and has completed
 		// its initial image fetch before declaring the service healthy
@@ -18,32 +106,60 @@ func HealthCheckRoute(store *store.Store) func(c echo.Context) error {
 			return c.String(http.StatusServiceUnavailable, "Service starting up - images not ready yet")
 		}
 
-		// Verify store has cameras loaded (basic sanity check)
-		lcc := store.Canyon("LCC")
-		bcc := store.Canyon("BCC")
-		
-		if len(lcc.Cameras) == 0 && len(bcc.Cameras) == 0 {
-			return c.String(http.StatusServiceUnavailable, "No cameras configured")
+		// An empty canyon is reported but only fails the probe if every
+		// canyon is empty
+		canyons := store.Canyons()
+		report, anyCameras := canyonReadiness(canyons)
+		if !anyCameras {
+			return c.String(http.StatusServiceUnavailable, "No cameras configured"+report)
 		}
 
-		// Smoke test: verify that LCC and BCC routes can render HTML
+		// Smoke test: verify that every canyon route can render HTML
 		// This catches template errors, data issues, and rendering pipeline problems
 		e := c.Echo()
-		
-		// Test LCC route
-		if err := testRoute(e, "/", "Little Cottonwood Canyon"); err != nil {
-			return c.String(http.StatusServiceUnavailable, 
-				fmt.Sprintf("Healthcheck failed - LCC route error: %v", err))
+		err := smoke.check(func() error {
+			for _, canyon := range canyons {
+				if err := testRoute(e, canyon.Path(), canyon.Name); err != nil {
+					return fmt.Errorf("%s route error: %w", canyon.ID, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return c.String(http.StatusServiceUnavailable, "Healthcheck failed - "+err.Error()+report)
+		}
+
+		if reason := degraded.degradedReason(); reason != "" {
+			return c.JSON(http.StatusOK, HealthStatus{Status: "degraded", Degraded: true, Reason: reason})
 		}
-		
-		// Test BCC route
-		if err := testRoute(e, "/bcc", "Big Cottonwood Canyon"); err != nil {
-			return c.String(http.StatusServiceUnavailable, 
-				fmt.Sprintf("Healthcheck failed - BCC route error: %v", err))
+		if wantsJSON(c) {
+			return c.JSON(http.StatusOK, HealthStatus{Status: "ok"})
 		}
+		return c.String(http.StatusOK, "OK"+report)
+	}
+}
 
-		return c.String(http.StatusOK, "OK")
+// canyonReadiness lists each canyon's enabled camera count, one per line,
+// if any canyon is empty (a healthy service just reports OK), and reports
+// whether any canyon has cameras
+func canyonReadiness(canyons store.Canyons) (string, bool) {
+	var report strings.Builder
+	anyCameras, anyEmpty := false, false
+	for i := range canyons {
+		canyon := &canyons[i]
+		count := len(canyon.EnabledCameras())
+		if count == 0 {
+			anyEmpty = true
+			fmt.Fprintf(&report, "\n%s: no cameras configured", canyon.ID)
+			continue
+		}
+		anyCameras = true
+		fmt.Fprintf(&report, "\n%s: %d camera(s)", canyon.ID, count)
+	}
+	if !anyEmpty {
+		return "", true
 	}
+	return report.String(), anyCameras
 }
 
 // testRoute performs an internal HTTP request to verify a route can render successfully
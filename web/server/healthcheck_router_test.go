@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stefanpenner/lcc-live/web/metrics"
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthCheckRoute_CachesSmokeTest(t *testing.T) {
+	srv := setupTestServer(t)
+
+	// Every smoke test renders each canyon page once
+	renders := func() float64 {
+		return testutil.ToFloat64(metrics.PageViewsTotal.WithLabelValues("LCC")) +
+			testutil.ToFloat64(metrics.PageViewsTotal.WithLabelValues("BCC"))
+	}
+	probe := func() {
+		rec := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthcheck", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	before := renders()
+	probe()
+	afterFirst := renders()
+	assert.Equal(t, before+2, afterFirst)
+
+	for range 5 {
+		probe()
+	}
+	assert.Equal(t, afterFirst, renders(), "probes within the TTL reuse the verdict")
+}
+
+func TestSmokeTestCache_Expires(t *testing.T) {
+	cache := &smokeTestCache{ttl: 20 * time.Millisecond}
+	runs := 0
+	test := func() error {
+		runs++
+		return nil
+	}
+
+	require.NoError(t, cache.check(test))
+	require.NoError(t, cache.check(test))
+	assert.Equal(t, 1, runs)
+
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, cache.check(test))
+	assert.Equal(t, 2, runs, "an expired verdict is re-checked")
+}
+
+func TestHealthCheckRoute_EmptyCanyon(t *testing.T) {
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("test image"))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	testStore := store.NewStore(&store.Canyons{
+		{ID: "LCC", Name: "Little Cottonwood Canyon", Cameras: []store.Camera{
+			{Kind: "img", Src: imageServer.URL + "/a.jpg", Alt: "Camera A"},
+		}},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	})
+	testStore.FetchImages(context.Background())
+
+	// The fallback pages render a real HTML document for the smoke test
+	app, err := Start(ServerConfig{
+		Store:            testStore,
+		StaticFS:         fstest.MapFS{},
+		TemplateFS:       fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name`)}},
+		LenientTemplates: true,
+	})
+	require.NoError(t, err)
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	t.Run("healthcheck stays up and reports the empty canyon", func(t *testing.T) {
+		rec := get("/healthcheck")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "OK\nLCC: 1 camera(s)\nBCC: no cameras configured", rec.Body.String())
+	})
+
+	t.Run("empty canyon renders an empty state", func(t *testing.T) {
+		rec := get("/bcc")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "No cameras are available for Big Cottonwood Canyon")
+	})
+}
+
+func TestHealthCheckRoute_Degraded(t *testing.T) {
+	var failing atomic.Int32 // how many of the cameras 404
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/")); n < int(failing.Load()) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == "GET" {
+			w.Write(testImage(r.URL.Path))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	var cameras []store.Camera
+	for i := range 4 {
+		cameras = append(cameras, store.Camera{Kind: "img", Src: fmt.Sprintf("%s/%d", imageServer.URL, i), Alt: fmt.Sprintf("Camera %d", i)})
+	}
+	testStore := store.NewStore(&store.Canyons{{ID: "LCC", Name: "Little Cottonwood Canyon", Cameras: cameras}})
+
+	app, err := Start(ServerConfig{
+		Store:             testStore,
+		StaticFS:          fstest.MapFS{},
+		TemplateFS:        fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name`)}},
+		LenientTemplates:  true,
+		DegradedThreshold: 0.5,
+	})
+	require.NoError(t, err)
+	probe := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/healthcheck", nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("unhealthy until the first sync", func(t *testing.T) {
+		assert.Equal(t, http.StatusServiceUnavailable, probe("").Code)
+	})
+
+	t.Run("healthy", func(t *testing.T) {
+		failing.Store(1)
+		testStore.FetchImages(context.Background())
+
+		rec := probe("")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "OK", rec.Body.String(), "a minority of failures is below the threshold")
+		assert.Equal(t, 0.0, testutil.ToFloat64(metrics.ServiceDegraded))
+
+		var status HealthStatus
+		require.NoError(t, json.Unmarshal(probe("application/json").Body.Bytes(), &status))
+		assert.Equal(t, HealthStatus{Status: "ok"}, status)
+	})
+
+	t.Run("degraded", func(t *testing.T) {
+		failing.Store(2)
+		testStore.FetchImages(context.Background())
+
+		rec := probe("")
+		assert.Equal(t, http.StatusOK, rec.Code, "a degraded service still serves")
+		var status HealthStatus
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+		assert.Equal(t, HealthStatus{Status: "degraded", Degraded: true, Reason: "2 of 4 cameras failed the last sync"}, status)
+		assert.Equal(t, 1.0, testutil.ToFloat64(metrics.ServiceDegraded))
+	})
+
+	t.Run("recovers", func(t *testing.T) {
+		failing.Store(0)
+		testStore.FetchImages(context.Background())
+
+		assert.Equal(t, "OK", probe("").Body.String())
+		assert.Equal(t, 0.0, testutil.ToFloat64(metrics.ServiceDegraded))
+	})
+}
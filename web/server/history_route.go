@@ -0,0 +1,28 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stefanpenner/lcc-live/web/store"
+)
+
+// HistoryData lists a camera's availability transitions, oldest first
+type HistoryData struct {
+	ID          string                         `json:"id"`
+	Transitions []store.AvailabilityTransition `json:"transitions"`
+}
+
+// CameraHistoryRoute serves the recent up/down transitions for a camera
+func CameraHistoryRoute(s *store.Store) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		entry, exists := s.Get(c.Param("id"))
+		if !exists {
+			return errorResponse(c, http.StatusNotFound, "Camera not found")
+		}
+
+		history, _ := s.History(entry.ID)
+		c.Response().Header().Set("Cache-Control", cachePolicy(c).API.CacheControl())
+		return c.JSON(http.StatusOK, HistoryData{ID: entry.ID, Transitions: history})
+	}
+}
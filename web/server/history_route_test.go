@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCameraHistoryRoute(t *testing.T) {
+	var down atomic.Bool
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("image"))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	canyons := &store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "Little Cottonwood Canyon",
+			Cameras: []store.Camera{
+				{Kind: "webcam", Src: imageServer.URL + "/cam.jpg", Alt: "Flaky Camera"},
+			},
+		},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	}
+	testStore := store.NewStore(canyons)
+	testStore.FetchImages(context.Background())
+	down.Store(true)
+	testStore.FetchImages(context.Background())
+	down.Store(false)
+	testStore.FetchImages(context.Background())
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/camera/flaky-camera/history.json", nil)
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var data HistoryData
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &data))
+	require.Len(t, data.Transitions, 3)
+	assert.True(t, data.Transitions[0].Available)
+	assert.False(t, data.Transitions[1].Available)
+	assert.True(t, data.Transitions[2].Available)
+
+	t.Run("unknown camera", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/camera/nope/history.json", nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/png" // register the decoder for icon sizes
+	"io/fs"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	staticCacheControl  = "public, max-age=86400, immutable"
+	mimeWebAppManifest  = "application/manifest+json"
+	faviconFile         = "favicon.png"
+	appleTouchIconFile  = "apple-touch-icon.png"
+	manifestThemeColor  = "#667eea" // matches the theme-color meta tag
+	manifestBgColor     = "#f8fafc"
+	manifestDisplayName = "LCC.live"
+)
+
+// setStaticCacheHeaders caches static assets for a day, or not at all in dev
+// mode so edits show up on reload. The long lifetime is safe because static
+// files rarely change, and HTML pages (cache-busted via version ETags)
+// reference new URLs when they do.
+func setStaticCacheHeaders(c echo.Context, devMode bool) {
+	if devMode {
+		c.Response().Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+		c.Response().Header().Set("Pragma", "no-cache")
+		c.Response().Header().Set("Expires", "0")
+		return
+	}
+	c.Response().Header().Set("Cache-Control", staticCacheControl)
+}
+
+// StaticFileRoute serves one file from staticFS at a fixed path, such as
+// /favicon.ico, which browsers request whether or not a page links it.
+// The Content-Type follows the served file, so a PNG can stand in for .ico.
+func StaticFileRoute(staticFS fs.FS, name string, devMode bool) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		if _, err := fs.Stat(staticFS, name); err != nil {
+			return echo.ErrNotFound
+		}
+		setStaticCacheHeaders(c, devMode)
+		http.ServeFileFS(c.Response(), c.Request(), staticFS, name)
+		return nil
+	}
+}
+
+// ManifestIcon is an icon entry in the web app manifest
+type ManifestIcon struct {
+	Src   string `json:"src"`
+	Sizes string `json:"sizes"`
+	Type  string `json:"type"`
+}
+
+// WebAppManifest is the subset of the web app manifest needed to install
+// the site as an app
+type WebAppManifest struct {
+	Name            string         `json:"name"`
+	ShortName       string         `json:"short_name"`
+	StartURL        string         `json:"start_url"`
+	Display         string         `json:"display"`
+	BackgroundColor string         `json:"background_color"`
+	ThemeColor      string         `json:"theme_color"`
+	Icons           []ManifestIcon `json:"icons"`
+}
+
+// manifestIcons lists the icons in staticFS with their pixel sizes, skipping
+// any that are missing or can't be decoded
+func manifestIcons(staticFS fs.FS) []ManifestIcon {
+	icons := []ManifestIcon{}
+	for _, icon := range []struct{ name, path string }{
+		{faviconFile, "/favicon.ico"},
+		{appleTouchIconFile, "/" + appleTouchIconFile},
+	} {
+		f, err := staticFS.Open(icon.name)
+		if err != nil {
+			continue
+		}
+		config, format, err := image.DecodeConfig(f)
+		_ = f.Close()
+		if err != nil {
+			continue
+		}
+		icons = append(icons, ManifestIcon{
+			Src:   icon.path,
+			Sizes: fmt.Sprintf("%dx%d", config.Width, config.Height),
+			Type:  "image/" + format,
+		})
+	}
+	return icons
+}
+
+// ManifestRoute serves /manifest.webmanifest so the site can be installed
+// as an app
+func ManifestRoute(staticFS fs.FS, devMode bool) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		body, err := json.Marshal(WebAppManifest{
+			Name:            manifestDisplayName,
+			ShortName:       manifestDisplayName,
+			StartURL:        "/",
+			Display:         "standalone",
+			BackgroundColor: manifestBgColor,
+			ThemeColor:      manifestThemeColor,
+			Icons:           manifestIcons(staticFS),
+		})
+		if err != nil {
+			return err
+		}
+		setStaticCacheHeaders(c, devMode)
+		return c.Blob(http.StatusOK, mimeWebAppManifest, body)
+	}
+}
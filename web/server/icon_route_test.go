@@ -0,0 +1,101 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPNG(t *testing.T, size int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, size, size))))
+	return buf.Bytes()
+}
+
+func TestIconRoutes(t *testing.T) {
+	favicon := testPNG(t, 32)
+	touchIcon := testPNG(t, 180)
+	newApp := func(t *testing.T, staticFS fstest.MapFS, devMode bool) http.Handler {
+		t.Helper()
+		app, err := Start(ServerConfig{
+			Store:      store.NewStore(&store.Canyons{{ID: "LCC", Name: "LCC"}, {ID: "BCC", Name: "BCC"}}),
+			StaticFS:   staticFS,
+			TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+			DevMode:    devMode,
+		})
+		require.NoError(t, err)
+		return app
+	}
+	app := newApp(t, fstest.MapFS{
+		faviconFile:        &fstest.MapFile{Data: favicon},
+		appleTouchIconFile: &fstest.MapFile{Data: touchIcon},
+	}, false)
+	get := func(app http.Handler, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("favicon.ico", func(t *testing.T) {
+		rec := get(app, "/favicon.ico")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+		assert.Equal(t, staticCacheControl, rec.Header().Get("Cache-Control"))
+		assert.Contains(t, rec.Header().Get("Cache-Control"), "immutable")
+		assert.Equal(t, favicon, rec.Body.Bytes())
+	})
+
+	t.Run("apple touch icons", func(t *testing.T) {
+		for _, path := range []string{"/apple-touch-icon.png", "/apple-touch-icon-precomposed.png"} {
+			rec := get(app, path)
+			require.Equal(t, http.StatusOK, rec.Code, path)
+			assert.Equal(t, "image/png", rec.Header().Get("Content-Type"), path)
+			assert.Equal(t, touchIcon, rec.Body.Bytes(), path)
+		}
+	})
+
+	t.Run("manifest", func(t *testing.T) {
+		rec := get(app, "/manifest.webmanifest")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, mimeWebAppManifest, rec.Header().Get("Content-Type"))
+		assert.Equal(t, staticCacheControl, rec.Header().Get("Cache-Control"))
+
+		var manifest WebAppManifest
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &manifest))
+		assert.Equal(t, "/", manifest.StartURL)
+		assert.Equal(t, "standalone", manifest.Display)
+		assert.Equal(t, []ManifestIcon{
+			{Src: "/favicon.ico", Sizes: "32x32", Type: "image/png"},
+			{Src: "/apple-touch-icon.png", Sizes: "180x180", Type: "image/png"},
+		}, manifest.Icons)
+	})
+
+	t.Run("dev mode isn't cached", func(t *testing.T) {
+		dev := newApp(t, fstest.MapFS{faviconFile: &fstest.MapFile{Data: favicon}}, true)
+		rec := get(dev, "/favicon.ico")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Header().Get("Cache-Control"), "no-cache")
+	})
+
+	t.Run("missing icon", func(t *testing.T) {
+		empty := newApp(t, fstest.MapFS{}, false)
+		rec := get(empty, "/favicon.ico")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.NotContains(t, rec.Header().Get("Cache-Control"), "immutable")
+
+		rec = get(empty, "/manifest.webmanifest")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"icons":[]`)
+	})
+}
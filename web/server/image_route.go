@@ -1,63 +1,220 @@
 package server
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/cespare/xxhash/v2"
 	"github.com/labstack/echo/v4"
 	"github.com/stefanpenner/lcc-live/web/metrics"
 	"github.com/stefanpenner/lcc-live/web/store"
 )
 
-func ImageRoute(store *store.Store) func(c echo.Context) error {
+// imageRetryAfter is the Retry-After (seconds) sent while a known camera has
+// no image; roughly one sync interval
+const imageRetryAfter = "5"
+
+// reloadRetryAfter is the Retry-After (seconds) sent for lookups that miss
+// while the store is reloading; reloads finish well within it
+const reloadRetryAfter = "1"
+
+// reloadingResponse answers a lookup that missed during a store reload. The
+// camera or canyon may be about to appear, so a short 503 beats a 404 that
+// clients and caches would believe.
+func reloadingResponse(c echo.Context) error {
+	c.Response().Header().Set("Retry-After", reloadRetryAfter)
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.String(http.StatusServiceUnavailable, "reloading, try again shortly")
+}
+
+// coldFetchTimeout bounds the on-demand fetch for a camera that has never
+// been fetched, so a slow origin can't hold the request for long
+const coldFetchTimeout = 2 * time.Second
+
+// placeholderFile is the static file served in place of a missing image
+const placeholderFile = "camera-offline.svg"
+
+// wantsPlaceholder reports whether the client would rather render a
+// placeholder image than an error, i.e. it's an <img> request
+func wantsPlaceholder(c echo.Context) bool {
+	return c.QueryParam("placeholder") == "1" || strings.Contains(c.Request().Header.Get("Accept"), "image/")
+}
+
+// ImageRoute serves a camera's latest image. If placeholder is non-nil it's
+// served (as SVG) to image requests for cameras that have no image yet.
+// JPEG and PNG frames are transcoded to WebP for clients that accept it.
+func ImageRoute(store *store.Store, placeholder []byte) func(c echo.Context) error {
+	placeholderETag := "\"" + strconv.FormatUint(xxhash.Sum64(placeholder), 10) + "\""
+	webpImages := newWebPCache(maxWebPImages)
+
 	return func(c echo.Context) error {
+		start := time.Now()
 		id := c.Param("id")
+		if t := c.QueryParam("t"); t != "" {
+			return serveFrame(c, store, id, t)
+		}
 		entry, exists := store.Get(id)
+		timePhase(c, "store", start)
+		if !exists && store.IsReloading() {
+			return reloadingResponse(c)
+		}
+		if !exists || !entry.Camera.IsEnabled() {
+			return c.String(http.StatusNotFound, "image not found")
+		}
 
-		status := http.StatusNotFound
+		// Track image view
+		cameraName := entry.Camera.Alt
+		if cameraName == "" {
+			cameraName = entry.Camera.ID
+		}
+		metrics.ImageViewsTotal.WithLabelValues(cameraName, entry.Camera.Canyon).Inc()
 
-		if exists {
-			// Track image view
-			cameraName := entry.Camera.Alt
-			if cameraName == "" {
-				cameraName = entry.Camera.ID
-			}
-			metrics.ImageViewsTotal.WithLabelValues(cameraName, entry.Camera.Canyon).Inc()
-			if entry.HTTPHeaders.Status == http.StatusOK {
-				headers := entry.HTTPHeaders
-
-				c.Response().Header().Set("Content-Type", headers.ContentType)
-				// See web/docs/caching.md for analysis of max-age tradeoffs.
-				c.Response().Header().Set("Cache-Control", "public, max-age=3, stale-while-revalidate=120")
-				c.Response().Header().Set("ETag", entry.Image.ETag)
-				c.Response().Header().Set("Content-Length", fmt.Sprintf("%d", headers.ContentLength))
-				if !entry.FetchedAt.IsZero() {
-					c.Response().Header().Set("Last-Modified", entry.FetchedAt.UTC().Format(time.RFC1123))
+		// A cold camera (e.g. one just added by a reload) is fetched now
+		// rather than at the next sync; concurrent requests share the fetch.
+		// Once any fetch has been tried, a camera that's down is left to the
+		// sync loop, so requests for it don't each wait on its origin.
+		if entry.HTTPHeaders.Status != http.StatusOK && entry.Image.ETag == "" && entry.AttemptedAt.IsZero() {
+			ctx, cancel := context.WithTimeout(c.Request().Context(), coldFetchTimeout)
+			err := store.FetchOne(ctx, entry.ID)
+			cancel()
+			if err == nil {
+				// A reload may have removed the camera meanwhile
+				if fetched, ok := store.Get(entry.ID); ok {
+					entry = fetched
 				}
+			}
+		}
 
-				if ifNoneMatch := c.Request().Header.Get("If-None-Match"); ifNoneMatch != "" {
-					if ifNoneMatch == entry.Image.ETag {
-						// Track cache hit
-						metrics.CacheHits.WithLabelValues(c.Path()).Inc()
-						return c.NoContent(http.StatusNotModified)
-					}
-				}
-				if c.Request().Method == http.MethodHead {
-					return c.NoContent(http.StatusOK)
-				} else {
-					// Track response size
-					metrics.ResponseSizeBytes.WithLabelValues(c.Path()).Observe(float64(len(entry.Image.Bytes)))
-					return c.Blob(http.StatusOK, headers.ContentType, entry.Image.Bytes)
+		if entry.HTTPHeaders.Status == http.StatusOK {
+			// Cameras with alternate Sources serve the size (?w=) and
+			// format (Accept) that suit the client best
+			width, _ := strconv.Atoi(c.QueryParam("w"))
+			accept := c.Request().Header.Get("Accept")
+			served := selectImage(entry, width, accept)
+			if len(entry.Variants) > 0 || canTranscodeToWebP(entry.HTTPHeaders.ContentType) {
+				c.Response().Header().Add("Vary", "Accept")
+			}
+			if canTranscodeToWebP(served.contentType) && acceptsWebP(accept) {
+				// Frames that won't decode are served as they are
+				if transcoded, err := webpImages.transcode(entry.ID, served); err == nil {
+					served = transcoded
 				}
 			}
-			status = entry.HTTPHeaders.Status
+
+			c.Response().Header().Set("Content-Type", served.contentType)
+			setCacheTag(c, entry.Camera.Canyon, entry.Camera.GetSlug())
+			// See web/docs/caching.md for analysis of max-age tradeoffs.
+			c.Response().Header().Set("Cache-Control", cachePolicy(c).Image.CacheControl())
+			c.Response().Header().Set("ETag", served.image.ETag)
+			c.Response().Header().Set("Content-Length", fmt.Sprintf("%d", served.contentLength))
+			if !entry.FetchedAt.IsZero() {
+				c.Response().Header().Set("Last-Modified", entry.FetchedAt.UTC().Format(time.RFC1123))
+			}
+
+			if notModified(c, served.image.ETag) {
+				// Track cache hit
+				metrics.CacheHits.WithLabelValues(c.Path()).Inc()
+				markTiming(c, "cache", "hit")
+				err := c.NoContent(http.StatusNotModified)
+				metrics.ImageServeDuration.WithLabelValues("not_modified").Observe(time.Since(start).Seconds())
+				return err
+			}
+			markTiming(c, "cache", "miss")
+			c.Response().Header().Set("Accept-Ranges", "bytes")
+			if c.Request().Method == http.MethodHead {
+				return c.NoContent(http.StatusOK)
+			} else {
+				// Track response size
+				metrics.ResponseSizeBytes.WithLabelValues(c.Path()).Observe(float64(len(served.image.Bytes)))
+				err := serveImageBytes(c, served.image.Bytes)
+				metrics.ImageServeDuration.WithLabelValues("full").Observe(time.Since(start).Seconds())
+				return err
+			}
+		}
+
+		// Whether a missing image gets the placeholder or a 503 depends on
+		// Accept, so caches must keep the two apart
+		if placeholder != nil {
+			c.Response().Header().Add("Vary", "Accept")
+		}
+
+		// Keep <img> grids intact with a short-lived placeholder; its ETag
+		// differs from any real frame so pollers swap it out once one arrives
+		if placeholder != nil && wantsPlaceholder(c) {
+			c.Response().Header().Set("Cache-Control", "public, max-age=3")
+			c.Response().Header().Set("ETag", placeholderETag)
+			if c.Request().Method == http.MethodHead {
+				c.Response().Header().Set("Content-Type", "image/svg+xml")
+				return c.NoContent(http.StatusOK)
+			}
+			return c.Blob(http.StatusOK, "image/svg+xml", placeholder)
+		}
+
+		// The camera exists but we have no image for it yet (e.g. at startup,
+		// or its origin has been failing), so ask clients to retry
+		c.Response().Header().Set("Retry-After", imageRetryAfter)
+		return c.String(http.StatusServiceUnavailable, "image not available yet")
+	}
+}
+
+// ContentImageURL returns the content-addressed URL for a camera's current
+// frame, or the live /image/:id URL (with a placeholder fallback) if no frame
+// has been fetched yet
+func ContentImageURL(entry store.EntrySnapshot) string {
+	if entry.Image == nil || entry.Image.ETag == "" {
+		return "/image/" + entry.ID + "?placeholder=1"
+	}
+	return "/image/" + entry.ID + "/" + trimETag(entry.Image.ETag)
+}
+
+// ImageByETagRoute serves a camera frame at a content-addressed URL. Since
+// the bytes behind /image/:id/:etag never change, they can be cached forever;
+// requests for a frame that is no longer current redirect to the current one.
+func ImageByETagRoute(store *store.Store) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		entry, exists := store.Get(c.Param("id"))
+		if !exists && store.IsReloading() {
+			return reloadingResponse(c)
+		}
+		if !exists || !entry.Camera.IsEnabled() || entry.HTTPHeaders.Status != http.StatusOK {
+			return c.String(http.StatusNotFound, "image not found")
 		}
 
-		// Ensure we have a valid HTTP status code
-		if status == 0 {
-			status = http.StatusNotFound
+		if trimETag(entry.Image.ETag) != c.Param("etag") {
+			c.Response().Header().Set("Cache-Control", "public, max-age=3")
+			return c.Redirect(http.StatusFound, ContentImageURL(entry))
 		}
-		return c.String(status, "image not found")
+
+		headers := entry.HTTPHeaders
+		c.Response().Header().Set("Content-Type", headers.ContentType)
+		c.Response().Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		setCacheTag(c, entry.Camera.Canyon, entry.Camera.GetSlug())
+		c.Response().Header().Set("ETag", entry.Image.ETag)
+		c.Response().Header().Set("Content-Length", fmt.Sprintf("%d", headers.ContentLength))
+
+		if notModified(c, entry.Image.ETag) {
+			metrics.CacheHits.WithLabelValues(c.Path()).Inc()
+			return c.NoContent(http.StatusNotModified)
+		}
+		c.Response().Header().Set("Accept-Ranges", "bytes")
+		if c.Request().Method == http.MethodHead {
+			return c.NoContent(http.StatusOK)
+		}
+		metrics.ResponseSizeBytes.WithLabelValues(c.Path()).Observe(float64(len(entry.Image.Bytes)))
+		return serveImageBytes(c, entry.Image.Bytes)
 	}
 }
+
+// serveImageBytes sends an image whose Content-Type and ETag headers are
+// already set, honoring Range. http.ServeContent checks If-Range against
+// that ETag, so a client resuming a frame that has since changed gets the
+// new frame whole rather than a splice of the two.
+func serveImageBytes(c echo.Context, image []byte) error {
+	http.ServeContent(c.Response(), c.Request(), "", time.Time{}, bytes.NewReader(image))
+	return nil
+}
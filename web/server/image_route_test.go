@@ -0,0 +1,553 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stefanpenner/lcc-live/web/metrics"
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageByETagRoute(t *testing.T) {
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("test image"))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	canyons := &store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "Little Cottonwood Canyon",
+			Cameras: []store.Camera{
+				{Kind: "img", Src: imageServer.URL + "/cam.jpg", Alt: "Immutable Camera"},
+			},
+		},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	}
+	testStore := store.NewStore(canyons)
+	testStore.FetchImages(context.Background())
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{range .Cameras}}{{index $.ImageURLs .ID}}{{end}}`)}},
+	})
+	require.NoError(t, err)
+
+	entry, exists := testStore.Get((*canyons)[0].Cameras[0].ID)
+	require.True(t, exists)
+	current := ContentImageURL(entry)
+	assert.True(t, strings.HasSuffix(current, "/"+strings.Trim(entry.Image.ETag, "\"")))
+
+	t.Run("canyon page emits content-addressed URLs", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), current)
+	})
+
+	t.Run("matching etag", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, current, nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "public, max-age=31536000, immutable", rec.Header().Get("Cache-Control"))
+		assert.Equal(t, entry.Image.ETag, rec.Header().Get("ETag"))
+		assert.Equal(t, string(testImage("test image")), rec.Body.String())
+	})
+
+	t.Run("stale etag redirects to current frame", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/image/"+entry.ID+"/12345", nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusFound, rec.Code)
+		assert.Equal(t, current, rec.Header().Get("Location"))
+		assert.NotContains(t, rec.Header().Get("Cache-Control"), "immutable")
+	})
+
+	t.Run("unknown camera", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/image/nope/12345", nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+// imageServeCount returns how many serves ImageServeDuration has observed
+// with the given result
+func imageServeCount(t *testing.T, result string) uint64 {
+	var pb dto.Metric
+	require.NoError(t, metrics.ImageServeDuration.WithLabelValues(result).(prometheus.Metric).Write(&pb))
+	return pb.GetHistogram().GetSampleCount()
+}
+
+func TestImageRoute_ServeDuration(t *testing.T) {
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("timed image"))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	testStore := store.NewStore(&store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "Little Cottonwood Canyon",
+			Cameras: []store.Camera{
+				{Kind: "img", Src: imageServer.URL + "/cam.jpg", Alt: "Timed Camera"},
+			},
+		},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	})
+	testStore.FetchImages(context.Background())
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+
+	fullBefore := imageServeCount(t, "full")
+	notModifiedBefore := imageServeCount(t, "not_modified")
+
+	req := httptest.NewRequest(http.MethodGet, "/image/timed-camera", nil)
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/image/timed-camera", nil)
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNotModified, rec.Code)
+
+	assert.Equal(t, fullBefore+1, imageServeCount(t, "full"))
+	assert.Equal(t, notModifiedBefore+1, imageServeCount(t, "not_modified"))
+}
+
+func TestImageRoute_FetchesColdCamera(t *testing.T) {
+	var up atomic.Bool
+	var fetches atomic.Int32
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if !up.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("late image"))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	lateCamera := store.Camera{Kind: "img", Src: imageServer.URL + "/cam.jpg", Alt: "Late Camera"}
+	newApp := func(t *testing.T, cameras ...store.Camera) (*store.Store, http.Handler) {
+		testStore := store.NewStore(&store.Canyons{
+			{ID: "LCC", Name: "Little Cottonwood Canyon", Cameras: cameras},
+			{ID: "BCC", Name: "Big Cottonwood Canyon"},
+		})
+		app, err := Start(ServerConfig{
+			Store:      testStore,
+			StaticFS:   fstest.MapFS{},
+			TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+		})
+		require.NoError(t, err)
+		return testStore, app
+	}
+
+	t.Run("added by a reload", func(t *testing.T) {
+		up.Store(true)
+		testStore, app := newApp(t)
+		testStore.FetchImages(context.Background())
+		require.NoError(t, testStore.Reload(&store.Canyons{
+			{ID: "LCC", Name: "Little Cottonwood Canyon", Cameras: []store.Camera{lateCamera}},
+			{ID: "BCC", Name: "Big Cottonwood Canyon"},
+		}))
+
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/image/late-camera", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, string(testImage("late image")), rec.Body.String())
+	})
+
+	t.Run("down after a sync is left to the sync loop", func(t *testing.T) {
+		up.Store(false)
+		testStore, app := newApp(t, lateCamera)
+		// The first sync fails, leaving the camera without an image
+		testStore.FetchImages(context.Background())
+		fetches.Store(0)
+
+		for range 3 {
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/image/late-camera", nil))
+			assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		}
+		assert.Zero(t, fetches.Load(), "requests don't refetch a camera that's down")
+	})
+}
+
+func TestImageRoute_NotFoundVsUnavailable(t *testing.T) {
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(imageServer.Close)
+
+	canyons := &store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "Little Cottonwood Canyon",
+			Cameras: []store.Camera{
+				{Kind: "img", Src: imageServer.URL + "/down.jpg", Alt: "Down Camera"},
+			},
+		},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	}
+	testStore := store.NewStore(canyons)
+	testStore.FetchImages(context.Background())
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+
+	t.Run("known camera without an image", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/image/down-camera", nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, imageRetryAfter, rec.Header().Get("Retry-After"))
+	})
+
+	t.Run("unknown camera", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/image/does-not-exist", nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Empty(t, rec.Header().Get("Retry-After"))
+	})
+}
+
+func TestImageRoute_Placeholder(t *testing.T) {
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(imageServer.Close)
+
+	canyons := &store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "Little Cottonwood Canyon",
+			Cameras: []store.Camera{
+				{Kind: "img", Src: imageServer.URL + "/down.jpg", Alt: "Down Camera"},
+			},
+		},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	}
+	testStore := store.NewStore(canyons)
+	testStore.FetchImages(context.Background())
+
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`)
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{placeholderFile: &fstest.MapFile{Data: svg}},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+
+	t.Run("query flag", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/image/down-camera?placeholder=1", nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "image/svg+xml", rec.Header().Get("Content-Type"))
+		assert.Equal(t, "public, max-age=3", rec.Header().Get("Cache-Control"))
+		assert.NotEmpty(t, rec.Header().Get("ETag"))
+		assert.Equal(t, svg, rec.Body.Bytes())
+	})
+
+	t.Run("image Accept header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/image/down-camera", nil)
+		req.Header.Set("Accept", "image/avif,image/webp,*/*")
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "image/svg+xml", rec.Header().Get("Content-Type"))
+		assert.Equal(t, "Accept", rec.Header().Get("Vary"))
+	})
+
+	t.Run("other clients still get 503", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/image/down-camera", nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, "Accept", rec.Header().Get("Vary"), "caches mustn't hand this to <img> requests")
+	})
+
+	t.Run("unknown camera is still 404", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/image/nope?placeholder=1", nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestImageRoute_SkipsGzip(t *testing.T) {
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage(strings.Repeat("compressible ", 200)))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	canyons := &store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "Little Cottonwood Canyon",
+			Cameras: []store.Camera{
+				{Kind: "img", Src: imageServer.URL + "/cam.jpg", Alt: "Gzip Camera"},
+			},
+		},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	}
+	testStore := store.NewStore(canyons)
+	testStore.FetchImages(context.Background())
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(strings.Repeat("{{.Name}} ", 200))}},
+	})
+	require.NoError(t, err)
+
+	for _, path := range []string{"/image/gzip-camera", "/camera/gzip-camera/og.jpg"} {
+		t.Run(path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			assert.Empty(t, rec.Header().Get("Content-Encoding"))
+		})
+	}
+
+	t.Run("pages are still compressed", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	})
+}
+
+func TestImageRoute_ConditionalETags(t *testing.T) {
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("conditional image"))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	testStore := store.NewStore(&store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "Little Cottonwood Canyon",
+			Cameras: []store.Camera{
+				{Kind: "img", Src: imageServer.URL + "/cam.jpg", Alt: "Conditional Camera"},
+			},
+		},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	})
+	testStore.FetchImages(context.Background())
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/image/conditional-camera", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	for header, want := range map[string]int{
+		etag:                 http.StatusNotModified,
+		"W/" + etag:          http.StatusNotModified,
+		`"stale", ` + etag:   http.StatusNotModified,
+		"*":                  http.StatusNotModified,
+		`"stale", W/"other"`: http.StatusOK,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/image/conditional-camera", nil)
+		req.Header.Set("If-None-Match", header)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		assert.Equal(t, want, rec.Code, "If-None-Match: %s", header)
+	}
+}
+
+func TestImageRoute_RangeAndIfRange(t *testing.T) {
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("ranged image"))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	testStore := store.NewStore(&store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "Little Cottonwood Canyon",
+			Cameras: []store.Camera{
+				{Kind: "img", Src: imageServer.URL + "/cam.jpg", Alt: "Ranged Camera"},
+			},
+		},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	})
+	testStore.FetchImages(context.Background())
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+
+	full := testImage("ranged image")
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/image/ranged-camera", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "bytes", rec.Header().Get("Accept-Ranges"))
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	t.Run("range", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/image/ranged-camera", nil)
+		req.Header.Set("Range", "bytes=0-3")
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusPartialContent, rec.Code)
+		assert.Equal(t, string(full[:4]), rec.Body.String())
+		assert.Equal(t, etag, rec.Header().Get("ETag"))
+	})
+
+	t.Run("if-range with current etag", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/image/ranged-camera", nil)
+		req.Header.Set("Range", "bytes=4-")
+		req.Header.Set("If-Range", etag)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusPartialContent, rec.Code)
+		assert.Equal(t, string(full[4:]), rec.Body.String())
+	})
+
+	t.Run("if-range with stale etag", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/image/ranged-camera", nil)
+		req.Header.Set("Range", "bytes=4-")
+		req.Header.Set("If-Range", `"stale"`)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, string(full), rec.Body.String())
+	})
+}
+
+func TestImageRoute_ConcurrentReload(t *testing.T) {
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("image " + r.URL.Path))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	survivor := store.Camera{Kind: "img", Src: imageServer.URL + "/survivor.jpg", Alt: "Survivor"}
+	configs := []*store.Canyons{}
+	for _, name := range []string{"First", "Second"} {
+		configs = append(configs, &store.Canyons{
+			{ID: "LCC", Name: "LCC", Cameras: []store.Camera{
+				survivor,
+				{Kind: "img", Src: imageServer.URL + "/" + name + ".jpg", Alt: name},
+			}},
+			{ID: "BCC", Name: "BCC"},
+		})
+	}
+	testStore := store.NewStore(configs[0])
+	testStore.FetchImages(context.Background())
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+
+	var done atomic.Bool
+	reloaded := make(chan struct{})
+	go func() {
+		defer close(reloaded)
+		for i := 0; !done.Load(); i++ {
+			// Fresh copies, since Reload takes ownership of its canyons
+			next := append(store.Canyons(nil), *configs[i%2]...)
+			for j := range next {
+				next[j].Cameras = append([]store.Camera(nil), next[j].Cameras...)
+			}
+			assert.NoError(t, testStore.Reload(&next))
+		}
+	}()
+
+	for range 200 {
+		for _, path := range []string{"/image/survivor", "/"} {
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			assert.Equal(t, http.StatusOK, rec.Code, "%s during a reload", path)
+		}
+
+		// A camera that comes and goes is either there, gone, or mid-reload
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/image/second", nil))
+		assert.Contains(t, []int{http.StatusOK, http.StatusNotFound, http.StatusServiceUnavailable}, rec.Code)
+		if rec.Code == http.StatusServiceUnavailable {
+			assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+		}
+	}
+	done.Store(true)
+	<-reloaded
+}
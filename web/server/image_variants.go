@@ -0,0 +1,93 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+)
+
+// servedImage is the image, main or variant, chosen for a response
+type servedImage struct {
+	image         *store.Image
+	contentType   string
+	contentLength int64
+	width         int
+}
+
+// acceptsType reports whether an Accept header allows contentType. An empty
+// header accepts anything.
+func acceptsType(accept, contentType string) bool {
+	if strings.TrimSpace(accept) == "" {
+		return true
+	}
+	for _, part := range strings.Split(accept, ",") {
+		media, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		media = strings.TrimSpace(media)
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				continue
+			}
+		}
+		if media == "*/*" || strings.EqualFold(media, contentType) {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(media, "/*"); ok && strings.HasPrefix(contentType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// selectImage picks which of a camera's images to serve: among those the
+// client accepts, the narrowest at least width wide, or the widest if none
+// is. Without a width (<= 0) the main image is preferred.
+func selectImage(entry store.EntrySnapshot, width int, accept string) servedImage {
+	main := servedImage{
+		image:         entry.Image,
+		contentType:   entry.HTTPHeaders.ContentType,
+		contentLength: entry.HTTPHeaders.ContentLength,
+		width:         entry.Image.Width,
+	}
+	candidates := []servedImage{main}
+	for _, variant := range entry.Variants {
+		variantWidth := variant.MaxWidth
+		if variantWidth <= 0 {
+			variantWidth = variant.Image.Width
+		}
+		candidates = append(candidates, servedImage{
+			image:         variant.Image,
+			contentType:   variant.ContentType,
+			contentLength: int64(len(variant.Image.Bytes)),
+			width:         variantWidth,
+		})
+	}
+
+	// Formats the client can't use are dropped, unless that's all of them
+	var accepted []servedImage
+	for _, candidate := range candidates {
+		if acceptsType(accept, candidate.contentType) {
+			accepted = append(accepted, candidate)
+		}
+	}
+	if len(accepted) == 0 {
+		accepted = candidates
+	}
+
+	if width <= 0 {
+		return accepted[0]
+	}
+	var best *servedImage
+	for i := range accepted {
+		candidate := &accepted[i]
+		switch {
+		case best == nil:
+			best = candidate
+		case candidate.width >= width && (best.width < width || candidate.width < best.width):
+			best = candidate
+		case best.width < width && candidate.width > best.width:
+			best = candidate
+		}
+	}
+	return *best
+}
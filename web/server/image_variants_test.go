@@ -0,0 +1,113 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageRoute_Variants(t *testing.T) {
+	encode := func(width int, asPNG bool) []byte {
+		var buf bytes.Buffer
+		img := image.NewRGBA(image.Rect(0, 0, width, 10))
+		if asPNG {
+			require.NoError(t, png.Encode(&buf, img))
+		} else {
+			require.NoError(t, jpeg.Encode(&buf, img, nil))
+		}
+		return buf.Bytes()
+	}
+	images := map[string][]byte{
+		"/full.jpg":   encode(1600, false),
+		"/small.jpg":  encode(320, false),
+		"/medium.png": encode(800, true),
+	}
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.Write(images[r.URL.Path])
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	testStore := store.NewStore(&store.Canyons{
+		{ID: "LCC", Name: "LCC", Cameras: []store.Camera{
+			{Kind: "img", Src: imageServer.URL + "/full.jpg", Alt: "Multi", Sources: []store.ImageSource{
+				{Src: imageServer.URL + "/small.jpg", MaxWidth: 320},
+				{Src: imageServer.URL + "/medium.png", MaxWidth: 800},
+			}},
+			{Kind: "img", Src: imageServer.URL + "/small.jpg", Alt: "Single"},
+		}},
+		{ID: "BCC", Name: "BCC"},
+	})
+	testStore.FetchImages(context.Background())
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, tc := range []struct {
+		name   string
+		path   string
+		accept string
+		want   string
+	}{
+		{"no hint serves the main image", "/image/multi", "", "/full.jpg"},
+		{"narrowest that covers the width", "/image/multi?w=300", "", "/small.jpg"},
+		{"exact width", "/image/multi?w=320", "", "/small.jpg"},
+		{"between sizes rounds up", "/image/multi?w=500", "", "/medium.png"},
+		{"wider than the variants", "/image/multi?w=1200", "", "/full.jpg"},
+		{"wider than everything serves the widest", "/image/multi?w=4000", "", "/full.jpg"},
+		{"accept narrows the formats", "/image/multi?w=500", "image/jpeg", "/full.jpg"},
+		{"unacceptable formats are ignored if nothing fits", "/image/multi", "image/avif", "/full.jpg"},
+		{"single source cameras ignore the hint", "/image/single?w=1200", "", "/small.jpg"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := get(tc.path, tc.accept)
+			require.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, images[tc.want], rec.Body.Bytes())
+		})
+	}
+
+	t.Run("variants vary by accept and have their own etags", func(t *testing.T) {
+		small, full := get("/image/multi?w=300", ""), get("/image/multi", "")
+		assert.Contains(t, small.Header().Values("Vary"), "Accept")
+		assert.Equal(t, "image/jpeg", small.Header().Get("Content-Type"))
+		assert.NotEqual(t, small.Header().Get("ETag"), full.Header().Get("ETag"))
+		// JPEG frames may be transcoded to WebP, so they vary too
+		assert.Contains(t, get("/image/single", "").Header().Values("Vary"), "Accept")
+
+		medium := get("/image/multi?w=500", "")
+		assert.Equal(t, "image/png", medium.Header().Get("Content-Type"))
+	})
+}
+
+func TestAcceptsType(t *testing.T) {
+	assert.True(t, acceptsType("", "image/png"))
+	assert.True(t, acceptsType("image/avif,image/webp,image/*,*/*;q=0.8", "image/png"))
+	assert.True(t, acceptsType("image/*", "image/jpeg"))
+	assert.True(t, acceptsType("image/jpeg", "image/jpeg"))
+	assert.False(t, acceptsType("image/jpeg", "image/png"))
+	assert.False(t, acceptsType("image/png;q=0, image/jpeg", "image/png"))
+}
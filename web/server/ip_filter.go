@@ -0,0 +1,110 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// IPFilter blocks clients by address, e.g. during abuse incidents. An address
+// is blocked if it matches Deny, or if Allow is set and it matches none of it.
+type IPFilter struct {
+	Allow []netip.Prefix
+	Deny  []netip.Prefix
+}
+
+// Enabled reports whether the filter has any rules
+func (f IPFilter) Enabled() bool {
+	return len(f.Allow) > 0 || len(f.Deny) > 0
+}
+
+// Allowed reports whether a client at addr may make requests
+func (f IPFilter) Allowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range f.Deny {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	if len(f.Allow) == 0 {
+		return true
+	}
+	for _, prefix := range f.Allow {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseIPPrefixes parses a comma-separated list of CIDRs and bare IPs (which
+// match just that address)
+func ParseIPPrefixes(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if strings.Contains(field, "/") {
+			prefix, err := netip.ParsePrefix(field)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP or CIDR %q", field)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// IPFilterMiddleware rejects blocked clients with 403, identifying them by
+// c.RealIP() (which honors X-Forwarded-For, only from TrustedProxies when
+// they're configured). Clients whose address can't be
+// parsed are only blocked when an allowlist is set.
+func IPFilterMiddleware(f IPFilter) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if probePaths[c.Request().URL.Path] {
+				return next(c)
+			}
+			addr, err := netip.ParseAddr(c.RealIP())
+			if err != nil {
+				if len(f.Allow) > 0 {
+					return c.String(http.StatusForbidden, "forbidden")
+				}
+				return next(c)
+			}
+			if !f.Allowed(addr) {
+				return c.String(http.StatusForbidden, "forbidden")
+			}
+			return next(c)
+		}
+	}
+}
+
+// TrustedProxyIPExtractor derives the client IP from X-Forwarded-For only
+// when the immediate peer is a trusted proxy: one in trusted, or on a
+// loopback, link-local or private address (such as Fly's proxy). Hops are
+// walked right to left, skipping trusted ones, so a client can't spoof its
+// address by sending the header itself.
+func TrustedProxyIPExtractor(trusted []netip.Prefix) echo.IPExtractor {
+	options := make([]echo.TrustOption, 0, len(trusted))
+	for _, prefix := range trusted {
+		options = append(options, echo.TrustIPRange(&net.IPNet{
+			IP:   prefix.Addr().AsSlice(),
+			Mask: net.CIDRMask(prefix.Bits(), prefix.Addr().BitLen()),
+		}))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
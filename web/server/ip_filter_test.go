@@ -0,0 +1,121 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIPPrefixes(t *testing.T) {
+	prefixes, err := ParseIPPrefixes(" 203.0.113.0/24, 198.51.100.7 ,2001:db8::/32,,::ffff:192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("203.0.113.0/24"),
+		netip.MustParsePrefix("198.51.100.7/32"),
+		netip.MustParsePrefix("2001:db8::/32"),
+		netip.MustParsePrefix("192.0.2.1/32"),
+	}, prefixes)
+
+	prefixes, err = ParseIPPrefixes("")
+	require.NoError(t, err)
+	assert.Empty(t, prefixes)
+
+	_, err = ParseIPPrefixes("203.0.113.0/24,not-an-ip")
+	assert.Error(t, err)
+	_, err = ParseIPPrefixes("203.0.113.0/99")
+	assert.Error(t, err)
+}
+
+func TestStart_IPFilter(t *testing.T) {
+	deny, err := ParseIPPrefixes("203.0.113.0/24")
+	require.NoError(t, err)
+
+	app, err := Start(ServerConfig{
+		Store:      store.NewStore(&store.Canyons{{ID: "LCC", Name: "Little Cottonwood Canyon"}}),
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+		IPFilter:   IPFilter{Deny: deny},
+	})
+	require.NoError(t, err)
+
+	request := func(path, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("denied CIDR", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, request("/", "203.0.113.42").Code)
+		assert.Equal(t, http.StatusForbidden, request("/robots.txt", "203.0.113.42, 10.0.0.1").Code)
+	})
+
+	t.Run("allowed IP passes through", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("/robots.txt", "198.51.100.7").Code)
+	})
+
+	t.Run("healthcheck is exempt", func(t *testing.T) {
+		assert.NotEqual(t, http.StatusForbidden, request("/healthcheck", "203.0.113.42").Code)
+	})
+}
+
+func TestIPFilter_Allowlist(t *testing.T) {
+	allow, err := ParseIPPrefixes("10.0.0.0/8")
+	require.NoError(t, err)
+	deny, err := ParseIPPrefixes("10.0.0.13")
+	require.NoError(t, err)
+	filter := IPFilter{Allow: allow, Deny: deny}
+
+	assert.True(t, filter.Allowed(netip.MustParseAddr("10.1.2.3")))
+	assert.True(t, filter.Allowed(netip.MustParseAddr("::ffff:10.1.2.3")))
+	assert.False(t, filter.Allowed(netip.MustParseAddr("10.0.0.13")), "deny wins over allow")
+	assert.False(t, filter.Allowed(netip.MustParseAddr("192.0.2.1")), "unlisted addresses are blocked")
+	assert.True(t, IPFilter{}.Allowed(netip.MustParseAddr("192.0.2.1")))
+}
+
+func TestStart_TrustedProxies(t *testing.T) {
+	deny, err := ParseIPPrefixes("203.0.113.0/24")
+	require.NoError(t, err)
+	trusted, err := ParseIPPrefixes("198.51.100.0/24")
+	require.NoError(t, err)
+
+	app, err := Start(ServerConfig{
+		Store:          store.NewStore(&store.Canyons{{ID: "LCC", Name: "Little Cottonwood Canyon"}}),
+		StaticFS:       fstest.MapFS{},
+		TemplateFS:     fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+		IPFilter:       IPFilter{Deny: deny},
+		TrustedProxies: trusted,
+	})
+	require.NoError(t, err)
+
+	request := func(peer, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodGet, "/robots.txt", nil)
+		req.RemoteAddr = peer + ":4321"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run("spoofed header from an untrusted peer is ignored", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, request("203.0.113.9", "192.0.2.1"))
+		assert.Equal(t, http.StatusOK, request("192.0.2.1", "203.0.113.9"))
+	})
+
+	t.Run("header from a trusted proxy is believed", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, request("198.51.100.7", "203.0.113.9"))
+		assert.Equal(t, http.StatusOK, request("198.51.100.7", "192.0.2.1"))
+	})
+
+	t.Run("the nearest untrusted hop is the client", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, request("198.51.100.7", "192.0.2.1, 203.0.113.9, 198.51.100.8"))
+		assert.Equal(t, http.StatusOK, request("198.51.100.7", "203.0.113.9, 192.0.2.1"))
+	})
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// DefaultLogHistory is how many recent lines a LogHub replays, matching
+	// the TUI's scrollback
+	DefaultLogHistory = 1000
+	// logSubscriberBuffer is how many lines a stream client may fall behind
+	// before it's dropped
+	logSubscriberBuffer = 256
+	logStreamPath       = "/_/logs"
+)
+
+// ansiEscape matches the color codes in styled log lines
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// LogHub keeps the last lines logged and fans new ones out to stream
+// clients. Publishing never blocks: a client that can't keep up is dropped.
+type LogHub struct {
+	mu          sync.Mutex
+	lines       []string // oldest first
+	max         int
+	subscribers map[chan string]struct{}
+}
+
+// NewLogHub returns a hub that keeps the last max lines (DefaultLogHistory
+// if max <= 0)
+func NewLogHub(max int) *LogHub {
+	if max <= 0 {
+		max = DefaultLogHistory
+	}
+	return &LogHub{
+		lines:       make([]string, 0, max),
+		max:         max,
+		subscribers: make(map[chan string]struct{}),
+	}
+}
+
+// Publish records a log line and sends it to every stream client
+func (h *LogHub) Publish(line string) {
+	line = ansiEscape.ReplaceAllString(line, "")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.lines) == h.max {
+		copy(h.lines, h.lines[1:])
+		h.lines = h.lines[:h.max-1]
+	}
+	h.lines = append(h.lines, line)
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- line:
+		default:
+			// Too slow; drop the client rather than block logging
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe returns the recent lines and a channel of new ones, which is
+// closed if the client falls too far behind. Call unsubscribe when done.
+func (h *LogHub) subscribe() (history []string, lines <-chan string, unsubscribe func()) {
+	ch := make(chan string, logSubscriberBuffer)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	history = append([]string(nil), h.lines...)
+	h.subscribers[ch] = struct{}{}
+
+	return history, ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subscribers[ch]; ok {
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// writeEvent writes one log line as a server-sent event
+func writeEvent(w http.ResponseWriter, line string) error {
+	var b strings.Builder
+	for _, part := range strings.Split(line, "\n") {
+		b.WriteString("data: ")
+		b.WriteString(strings.TrimRight(part, "\r"))
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	_, err := fmt.Fprint(w, b.String())
+	return err
+}
+
+// LogStreamRoute streams log lines as server-sent events: the recent history
+// first, then new lines as they're logged
+func LogStreamRoute(hub *LogHub) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		history, lines, unsubscribe := hub.subscribe()
+		defer unsubscribe()
+
+		w := c.Response()
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		for _, line := range history {
+			if err := writeEvent(w, line); err != nil {
+				return nil
+			}
+		}
+		w.Flush()
+
+		ctx := c.Request().Context()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case line, ok := <-lines:
+				if !ok {
+					return nil // dropped for falling behind
+				}
+				if err := writeEvent(w, line); err != nil {
+					return nil
+				}
+				w.Flush()
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stefanpenner/lcc-live/web/logger"
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogStreamRoute(t *testing.T) {
+	hub := NewLogHub(10)
+	hub.Publish("\x1b[1mbefore connect\x1b[0m")
+	logger.Tee = hub.Publish
+	t.Cleanup(func() { logger.Tee = nil })
+
+	app, err := Start(ServerConfig{
+		Store:      store.NewStore(&store.Canyons{{ID: "LCC", Name: "LCC"}, {ID: "BCC", Name: "BCC"}}),
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+		AdminToken: "admin-secret",
+		LogHub:     hub,
+		// The stream must outlive the request timeout
+		RequestTimeout: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	server := httptest.NewServer(app)
+	t.Cleanup(server.Close)
+
+	t.Run("requires the admin token", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/_/logs")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("replays history then streams new lines", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/_/logs", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		assert.Contains(t, resp.Header.Get("Cache-Control"), "no-store")
+
+		events := make(chan string)
+		go func() {
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+					events <- data
+				}
+			}
+			close(events)
+		}()
+		next := func() string {
+			select {
+			case event := <-events:
+				return event
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for a log line")
+				return ""
+			}
+		}
+
+		assert.Equal(t, "before connect", next(), "history is replayed without color codes")
+
+		time.Sleep(100 * time.Millisecond) // past the request timeout
+		logger.Info("camera fetch failed")
+		assert.Contains(t, next(), "camera fetch failed")
+	})
+}
+
+func TestLogHub(t *testing.T) {
+	t.Run("keeps the last lines", func(t *testing.T) {
+		hub := NewLogHub(2)
+		hub.Publish("one")
+		hub.Publish("two")
+		hub.Publish("three")
+		history, _, unsubscribe := hub.subscribe()
+		defer unsubscribe()
+		assert.Equal(t, []string{"two", "three"}, history)
+	})
+
+	t.Run("drops slow clients", func(t *testing.T) {
+		hub := NewLogHub(0)
+		_, lines, unsubscribe := hub.subscribe()
+		defer unsubscribe()
+
+		for range logSubscriberBuffer + 1 {
+			hub.Publish("line") // never blocks
+		}
+		received := 0
+		for range lines {
+			received++
+		}
+		assert.Equal(t, logSubscriberBuffer, received, "the channel is closed once the client falls behind")
+	})
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maintenanceRetryAfter is how long clients are told to wait, in seconds,
+// while the site is down for maintenance
+const maintenanceRetryAfter = "300"
+
+// MaintenanceState is whether public routes are down for maintenance, as
+// read and set by /_/maintenance
+type MaintenanceState struct {
+	Enabled bool `json:"enabled"`
+	// ServeImages keeps /image/ up during maintenance, so embeds and the
+	// apps keep working
+	ServeImages bool `json:"serveImages"`
+}
+
+// Maintenance is a runtime toggle for maintenance mode. It holds the current
+// MaintenanceState, swapped whole so readers never see half an update.
+type Maintenance struct {
+	state atomic.Pointer[MaintenanceState]
+}
+
+// NewMaintenance returns a toggle that starts enabled or not, with images
+// served during maintenance
+func NewMaintenance(enabled bool) *Maintenance {
+	m := &Maintenance{}
+	m.state.Store(&MaintenanceState{Enabled: enabled, ServeImages: true})
+	return m
+}
+
+// State returns the current maintenance state
+func (m *Maintenance) State() MaintenanceState {
+	return *m.state.Load()
+}
+
+// SetEnabled turns maintenance mode on or off
+func (m *Maintenance) SetEnabled(enabled bool) {
+	m.update(MaintenanceUpdate{Enabled: &enabled})
+}
+
+// MaintenanceUpdate changes the given fields of a MaintenanceState
+type MaintenanceUpdate struct {
+	Enabled     *bool `json:"enabled"`
+	ServeImages *bool `json:"serveImages"`
+}
+
+// update applies u atomically, retrying if another update lands first, and
+// returns the resulting state
+func (m *Maintenance) update(u MaintenanceUpdate) MaintenanceState {
+	for {
+		current := m.state.Load()
+		next := *current
+		if u.Enabled != nil {
+			next.Enabled = *u.Enabled
+		}
+		if u.ServeImages != nil {
+			next.ServeImages = *u.ServeImages
+		}
+		if m.state.CompareAndSwap(current, &next) {
+			return next
+		}
+	}
+}
+
+// servesDuringMaintenance reports whether a path stays up in maintenance
+// mode: admin endpoints, liveness, static assets, and images if state
+// allows
+func servesDuringMaintenance(path string, state *MaintenanceState) bool {
+	switch {
+	case strings.HasPrefix(path, "/_/"), path == "/livez", strings.HasPrefix(path, staticPrefix):
+		return true
+	case strings.HasPrefix(path, "/image/"):
+		return state.ServeImages
+	}
+	return false
+}
+
+// maintenanceMiddleware answers public routes with a 503 and the
+// maintenance page (or problem+json for JSON endpoints) while maintenance
+// mode is on. /healthcheck is down too, so load balancers drain the
+// instance while the process keeps running.
+func maintenanceMiddleware(m *Maintenance) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			state := m.state.Load()
+			if !state.Enabled || servesDuringMaintenance(c.Request().URL.Path, state) {
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set("Retry-After", maintenanceRetryAfter)
+			header.Set("Cache-Control", "no-store")
+			if wantsJSON(c) {
+				return errorResponse(c, http.StatusServiceUnavailable, "down for maintenance")
+			}
+			if err := c.Render(http.StatusServiceUnavailable, "maintenance.html.tmpl", nil); err != nil {
+				return c.String(http.StatusServiceUnavailable, "Down for maintenance")
+			}
+			return nil
+		}
+	}
+}
+
+// MaintenanceRoute reports the maintenance state (GET /_/maintenance) and
+// updates it from a JSON MaintenanceUpdate (POST); omitted fields keep their
+// current value
+func MaintenanceRoute(m *Maintenance) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		if c.Request().Method != http.MethodPost {
+			return c.JSON(http.StatusOK, m.State())
+		}
+		var u MaintenanceUpdate
+		if err := c.Bind(&u); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, m.update(u))
+	}
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	testStore := store.NewStore(&store.Canyons{
+		{ID: "LCC", Name: "Little Cottonwood Canyon"},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	})
+	testStore.FetchImages(context.Background())
+
+	maintenance := NewMaintenance(true)
+	app, err := Start(ServerConfig{
+		Store:    testStore,
+		StaticFS: fstest.MapFS{"style.css": &fstest.MapFile{Data: []byte("body{}")}},
+		TemplateFS: fstest.MapFS{
+			"canyon.html.tmpl":      &fstest.MapFile{Data: []byte(`{{.Name}}`)},
+			"maintenance.html.tmpl": &fstest.MapFile{Data: []byte(`Down for maintenance`)},
+		},
+		AdminToken:  "admin-secret",
+		Maintenance: maintenance,
+	})
+	require.NoError(t, err)
+
+	request := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("public routes serve the maintenance page", func(t *testing.T) {
+		for _, path := range []string{"/", "/bcc", "/healthcheck"} {
+			rec := request(http.MethodGet, path, "", "")
+			assert.Equal(t, http.StatusServiceUnavailable, rec.Code, path)
+			assert.Equal(t, maintenanceRetryAfter, rec.Header().Get("Retry-After"), path)
+			assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"), path)
+			assert.Contains(t, rec.Body.String(), "Down for maintenance", path)
+		}
+	})
+
+	t.Run("json routes get problem+json", func(t *testing.T) {
+		rec := request(http.MethodGet, "/bcc.json", "", "")
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, mimeProblemJSON, rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("admin, static and image routes stay up", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request(http.MethodGet, "/_/version", "", "").Code)
+		assert.Equal(t, http.StatusOK, request(http.MethodGet, "/s/style.css", "", "").Code)
+		assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/image/nope", "", "").Code)
+	})
+
+	t.Run("toggle requires the admin token", func(t *testing.T) {
+		rec := request(http.MethodPost, "/_/maintenance", "", `{"enabled":false}`)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.True(t, maintenance.State().Enabled)
+	})
+
+	t.Run("images can be taken down too", func(t *testing.T) {
+		rec := request(http.MethodPost, "/_/maintenance", "admin-secret", `{"serveImages":false}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, MaintenanceState{Enabled: true, ServeImages: false}, maintenance.State())
+		assert.Equal(t, http.StatusServiceUnavailable, request(http.MethodGet, "/image/nope", "", "").Code)
+	})
+
+	t.Run("disabling restores normal responses", func(t *testing.T) {
+		rec := request(http.MethodPost, "/_/maintenance", "admin-secret", `{"enabled":false}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		var state MaintenanceState
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &state))
+		assert.Equal(t, MaintenanceState{Enabled: false, ServeImages: false}, state)
+
+		rec = request(http.MethodGet, "/bcc", "", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "Big Cottonwood Canyon", rec.Body.String())
+		assert.Empty(t, rec.Header().Get("Retry-After"))
+	})
+}
+
+func TestMaintenance_ConcurrentUpdates(t *testing.T) {
+	m := NewMaintenance(false)
+	done := make(chan struct{})
+	for i := range 50 {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			m.SetEnabled(i%2 == 0)
+			_ = m.State()
+		}()
+	}
+	for range 50 {
+		<-done
+	}
+	assert.True(t, m.State().ServeImages, "toggling enabled leaves serveImages alone")
+}
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"errors"
+	"net/http"
 	"strconv"
 	"time"
 
@@ -8,6 +10,10 @@ import (
 	"github.com/stefanpenner/lcc-live/web/metrics"
 )
 
+// unmatchedPathLabel buckets requests that matched no route, so random URLs
+// can't create unbounded path label values
+const unmatchedPathLabel = "unmatched"
+
 // MetricsMiddleware records HTTP request metrics for Prometheus
 func MetricsMiddleware() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -32,10 +38,21 @@ func MetricsMiddleware() echo.MiddlewareFunc {
 			// Store latency in context for logger to use
 			c.Set("request_latency", elapsed)
 
-			// Normalize paths to avoid high cardinality
-			// Replace :id params with the placeholder
+			// Matched requests are labeled by route pattern (e.g. /image/:id);
+			// unmatched ones share a single label to bound cardinality
 			if path == "" {
-				path = c.Request().URL.Path
+				path = unmatchedPathLabel
+			}
+
+			// Errors (e.g. the router's 404) are only written to the response
+			// by the error handler after middleware returns
+			if err != nil && !c.Response().Committed {
+				var he *echo.HTTPError
+				if errors.As(err, &he) {
+					status = he.Code
+				} else {
+					status = http.StatusInternalServerError
+				}
 			}
 
 			statusStr := strconv.Itoa(status)
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stefanpenner/lcc-live/web/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsMiddleware_BucketsUnmatchedPaths(t *testing.T) {
+	e := echo.New()
+	e.Use(MetricsMiddleware())
+	e.GET("/image/:id", func(c echo.Context) error {
+		return c.String(http.StatusOK, "image")
+	})
+
+	unmatched := metrics.HTTPRequestsTotal.WithLabelValues(http.MethodGet, unmatchedPathLabel, "404")
+	route := metrics.HTTPRequestsTotal.WithLabelValues(http.MethodGet, "/image/:id", "200")
+	unmatchedBefore := testutil.ToFloat64(unmatched)
+	routeBefore := testutil.ToFloat64(route)
+
+	for _, path := range []string{"/random-abc123", "/wp-login.php", "/image/abc"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, unmatchedBefore+2, testutil.ToFloat64(unmatched))
+	assert.Equal(t, routeBefore+1, testutil.ToFloat64(route), "route patterns are kept intact")
+}
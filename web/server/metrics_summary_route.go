@@ -0,0 +1,88 @@
+package server
+
+import (
+	"net/http"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stefanpenner/lcc-live/web/store"
+)
+
+// SyncSummary describes the most recent FetchImages cycle
+type SyncSummary struct {
+	At         time.Time `json:"at"`
+	DurationMs int64     `json:"durationMs"`
+	Changed    int       `json:"changed"`
+	Unchanged  int       `json:"unchanged"`
+	Errors     int       `json:"errors"`
+}
+
+// MetricsSummary is a handful of key numbers for dashboards that don't
+// scrape Prometheus
+type MetricsSummary struct {
+	Cameras       int          `json:"cameras"`
+	CamerasUp     int          `json:"camerasUp"`
+	CamerasDown   int          `json:"camerasDown"`
+	LastSync      *SyncSummary `json:"lastSync"` // nil until the first sync
+	RequestsTotal int64        `json:"requestsTotal"`
+	MemoryMB      float64      `json:"memoryMB"`
+	Goroutines    int          `json:"goroutines"`
+}
+
+// syncTracker remembers the latest SyncResult, fed by Store.OnSync
+type syncTracker struct {
+	mu     sync.Mutex
+	at     time.Time
+	result *store.SyncResult
+}
+
+func (t *syncTracker) observe(result store.SyncResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.at = time.Now()
+	t.result = &result
+}
+
+func (t *syncTracker) latest() (time.Time, *store.SyncResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.at, t.result
+}
+
+// MetricsSummaryRoute serves MetricsSummary as JSON (GET /_/metrics.json).
+// Sync numbers come from the latest cycle seen since the server started.
+func MetricsSummaryRoute(s *store.Store) func(c echo.Context) error {
+	tracker := &syncTracker{}
+	s.OnSync(tracker.observe)
+
+	return func(c echo.Context) error {
+		summary := MetricsSummary{
+			Cameras:    len(s.Entries()),
+			Goroutines: runtime.NumGoroutine(),
+		}
+		if RequestCounter != nil {
+			summary.RequestsTotal = atomic.LoadInt64(RequestCounter)
+		}
+
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		summary.MemoryMB = float64(m.Alloc) / 1024 / 1024
+
+		if at, result := tracker.latest(); result != nil {
+			summary.CamerasUp = result.Changed + result.Unchanged
+			summary.CamerasDown = result.Errors
+			summary.LastSync = &SyncSummary{
+				At:         at,
+				DurationMs: result.Duration.Milliseconds(),
+				Changed:    result.Changed,
+				Unchanged:  result.Unchanged,
+				Errors:     result.Errors,
+			}
+		}
+
+		return c.JSON(http.StatusOK, summary)
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsSummaryRoute(t *testing.T) {
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down.jpg" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("summary"))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	testStore := store.NewStore(&store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "Little Cottonwood Canyon",
+			Cameras: []store.Camera{
+				{Kind: "img", Src: imageServer.URL + "/up.jpg", Alt: "Up Camera"},
+				{Kind: "img", Src: imageServer.URL + "/down.jpg", Alt: "Down Camera"},
+			},
+		},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	})
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+	testStore.FetchImages(context.Background())
+
+	req := httptest.NewRequest(http.MethodGet, "/_/metrics.json", nil)
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Cache-Control"), "no-store")
+
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &fields))
+	for _, field := range []string{"cameras", "camerasUp", "camerasDown", "lastSync", "requestsTotal", "memoryMB", "goroutines"} {
+		assert.Contains(t, fields, field)
+	}
+
+	var summary MetricsSummary
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &summary))
+	assert.Equal(t, 2, summary.Cameras)
+	assert.Equal(t, 1, summary.CamerasUp)
+	assert.Equal(t, 1, summary.CamerasDown)
+	require.NotNil(t, summary.LastSync)
+	assert.Equal(t, 1, summary.LastSync.Changed)
+	assert.Equal(t, 1, summary.LastSync.Errors)
+	assert.False(t, summary.LastSync.At.IsZero())
+	assert.Positive(t, summary.MemoryMB)
+	assert.Positive(t, summary.Goroutines)
+}
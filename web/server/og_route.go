@@ -0,0 +1,132 @@
+package server
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	_ "image/gif" // register decoders for camera formats
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stefanpenner/lcc-live/web/store"
+)
+
+// ogImageWidth is the width social networks render link previews at
+const ogImageWidth = 1200
+
+type ogImage struct {
+	etag  string
+	bytes []byte
+}
+
+// OGImageRoute serves a camera's current frame scaled for social link
+// previews. Scaled images are cached per camera until its frame changes.
+func OGImageRoute(s *store.Store) func(c echo.Context) error {
+	var (
+		mu    sync.Mutex
+		cache = make(map[string]ogImage)
+	)
+
+	return func(c echo.Context) error {
+		entry, exists := s.Get(c.Param("id"))
+		if !exists || !entry.Camera.IsEnabled() || !entry.Camera.IsFetchable() {
+			return c.String(http.StatusNotFound, "Camera not found")
+		}
+		if entry.HTTPHeaders.Status != http.StatusOK || len(entry.Image.Bytes) == 0 {
+			return c.String(http.StatusNotFound, "Image not available")
+		}
+
+		etag := "\"" + trimETag(entry.Image.ETag) + "-og\""
+		c.Response().Header().Set("Cache-Control", "public, max-age=60")
+		c.Response().Header().Set("ETag", etag)
+		if notModified(c, etag) {
+			return c.NoContent(http.StatusNotModified)
+		}
+
+		mu.Lock()
+		cached, ok := cache[entry.ID]
+		mu.Unlock()
+
+		if !ok || cached.etag != entry.Image.ETag {
+			scaled, err := scaleForOG(entry.Image.Bytes)
+			if err != nil {
+				// Undecodable frames are still better than no preview
+				return c.Blob(http.StatusOK, entry.HTTPHeaders.ContentType, entry.Image.Bytes)
+			}
+			cached = ogImage{etag: entry.Image.ETag, bytes: scaled}
+			mu.Lock()
+			cache[entry.ID] = cached
+			mu.Unlock()
+		}
+
+		if c.Request().Method == http.MethodHead {
+			return c.NoContent(http.StatusOK)
+		}
+		return c.Blob(http.StatusOK, "image/jpeg", cached.bytes)
+	}
+}
+
+// trimETag strips the surrounding quotes from an ETag
+func trimETag(etag string) string {
+	if len(etag) >= 2 && etag[0] == '"' && etag[len(etag)-1] == '"' {
+		return etag[1 : len(etag)-1]
+	}
+	return etag
+}
+
+// scaleForOG decodes an image and re-encodes it as a JPEG no wider than
+// ogImageWidth, preserving its aspect ratio
+func scaleForOG(data []byte) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	dst := src
+	if bounds := src.Bounds(); bounds.Dx() > ogImageWidth {
+		height := bounds.Dy() * ogImageWidth / bounds.Dx()
+		dst = downscale(src, ogImageWidth, height)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// downscale resizes src to width x height by averaging the source pixels
+// that fall within each destination pixel (a box filter)
+func downscale(src image.Image, width, height int) image.Image {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(bounds.Min.Y+(y+1)*bounds.Dy()/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(bounds.Min.X+(x+1)*bounds.Dx()/width, x0+1)
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, b, a = r+pr, g+pg, b+pb, a+pa
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+
+	return dst
+}
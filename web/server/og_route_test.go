@@ -0,0 +1,85 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOGImageRoute(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 1600, 900))
+	for y := 0; y < 900; y++ {
+		for x := 0; x < 1600; x++ {
+			src.Set(x, y, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+	var frame bytes.Buffer
+	require.NoError(t, jpeg.Encode(&frame, src, nil))
+
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(frame.Bytes())
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	canyons := &store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "Little Cottonwood Canyon",
+			Cameras: []store.Camera{
+				{Kind: "img", Src: imageServer.URL + "/cam.jpg", Alt: "Preview Camera"},
+			},
+		},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	}
+	testStore := store.NewStore(canyons)
+	testStore.FetchImages(context.Background())
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+
+	t.Run("valid camera", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/camera/preview-camera/og.jpg", nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "image/jpeg", rec.Header().Get("Content-Type"))
+		assert.Equal(t, "public, max-age=60", rec.Header().Get("Cache-Control"))
+
+		config, err := jpeg.DecodeConfig(bytes.NewReader(rec.Body.Bytes()))
+		require.NoError(t, err)
+		assert.Equal(t, ogImageWidth, config.Width)
+		assert.Equal(t, 675, config.Height, "aspect ratio should be preserved")
+
+		// Conditional requests revalidate against the frame's ETag
+		req = httptest.NewRequest(http.MethodGet, "/camera/preview-camera/og.jpg", nil)
+		req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+		rec = httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+	})
+
+	t.Run("unknown camera", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/camera/nope/og.jpg", nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -0,0 +1,25 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stefanpenner/lcc-live/web/store"
+)
+
+// CameraOriginRoute redirects to a camera's upstream image, for tools that
+// want a direct link and for seeing which origin backs a camera. The target
+// is only ever the configured Src, never anything from the request, so this
+// can't be used as an open redirect.
+func CameraOriginRoute(s *store.Store) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		entry, exists := s.Get(c.Param("id"))
+		if !exists || !entry.Camera.IsEnabled() || entry.Camera.Src == "" {
+			return errorResponse(c, http.StatusNotFound, "Camera not found")
+		}
+
+		// The Src may change on a reload
+		c.Response().Header().Set("Cache-Control", "no-store")
+		return c.Redirect(http.StatusFound, entry.Camera.Src)
+	}
+}
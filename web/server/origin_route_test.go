@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCameraOriginRoute(t *testing.T) {
+	disabled := false
+	canyons := &store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "Little Cottonwood Canyon",
+			Cameras: []store.Camera{
+				{Kind: "webcam", Src: "https://cams.example.com/lcc/1.jpg?size=large", Alt: "Origin Camera"},
+				{Kind: "webcam", Src: "https://cams.example.com/lcc/2.jpg", Alt: "Disabled Camera", Enabled: &disabled},
+			},
+		},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	}
+	testStore := store.NewStore(canyons)
+	// Nothing listens at the example origins, so skip the fetches; the
+	// cycle still marks the store ready
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	testStore.FetchImages(ctx)
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	t.Run("redirects to the configured src", func(t *testing.T) {
+		rec := get("/camera/origin-camera/origin")
+		require.Equal(t, http.StatusFound, rec.Code)
+		assert.Equal(t, "https://cams.example.com/lcc/1.jpg?size=large", rec.Header().Get("Location"))
+		assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	})
+
+	t.Run("ignores query parameters", func(t *testing.T) {
+		rec := get("/camera/origin-camera/origin?url=https://evil.example.com/")
+		require.Equal(t, http.StatusFound, rec.Code)
+		assert.Equal(t, "https://cams.example.com/lcc/1.jpg?size=large", rec.Header().Get("Location"))
+	})
+
+	t.Run("unknown camera", func(t *testing.T) {
+		rec := get("/camera/nope/origin")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Empty(t, rec.Header().Get("Location"))
+	})
+
+	t.Run("disabled camera", func(t *testing.T) {
+		rec := get("/camera/disabled-camera/origin")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("camera page still routes", func(t *testing.T) {
+		rec := get("/camera/origin-camera.json")
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const mimeProblemJSON = "application/problem+json"
+
+// Problem is an RFC 7807 problem details body, returned for errors on JSON
+// endpoints so API clients get structured errors
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// wantsJSON reports whether a request targets a JSON endpoint: a .json or
+// .geojson path, the /api/ namespace, or a client that asks for JSON
+func wantsJSON(c echo.Context) bool {
+	path := c.Request().URL.Path
+	if strings.HasSuffix(path, ".json") || strings.HasSuffix(path, ".geojson") || strings.HasPrefix(path, "/api/") {
+		return true
+	}
+	accept := c.Request().Header.Get("Accept")
+	return strings.Contains(accept, "application/json") || strings.Contains(accept, "+json")
+}
+
+// errorResponse replies with detail as problem+json for JSON endpoints and
+// as plain text otherwise
+func errorResponse(c echo.Context, status int, detail string) error {
+	if !wantsJSON(c) {
+		return c.String(status, detail)
+	}
+	body, err := renderJSON(Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	})
+	if err != nil {
+		return err
+	}
+	return c.Blob(status, mimeProblemJSON, body)
+}
+
+// problemErrorHandler renders errors returned from handlers (unknown routes,
+// echo.HTTPErrors) as problem+json for JSON endpoints, deferring to echo's
+// default handler for everything else
+func problemErrorHandler(e *echo.Echo) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		if c.Response().Committed || !wantsJSON(c) {
+			e.DefaultHTTPErrorHandler(err, c)
+			return
+		}
+
+		status := http.StatusInternalServerError
+		detail := ""
+		var he *echo.HTTPError
+		if errors.As(err, &he) {
+			status = he.Code
+			if message, ok := he.Message.(string); ok && message != http.StatusText(status) {
+				detail = message
+			}
+		}
+		if err := errorResponse(c, status, detail); err != nil {
+			e.Logger.Error(err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProblemDetails(t *testing.T) {
+	testStore := store.NewStore(&store.Canyons{
+		{ID: "LCC", Name: "Little Cottonwood Canyon"},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	})
+	testStore.FetchImages(context.Background())
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+
+	request := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	problemCases := []struct {
+		name   string
+		path   string
+		accept string
+		status int
+		detail string
+	}{
+		{"unknown camera json", "/camera/nope.json", "", http.StatusNotFound, "Camera not found"},
+		{"unknown camera with json accept", "/camera/nope", "application/json", http.StatusNotFound, "Camera not found"},
+		{"invalid canyon feed", "/api/v1/xyz", "", http.StatusBadRequest, "Invalid canyon: xyz"},
+		{"unknown json route", "/nope.json", "", http.StatusNotFound, ""},
+	}
+	for _, tc := range problemCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := request(tc.path, tc.accept)
+			require.Equal(t, tc.status, rec.Code)
+			assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+
+			var problem Problem
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+			assert.Equal(t, Problem{
+				Type:   "about:blank",
+				Title:  http.StatusText(tc.status),
+				Status: tc.status,
+				Detail: tc.detail,
+			}, problem)
+		})
+	}
+
+	t.Run("html paths keep plain text", func(t *testing.T) {
+		rec := request("/camera/nope", "text/html")
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+		assert.Equal(t, "Camera not found", rec.Body.String())
+	})
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stefanpenner/lcc-live/web/cloudflare"
+	"github.com/stefanpenner/lcc-live/web/store"
+)
+
+// PurgeRequest names the cameras whose cached URLs should be purged, by ID
+// or slug
+type PurgeRequest struct {
+	Cameras []string `json:"cameras" form:"camera"`
+}
+
+// PurgeResponse reports the URLs submitted and Cloudflare's answer
+type PurgeResponse struct {
+	URLs   []string                `json:"urls"`
+	Result *cloudflare.PurgeResult `json:"result,omitempty"`
+	Error  string                  `json:"error,omitempty"`
+}
+
+// requireAdminToken rejects requests without "Authorization: Bearer <token>"
+func requireAdminToken(token string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			given, ok := strings.CutPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid admin token")
+			}
+			return next(c)
+		}
+	}
+}
+
+// PurgeRoute purges cameras' image and page URLs from the Cloudflare cache
+func PurgeRoute(s *store.Store, client *cloudflare.Client) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		if client == nil {
+			return echo.NewHTTPError(http.StatusServiceUnavailable, "Cloudflare purging is not configured")
+		}
+
+		var req PurgeRequest
+		if err := c.Bind(&req); err != nil {
+			return err
+		}
+		if len(req.Cameras) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "no cameras given")
+		}
+
+		base := baseURL(c)
+		var urls []string
+		for _, name := range req.Cameras {
+			camera := findCamera(s, name)
+			if camera == nil {
+				return echo.NewHTTPError(http.StatusNotFound, "unknown camera: "+name)
+			}
+			urls = append(urls, cameraPurgeURLs(base, camera)...)
+		}
+
+		result, err := client.PurgeURLs(c.Request().Context(), urls)
+		if err != nil {
+			return c.JSON(http.StatusBadGateway, PurgeResponse{URLs: urls, Result: result, Error: err.Error()})
+		}
+		return c.JSON(http.StatusOK, PurgeResponse{URLs: urls, Result: result})
+	}
+}
+
+// findCamera looks a camera up by ID or slug without waiting for the first
+// image sync
+func findCamera(s *store.Store, idOrSlug string) *store.Camera {
+	for _, entry := range s.Entries() {
+		if entry.ID == idOrSlug || entry.Camera.GetSlug() == idOrSlug {
+			return entry.Camera
+		}
+	}
+	return nil
+}
+
+// cameraPurgeURLs lists the cacheable URLs that serve a camera
+func cameraPurgeURLs(base string, camera *store.Camera) []string {
+	urls := []string{base + "/image/" + camera.ID}
+	if slug := camera.GetSlug(); slug != "" {
+		urls = append(urls, base+"/camera/"+slug, base+"/camera/"+slug+".json")
+	}
+	return urls
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stefanpenner/lcc-live/web/cloudflare"
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurgeRoute(t *testing.T) {
+	var submitted []string
+	cfSuccess := true
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/zones/zone-1/purge_cache", r.URL.Path)
+		var body struct {
+			Files []string `json:"files"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		submitted = body.Files
+		if cfSuccess {
+			w.Write([]byte(`{"success":true,"errors":[],"result":{"id":"zone-1"}}`))
+		} else {
+			w.Write([]byte(`{"success":false,"errors":[{"code":10000,"message":"Authentication error"}]}`))
+		}
+	}))
+	t.Cleanup(api.Close)
+
+	cf := cloudflare.NewClient("zone-1", "cf-token")
+	cf.SetBaseURL(api.URL)
+
+	canyons := &store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "Little Cottonwood Canyon",
+			Cameras: []store.Camera{
+				{Kind: "img", Src: "http://cams.example/a.jpg", Alt: "Camera A"},
+				{Kind: "img", Src: "http://cams.example/b.jpg", Alt: "Camera B"},
+			},
+		},
+	}
+	testStore := store.NewStore(canyons)
+	cameraA := (*canyons)[0].Cameras[0]
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+		AdminToken: "admin-secret",
+		Cloudflare: cf,
+	})
+	require.NoError(t, err)
+
+	purge := func(token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "https://lcc.live/_/purge", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("requires the admin token", func(t *testing.T) {
+		submitted = nil
+		assert.Equal(t, http.StatusUnauthorized, purge("", `{"cameras":["camera-a"]}`).Code)
+		assert.Equal(t, http.StatusUnauthorized, purge("wrong", `{"cameras":["camera-a"]}`).Code)
+		assert.Nil(t, submitted, "nothing is purged without auth")
+	})
+
+	t.Run("purges a camera's URLs by slug or ID", func(t *testing.T) {
+		rec := purge("admin-secret", `{"cameras":["camera-a"]}`)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		want := []string{
+			"https://lcc.live/image/" + cameraA.ID,
+			"https://lcc.live/camera/camera-a",
+			"https://lcc.live/camera/camera-a.json",
+		}
+		assert.Equal(t, want, submitted)
+
+		var resp PurgeResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, want, resp.URLs)
+		require.NotNil(t, resp.Result)
+		assert.True(t, resp.Result.Success)
+
+		rec = purge("admin-secret", `{"cameras":["`+cameraA.ID+`","camera-b"]}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Len(t, submitted, 6)
+	})
+
+	t.Run("unknown camera", func(t *testing.T) {
+		submitted = nil
+		assert.Equal(t, http.StatusNotFound, purge("admin-secret", `{"cameras":["nope"]}`).Code)
+		assert.Equal(t, http.StatusBadRequest, purge("admin-secret", `{}`).Code)
+		assert.Nil(t, submitted)
+	})
+
+	t.Run("cloudflare failure", func(t *testing.T) {
+		cfSuccess = false
+		defer func() { cfSuccess = true }()
+		rec := purge("admin-secret", `{"cameras":["camera-a"]}`)
+		assert.Equal(t, http.StatusBadGateway, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Authentication error")
+	})
+}
+
+func TestPurgeRoute_DisabledWithoutAdminToken(t *testing.T) {
+	app, err := Start(ServerConfig{
+		Store:      store.NewStore(&store.Canyons{{ID: "LCC", Name: "LCC"}}),
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+		Cloudflare: cloudflare.NewClient("zone-1", "cf-token"),
+	})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/_/purge", strings.NewReader(`{"cameras":["a"]}`)))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestStart_BodyLimit(t *testing.T) {
+	app, err := Start(ServerConfig{
+		Store:      store.NewStore(&store.Canyons{{ID: "LCC", Name: "LCC"}, {ID: "BCC", Name: "BCC"}}),
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+		AdminToken: "admin-secret",
+		BodyLimit:  1024,
+	})
+	require.NoError(t, err)
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/_/maintenance", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	oversized := `{"enabled":false,"padding":"` + strings.Repeat("x", 2048) + `"}`
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post(oversized).Code)
+	assert.Equal(t, http.StatusOK, post(`{"enabled":false}`).Code)
+}
+
+func TestCacheTagHeaders(t *testing.T) {
+	srv := setupTestServer(t)
+
+	for path, tag := range map[string]string{
+		"/":                         "canyon-lcc",
+		"/bcc.json":                 "canyon-bcc",
+		"/image/lcc-camera-1":       "camera-lcc-camera-1,canyon-lcc",
+		"/camera/lcc-camera-1":      "camera-lcc-camera-1,canyon-lcc",
+		"/camera/lcc-camera-1.json": "camera-lcc-camera-1,canyon-lcc",
+	} {
+		rec := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, rec.Code, path)
+		assert.Equal(t, tag, rec.Header().Get("Cache-Tag"), path)
+	}
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"time"
+
+	sentryecho "github.com/getsentry/sentry-go/echo"
+	"github.com/labstack/echo/v4"
+	"github.com/stefanpenner/lcc-live/web/logger"
+)
+
+// recoverStackSize bounds the stack trace captured for a panic
+const recoverStackSize = 4 << 10 // 4 KB
+
+// RecoverMiddleware turns a panic in a handler or inner middleware into a
+// 500. The panic is reported through logger.Error and written to the error
+// log with the request ID and stack trace. It must be outermost to catch
+// panics in all middleware.
+//
+// With sentryEnabled, Sentry's Echo middleware runs just inside it, giving
+// handlers a per-request hub. That middleware captures the panic with the
+// request's context and re-panics, so logger.Error then only logs it rather
+// than sending it to Sentry a second time.
+func RecoverMiddleware(sentryEnabled bool) echo.MiddlewareFunc {
+	var sentryMiddleware echo.MiddlewareFunc
+	if sentryEnabled {
+		sentryMiddleware = sentryecho.New(sentryecho.Options{
+			Repanic: true,
+		})
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if sentryMiddleware != nil {
+			next = sentryMiddleware(next)
+		}
+		return func(c echo.Context) error {
+			start := time.Now()
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				if r == http.ErrAbortHandler {
+					panic(r)
+				}
+
+				err, ok := r.(error)
+				if !ok {
+					err = fmt.Errorf("%v", r)
+				}
+				err = fmt.Errorf("panic: %w", err)
+
+				stack := make([]byte, recoverStackSize)
+				stack = stack[:runtime.Stack(stack, false)]
+
+				req := c.Request()
+				requestID, _ := c.Get("request_id").(string)
+				if sentryEnabled {
+					logger.Error("[%s] %s %s: %v\n%s", requestID, req.Method, req.URL.Path, err, stack)
+				} else {
+					logger.Error(err, "[%s] %s %s: %v\n%s", requestID, req.Method, req.URL.Path, err, stack)
+				}
+				LogError(http.StatusInternalServerError, req.Method, req.URL.Path, req.URL.String(),
+					c.RealIP(), req.UserAgent(), time.Since(start), err)
+
+				// Renders problem+json for JSON endpoints
+				c.Error(echo.NewHTTPError(http.StatusInternalServerError))
+			}()
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sentryecho "github.com/getsentry/sentry-go/echo"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stefanpenner/lcc-live/web/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoverMiddleware(t *testing.T) {
+	var captured []error
+	logger.SetSentryCaptureException(func(err error) interface{} {
+		captured = append(captured, err)
+		return nil
+	})
+	t.Cleanup(func() { logger.SetSentryCaptureException(nil) })
+
+	e := echo.New()
+	e.HTTPErrorHandler = problemErrorHandler(e)
+	e.Use(RecoverMiddleware(false))
+	e.Use(middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+		RequestIDHandler: func(c echo.Context, id string) {
+			c.Set("request_id", id)
+		},
+	}))
+	e.GET("/api/v1/boom", func(c echo.Context) error {
+		panic("boom")
+	})
+	e.GET("/boom", func(c echo.Context) error {
+		panic(errors.New("kaboom"))
+	})
+
+	t.Run("json endpoint gets problem+json", func(t *testing.T) {
+		captured = nil
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/boom", nil))
+
+		require.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, mimeProblemJSON, rec.Header().Get("Content-Type"))
+		assert.NotEmpty(t, rec.Header().Get(echo.HeaderXRequestID))
+		var problem Problem
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+		assert.Equal(t, http.StatusInternalServerError, problem.Status)
+		assert.NotContains(t, rec.Body.String(), "boom", "panic details stay out of the response")
+
+		require.Len(t, captured, 1)
+		assert.EqualError(t, captured[0], "panic: boom")
+	})
+
+	t.Run("html endpoint gets a plain 500", func(t *testing.T) {
+		captured = nil
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/boom", nil))
+
+		require.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "kaboom")
+		require.Len(t, captured, 1)
+		assert.EqualError(t, captured[0], "panic: kaboom")
+	})
+}
+
+func TestRecoverMiddleware_Sentry(t *testing.T) {
+	var captured []error
+	logger.SetSentryCaptureException(func(err error) interface{} {
+		captured = append(captured, err)
+		return nil
+	})
+	t.Cleanup(func() { logger.SetSentryCaptureException(nil) })
+
+	e := echo.New()
+	e.HTTPErrorHandler = problemErrorHandler(e)
+	e.Use(RecoverMiddleware(true))
+	var hasHub bool
+	e.GET("/boom", func(c echo.Context) error {
+		hasHub = sentryecho.GetHubFromContext(c) != nil
+		panic("boom")
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/boom", nil))
+
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.True(t, hasHub, "handlers get a per-request Sentry hub")
+	assert.Empty(t, captured, "Sentry's middleware already reported the panic")
+}
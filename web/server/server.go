@@ -1,12 +1,18 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
 	"io/fs"
 	"math"
 	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
 	"regexp"
 	"strconv"
 	"strings"
@@ -15,10 +21,10 @@ import (
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
-	sentryecho "github.com/getsentry/sentry-go/echo"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stefanpenner/lcc-live/web/cloudflare"
 	"github.com/stefanpenner/lcc-live/web/store"
 )
 
@@ -50,6 +56,14 @@ func slugify(name string) string {
 	return slug
 }
 
+// skipGzip skips compressing camera images, which are already compressed
+func skipGzip(c echo.Context) bool {
+	path := c.Request().URL.Path
+	return strings.HasPrefix(path, "/image/") ||
+		(strings.HasPrefix(path, "/camera/") && strings.HasSuffix(path, "/og.jpg")) ||
+		isStreamingRequest(c)
+}
+
 func formatUnixTime(timestamp int64) string {
 	if timestamp == 0 {
 		return "Unknown"
@@ -123,16 +137,91 @@ func precipIcon(airTemp *string) template.HTML {
 	return template.HTML(svgRain)
 }
 
+// timeAgo is formatTimeAgo for a time.Time, such as a camera's FetchedAt
+func timeAgo(t time.Time) string {
+	if t.IsZero() {
+		return formatTimeAgo(0)
+	}
+	return formatTimeAgo(t.Unix())
+}
+
+// formatTemp renders a UDOT temperature reading in whole degrees
+// Fahrenheit, e.g. "31°F", or "" if there's no reading
+func formatTemp(temp *string) string {
+	rounded := roundTemp(temp)
+	if rounded == "" {
+		return ""
+	}
+	return rounded + "°F"
+}
+
+// imageURL is the path of a camera's live image, optionally sized for a
+// display width in pixels (ImageRoute's ?w=)
+func imageURL(cameraID string, width ...int) string {
+	path := "/image/" + url.PathEscape(cameraID)
+	if len(width) > 0 && width[0] > 0 {
+		path += "?w=" + strconv.Itoa(width[0])
+	}
+	return path
+}
+
+// toJSON encodes v for a <script> block; encoding/json escapes <, > and &,
+// so the result can't close the script
+func toJSON(v any) (template.JS, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return template.JS(b), nil
+}
+
+// templateFuncs are available to every page template:
+//
+//	slugify "Mt. Baldy"            mt-baldy
+//	formatUnixTime 1700000000      Nov 14, 2023 3:13 PM MST (local time)
+//	formatTimeAgo 1700000000       5m, 2h, 3d... ("just now" under a minute)
+//	timeAgo .FetchedAt             formatTimeAgo for a time.Time
+//	isStale 1700000000             older than 30 minutes (or unknown)
+//	roundTemp .AirTemperature      31
+//	formatTemp .AirTemperature     31°F
+//	precipIcon .AirTemperature     snow, mixed or rain SVG for the temperature
+//	imageURL .ID 640               /image/<id>?w=640 (width optional)
+//	json .                         JSON for a <script> block
+//	version                        the build's version string
 var templateFuncs = template.FuncMap{
 	"slugify":        slugify,
 	"formatUnixTime": formatUnixTime,
 	"formatTimeAgo":  formatTimeAgo,
+	"timeAgo":        timeAgo,
 	"isStale":        isStale,
 	"roundTemp":      roundTemp,
+	"formatTemp":     formatTemp,
 	"precipIcon":     precipIcon,
+	"imageURL":       imageURL,
+	"json":           toJSON,
 	"version":        GetVersionString,
 }
 
+// fallbackTemplates stand in for the real pages when they fail to parse
+// and LenientTemplates is set
+const fallbackTemplates = `
+{{define "canyon.html.tmpl"}}<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Name}}</title></head><body>
+<h1>{{.Name}}</h1>
+{{range .Cameras}}{{if ne .Kind "iframe"}}<p><img src="/image/{{.ID}}" alt="{{.Alt}}" width="100%"></p>{{end}}{{else}}<p>No cameras are available for {{.Name}} right now.</p>{{end}}
+</body></html>{{end}}
+{{define "camera.html.tmpl"}}<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Camera.Alt}}</title></head><body>
+<h1>{{.Camera.Alt}}</h1>
+{{if ne .Camera.Kind "iframe"}}<img src="{{.ImageURL}}" alt="{{.Camera.Alt}}" width="100%">{{end}}
+<p><a href="{{.CanyonPath}}">Back</a></p>
+</body></html>{{end}}
+{{define "maintenance.html.tmpl"}}<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Down for maintenance</title></head><body>
+<h1>Down for maintenance</h1>
+</body></html>{{end}}
+`
+
 // Render renders a template with the given data
 func (t *TemplateRenderer) Render(w io.Writer, name string, data interface{}, _ echo.Context) error {
 	// In dev mode, reload templates on every request for hot reloading
@@ -192,6 +281,103 @@ type ServerConfig struct {
 	TemplateFS    fs.FS
 	DevMode       bool
 	SentryEnabled bool
+	// LenientTemplates starts the server with minimal fallback pages, rather
+	// than failing, if the templates don't parse
+	LenientTemplates bool
+	// AdminToken gates mutating /_/ endpoints; they aren't registered
+	// without one
+	AdminToken string
+	// Cloudflare purges cached URLs via POST /_/purge (nil disables it)
+	Cloudflare *cloudflare.Client
+	// LogHub feeds GET /_/logs, an admin stream of recent log lines (nil
+	// disables it)
+	LogHub *LogHub
+	// CachePolicy sets Cache-Control lifetimes per route class; unset
+	// classes use DefaultCachePolicy
+	CachePolicy CachePolicy
+	// IPFilter blocks clients by address (no rules disables filtering)
+	IPFilter IPFilter
+	// TrustedProxies are the peers whose X-Forwarded-For is believed when
+	// finding the client IP (see TrustedProxyIPExtractor). Empty keeps
+	// Echo's default, which believes forwarded headers from anyone.
+	TrustedProxies []netip.Prefix
+	// AccessLogFormat is AccessLogText (the default), AccessLogJSON or
+	// AccessLogBoth
+	AccessLogFormat string
+	// AccessLogOutput receives JSON access logs (defaults to stdout)
+	AccessLogOutput io.Writer
+	// AccessLogSampleRate logs 1 in every N 2xx requests, in both access
+	// log formats; anything else is always logged (0 or 1 logs everything)
+	AccessLogSampleRate int
+	// CanonicalHost, e.g. "lcc.live" or "https://lcc.live", 301s requests
+	// for any other host to it (empty disables the redirect)
+	CanonicalHost string
+	// RequestTimeout bounds how long a handler may run before the client
+	// gets a 503 (zero uses DefaultRequestTimeout)
+	RequestTimeout time.Duration
+	// Context is the server's lifetime. Work that outlives its request, like
+	// a forced sync, stops when it's cancelled (nil never cancels).
+	Context context.Context
+	// HealthCheckSmokeTTL is how long /healthcheck reuses its page-render
+	// smoke test (zero uses DefaultHealthCheckSmokeTTL)
+	HealthCheckSmokeTTL time.Duration
+	// StaleCameraThreshold is how long an unchanged image may go before
+	// canyon JSON marks the camera stale (zero uses
+	// DefaultStaleCameraThreshold)
+	StaleCameraThreshold time.Duration
+	// DegradedThreshold is the fraction of cameras (0-1) that may fail a
+	// sync before /healthcheck reports the service degraded (zero uses
+	// DefaultDegradedThreshold)
+	DegradedThreshold float64
+	// ServerTiming adds Server-Timing headers (store lookup, render, total)
+	// to responses
+	ServerTiming bool
+	// Maintenance toggles maintenance mode at runtime, also via POST
+	// /_/maintenance (nil starts with it off)
+	Maintenance *Maintenance
+	// BodyLimit caps request bodies on mutating (non-GET/HEAD) requests, in
+	// bytes; larger bodies get a 413 (zero uses DefaultBodyLimit)
+	BodyLimit int64
+}
+
+// probePaths are hit by health probes, which must always reach the app
+// whatever the client filtering or host redirects
+var probePaths = map[string]bool{
+	"/healthcheck": true,
+	"/livez":       true,
+}
+
+// DefaultBodyLimit is plenty for the small JSON and form bodies the admin
+// endpoints take
+const DefaultBodyLimit = 64 << 10 // 64 KB
+
+// isReadOnlyRequest reports whether a request can't carry a meaningful body
+func isReadOnlyRequest(c echo.Context) bool {
+	switch c.Request().Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// DefaultRequestTimeout is long enough for the slowest handler (a Cloudflare
+// purge) while still bounding worst-case latency
+const DefaultRequestTimeout = 10 * time.Second
+
+// skipRequestTimeout reports whether a request may outlive the request
+// timeout: streams, and forced syncs, which are bounded by the store's own
+// fetch timeouts instead
+func skipRequestTimeout(c echo.Context) bool {
+	return isStreamingRequest(c) || c.Request().URL.Path == syncPath
+}
+
+// isStreamingRequest reports whether a request is for a long-lived stream
+// (SSE or WebSocket), which the request timeout must not cut off or buffer
+func isStreamingRequest(c echo.Context) bool {
+	req := c.Request()
+	return req.URL.Path == logStreamPath ||
+		strings.Contains(req.Header.Get("Accept"), "text/event-stream") ||
+		strings.EqualFold(req.Header.Get("Upgrade"), "websocket")
 }
 
 // Start starts the HTTP server with the given configuration
@@ -199,6 +385,10 @@ func Start(cfg ServerConfig) (*echo.Echo, error) {
 	e := echo.New()
 	e.HideBanner = true
 	e.HidePort = true
+	e.HTTPErrorHandler = problemErrorHandler(e)
+	if len(cfg.TrustedProxies) > 0 {
+		e.IPExtractor = TrustedProxyIPExtractor(cfg.TrustedProxies)
+	}
 
 	// Initialize error logger
 	if err := InitErrorLogger(""); err != nil {
@@ -213,22 +403,9 @@ func Start(cfg ServerConfig) (*echo.Echo, error) {
 		e.Logger.SetOutput(customLogWriter{})
 	}
 
-	// Recover middleware must be outermost to catch panics in all middleware
-	if !cfg.SentryEnabled {
-		e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{
-			DisableStackAll:   false,
-			DisablePrintStack: false,
-			StackSize:         4 << 10, // 4 KB
-			LogLevel:          0,       // Log all panics
-		}))
-	}
-
-	// Add Sentry middleware to capture panics and errors (only if Sentry is enabled)
-	if cfg.SentryEnabled {
-		e.Use(sentryecho.New(sentryecho.Options{
-			Repanic: true,
-		}))
-	}
+	// Recover middleware must be outermost to catch panics in all middleware.
+	// It runs Sentry's middleware, when enabled, just inside itself.
+	e.Use(RecoverMiddleware(cfg.SentryEnabled))
 
 	// Security headers
 	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -245,9 +422,44 @@ func Start(cfg ServerConfig) (*echo.Echo, error) {
 		}
 	})
 
-	// Request timeout to prevent slow clients from holding connections
-	e.Use(middleware.TimeoutWithConfig(middleware.TimeoutConfig{
-		Timeout: 30 * time.Second,
+	// Tag every request with an X-Request-Id (reusing the client's) so
+	// access logs can be correlated
+	e.Use(middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+		RequestIDHandler: func(c echo.Context, id string) {
+			c.Set("request_id", id)
+		},
+	}))
+
+	// Bound request bodies before any handler reads them
+	bodyLimit := cfg.BodyLimit
+	if bodyLimit <= 0 {
+		bodyLimit = DefaultBodyLimit
+	}
+	e.Use(middleware.BodyLimitWithConfig(middleware.BodyLimitConfig{
+		Skipper: isReadOnlyRequest,
+		Limit:   strconv.FormatInt(bodyLimit, 10),
+	}))
+
+	// Abort wedged handlers so they can't hold connections indefinitely
+	requestTimeout := cfg.RequestTimeout
+	if requestTimeout <= 0 {
+		requestTimeout = DefaultRequestTimeout
+	}
+	lifetime := cfg.Context
+	if lifetime == nil {
+		lifetime = context.Background()
+	}
+	// Uses a context deadline rather than middleware.Timeout, which answers
+	// from another goroutine and races handlers still holding the context
+	e.Use(middleware.ContextTimeoutWithConfig(middleware.ContextTimeoutConfig{
+		Skipper: skipRequestTimeout,
+		Timeout: requestTimeout,
+		ErrorHandler: func(err error, c echo.Context) error {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return echo.NewHTTPError(http.StatusServiceUnavailable, "request timed out").SetInternal(err)
+			}
+			return err
+		},
 	}))
 
 	// Add version header to all responses
@@ -260,6 +472,9 @@ func Start(cfg ServerConfig) (*echo.Echo, error) {
 
 	// Add metrics middleware early to track all requests
 	e.Use(MetricsMiddleware())
+	if cfg.ServerTiming {
+		e.Use(ServerTimingMiddleware())
+	}
 
 	// Increment request and error counters for UI stats, and log errors
 	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -288,35 +503,59 @@ func Start(cfg ServerConfig) (*echo.Echo, error) {
 		}
 	})
 
+	if cfg.CanonicalHost != "" {
+		redirect, err := CanonicalHostMiddleware(cfg.CanonicalHost)
+		if err != nil {
+			return nil, err
+		}
+		e.Use(redirect)
+	}
+
+	// Block denied clients before any handler runs (blocked requests are
+	// still counted and logged above)
+	if cfg.IPFilter.Enabled() {
+		e.Use(IPFilterMiddleware(cfg.IPFilter))
+	}
+
 	e.Use(middleware.GzipWithConfig(middleware.GzipConfig{
 		Level: 5,
+		Skipper: func(c echo.Context) bool {
+			if !strings.HasPrefix(c.Request().URL.Path, staticPrefix) {
+				return skipGzip(c)
+			}
+			// Precompressed static assets are sent as-is, and static
+			// misses are too small to be worth compressing
+			lookup := lookupStatic(c, cfg.StaticFS)
+			return lookup.asset == "" || lookup.encoding != ""
+		},
 	}))
 
 	// Serve static files with long-term caching
 	// These files (CSS, JS, images) are versioned via their URLs or rarely change
-	e.GET("/s/*", func(c echo.Context) error {
-		// In dev mode, disable caching for static files
-		if cfg.DevMode {
-			c.Response().Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-			c.Response().Header().Set("Pragma", "no-cache")
-			c.Response().Header().Set("Expires", "0")
-		} else {
-			// Set aggressive caching for static assets
-			// Long cache time is safe because:
-			// 1. Static files rarely change
-			// 2. HTML pages are already cache-busted via version ETags
-			// 3. When HTML changes, it references new/updated static files
-			c.Response().Header().Set("Cache-Control", "public, max-age=86400, immutable")
+	e.GET(staticPrefix+"*", StaticRoute(cfg.StaticFS, cfg.DevMode))
+	// Well-known paths browsers and iOS request without a link
+	e.GET("/favicon.ico", StaticFileRoute(cfg.StaticFS, faviconFile, cfg.DevMode))
+	e.GET("/apple-touch-icon.png", StaticFileRoute(cfg.StaticFS, appleTouchIconFile, cfg.DevMode))
+	e.GET("/apple-touch-icon-precomposed.png", StaticFileRoute(cfg.StaticFS, appleTouchIconFile, cfg.DevMode))
+	e.GET("/manifest.webmanifest", ManifestRoute(cfg.StaticFS, cfg.DevMode))
+
+	// Structured access logs for log pipelines
+	e.Use(accessLogSampling(cfg.AccessLogSampleRate))
+	if cfg.AccessLogFormat == AccessLogJSON || cfg.AccessLogFormat == AccessLogBoth {
+		out := cfg.AccessLogOutput
+		if out == nil {
+			out = os.Stdout
 		}
-		return echo.WrapHandler(http.StripPrefix("/s", http.FileServer(http.FS(cfg.StaticFS))))(c)
-	})
+		e.Use(jsonAccessLog(out))
+	}
+	styledAccessLog := cfg.AccessLogFormat != AccessLogJSON
 
 	// Custom logger middleware that routes through our UI
 	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			err := next(c)
 
-			if LogWriter != nil {
+			if LogWriter != nil && styledAccessLog && shouldLogAccess(c, err) {
 				req := c.Request()
 				res := c.Response()
 
@@ -427,7 +666,14 @@ func Start(cfg ServerConfig) (*echo.Echo, error) {
 	// Custom Rendering Stuff [
 	tmpl, err := template.New("").Funcs(templateFuncs).ParseFS(cfg.TemplateFS, "*.html.tmpl")
 	if err != nil {
-		return nil, err
+		if !cfg.LenientTemplates {
+			return nil, err
+		}
+		// Keep images, JSON, and health checks up with bare-bones pages
+		if LogWriter != nil {
+			LogWriter(fmt.Sprintf("Warning: Failed to parse templates, using fallback pages: %v", err))
+		}
+		tmpl = template.Must(template.New("").Funcs(templateFuncs).Parse(fallbackTemplates))
 	}
 	renderer := &TemplateRenderer{
 		templates: tmpl,
@@ -436,6 +682,27 @@ func Start(cfg ServerConfig) (*echo.Echo, error) {
 	}
 	e.Renderer = renderer
 
+	// Public routes serve the maintenance page while maintenance mode is on
+	maintenance := cfg.Maintenance
+	if maintenance == nil {
+		maintenance = NewMaintenance(false)
+	}
+	e.Use(maintenanceMiddleware(maintenance))
+
+	// Make the cache policy available to routes that set Cache-Control
+	policy := cfg.CachePolicy.withDefaults()
+	staleThreshold := cfg.StaleCameraThreshold
+	if staleThreshold <= 0 {
+		staleThreshold = DefaultStaleCameraThreshold
+	}
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("_cache_policy", policy)
+			c.Set("_stale_camera_threshold", staleThreshold)
+			return next(c)
+		}
+	})
+
 	// In dev mode, set dev mode flag on context and disable caching for all responses
 	if cfg.DevMode {
 		e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -454,32 +721,49 @@ func Start(cfg ServerConfig) (*echo.Echo, error) {
 		})
 	}
 
-	// handleIndex handles both GET and HEAD requests for the index route
-
-	e.GET("/", CanyonRoute(cfg.Store, "LCC"))
-	e.HEAD("/", CanyonRoute(cfg.Store, "LCC"))
-	e.GET("/.json", CanyonRoute(cfg.Store, "LCC"))
-	e.HEAD("/.json", CanyonRoute(cfg.Store, "LCC"))
-
-	e.GET("/lcc", CanyonRoute(cfg.Store, "LCC"))
-	e.HEAD("/lcc", CanyonRoute(cfg.Store, "LCC"))
-	e.GET("/lcc.json", CanyonRoute(cfg.Store, "LCC"))
-	e.HEAD("/lcc.json", CanyonRoute(cfg.Store, "LCC"))
+	// Each canyon is served at /<id> and /<id>.json; the first canyon is
+	// also the index. Canyons added by a later Reload need a restart to get
+	// routes.
+	for i, canyon := range cfg.Store.Canyons() {
+		handler := CanyonRoute(cfg.Store, canyon.ID)
+		if i == 0 {
+			e.GET("/", handler)
+			e.HEAD("/", handler)
+			e.GET("/.json", handler)
+			e.HEAD("/.json", handler)
+		}
+		e.GET(canyon.Path(), handler)
+		e.HEAD(canyon.Path(), handler)
+		e.GET(canyon.Path()+".json", handler)
+		e.HEAD(canyon.Path()+".json", handler)
+		e.GET(canyon.Path()+"/conditions.geojson", ConditionsGeoJSONRoute(cfg.Store, canyon.ID))
+	}
 
-	e.GET("/bcc", CanyonRoute(cfg.Store, "BCC"))
-	e.HEAD("/bcc", CanyonRoute(cfg.Store, "BCC"))
-	e.GET("/bcc.json", CanyonRoute(cfg.Store, "BCC"))
-	e.HEAD("/bcc.json", CanyonRoute(cfg.Store, "BCC"))
+	// Optional; without it missing images get a plain 503
+	placeholder, _ := fs.ReadFile(cfg.StaticFS, placeholderFile)
+	e.GET("/image/:id", ImageRoute(cfg.Store, placeholder))
+	e.HEAD("/image/:id", ImageRoute(cfg.Store, placeholder))
+	e.GET("/image/:id/:etag", ImageByETagRoute(cfg.Store))
+	e.HEAD("/image/:id/:etag", ImageByETagRoute(cfg.Store))
 
-	e.GET("/image/:id", ImageRoute(cfg.Store))
-	e.HEAD("/image/:id", ImageRoute(cfg.Store))
+	e.GET("/camera/:id/timelapse.json", TimelapseRoute(cfg.Store))
+	e.GET("/camera/:id/diff", FrameDiffRoute(cfg.Store))
+	e.GET("/camera/:id/history.json", CameraHistoryRoute(cfg.Store))
+	e.GET("/camera/:id/origin", CameraOriginRoute(cfg.Store))
+	e.GET("/camera/:id/og.jpg", OGImageRoute(cfg.Store))
+	e.HEAD("/camera/:id/og.jpg", OGImageRoute(cfg.Store))
 
 	e.GET("/camera/*", CameraRoute(cfg.Store))
 	e.HEAD("/camera/*", CameraRoute(cfg.Store))
 
+	e.GET("/sitemap.xml", SitemapRoute(cfg.Store))
+	e.GET("/robots.txt", RobotsRoute())
+
 	e.GET("/api/canyon/:canyon/udot", UDOTRoute(cfg.Store))
+	e.GET("/api/v1/:canyon", CanyonFeedRoute(cfg.Store))
+	e.HEAD("/api/v1/:canyon", CanyonFeedRoute(cfg.Store))
 
-	e.GET("/healthcheck", HealthCheckRoute(cfg.Store))
+	e.GET("/healthcheck", HealthCheckRoute(cfg.Store, cfg.HealthCheckSmokeTTL, cfg.DegradedThreshold))
 
 	// Internal/admin endpoints under /_/
 	// These endpoints should never be cached
@@ -493,8 +777,21 @@ func Start(cfg ServerConfig) (*echo.Echo, error) {
 			return next(c)
 		}
 	})
-	internal.GET("/version", VersionRoute())
+	internal.GET("/version", VersionRoute(cfg.Store))
+	internal.GET("/cameras", CamerasRoute(cfg.Store))
 	internal.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
+	internal.GET("/metrics.json", MetricsSummaryRoute(cfg.Store))
+	internal.GET("/maintenance", MaintenanceRoute(maintenance))
+
+	if cfg.AdminToken != "" {
+		admin := requireAdminToken(cfg.AdminToken)
+		internal.POST("/purge", PurgeRoute(cfg.Store, cfg.Cloudflare), admin)
+		internal.POST("/sync", SyncRoute(lifetime, cfg.Store), admin)
+		internal.POST("/maintenance", MaintenanceRoute(maintenance), admin)
+		if cfg.LogHub != nil {
+			internal.GET("/logs", LogStreamRoute(cfg.LogHub), admin)
+		}
+	}
 
 	return e, nil
 }
@@ -32,13 +32,14 @@ func FuzzImageRoute(f *testing.F) {
 		w.Header().Set("Content-Type", "image/jpeg")
 		w.Header().Set("ETag", "\"test-etag\"")
 		if r.Method == "GET" {
-			w.Write([]byte("test image"))
+			w.Write(testImage("test image"))
 		}
 	}))
 	defer imageServer.Close()
 
 	canyons := &store.Canyons{
-		LCC: store.Canyon{
+		{
+			ID:   "LCC",
 			Name: "Little Cottonwood Canyon",
 			ETag: "\"test-lcc-etag\"",
 			Cameras: []store.Camera{
@@ -50,7 +51,7 @@ func FuzzImageRoute(f *testing.F) {
 				},
 			},
 		},
-		BCC: store.Canyon{Name: "BCC"},
+		{ID: "BCC", Name: "BCC"},
 	}
 
 	testStore := store.NewStore(canyons)
@@ -64,11 +65,10 @@ func FuzzImageRoute(f *testing.F) {
 	staticFS := fstest.MapFS{}
 
 	app, err := Start(ServerConfig{
-		Store:         testStore,
-		StaticFS:      staticFS,
-		TemplateFS:    tmplFS,
-		DevMode:       false,
-		SentryEnabled: false,
+		Store:      testStore,
+		StaticFS:   staticFS,
+		TemplateFS: tmplFS,
+		DevMode:    false,
 	})
 	if err != nil {
 		f.Fatal(err)
@@ -129,13 +129,14 @@ func FuzzCanyonRoute(f *testing.F) {
 		w.Header().Set("Content-Type", "image/jpeg")
 		w.Header().Set("ETag", "\"test-etag\"")
 		if r.Method == "GET" {
-			w.Write([]byte("test image"))
+			w.Write(testImage("test image"))
 		}
 	}))
 	defer imageServer.Close()
 
 	canyons := &store.Canyons{
-		LCC: store.Canyon{
+		{
+			ID:   "LCC",
 			Name: "Little Cottonwood Canyon",
 			ETag: "\"test-lcc-etag\"",
 			Status: store.Camera{
@@ -153,7 +154,8 @@ func FuzzCanyonRoute(f *testing.F) {
 				},
 			},
 		},
-		BCC: store.Canyon{
+		{
+			ID:   "BCC",
 			Name: "Big Cottonwood Canyon",
 			ETag: "\"test-bcc-etag\"",
 			Status: store.Camera{
@@ -188,11 +190,10 @@ func FuzzCanyonRoute(f *testing.F) {
 	}
 
 	app, err := Start(ServerConfig{
-		Store:         testStore,
-		StaticFS:      staticFS,
-		TemplateFS:    tmplFS,
-		DevMode:       false,
-		SentryEnabled: false,
+		Store:      testStore,
+		StaticFS:   staticFS,
+		TemplateFS: tmplFS,
+		DevMode:    false,
 	})
 	if err != nil {
 		f.Fatal(err)
@@ -253,13 +254,14 @@ func FuzzHTTPHeaders(f *testing.F) {
 		w.Header().Set("Content-Type", "image/jpeg")
 		w.Header().Set("ETag", "\"test-etag\"")
 		if r.Method == "GET" {
-			w.Write([]byte("test image"))
+			w.Write(testImage("test image"))
 		}
 	}))
 	defer imageServer.Close()
 
 	canyons := &store.Canyons{
-		LCC: store.Canyon{
+		{
+			ID:   "LCC",
 			Name: "LCC",
 			ETag: "\"test-lcc-etag\"",
 			Cameras: []store.Camera{
@@ -271,7 +273,7 @@ func FuzzHTTPHeaders(f *testing.F) {
 				},
 			},
 		},
-		BCC: store.Canyon{Name: "BCC"},
+		{ID: "BCC", Name: "BCC"},
 	}
 
 	testStore := store.NewStore(canyons)
@@ -285,18 +287,17 @@ func FuzzHTTPHeaders(f *testing.F) {
 	staticFS := fstest.MapFS{}
 
 	app, err := Start(ServerConfig{
-		Store:         testStore,
-		StaticFS:      staticFS,
-		TemplateFS:    tmplFS,
-		DevMode:       false,
-		SentryEnabled: false,
+		Store:      testStore,
+		StaticFS:   staticFS,
+		TemplateFS: tmplFS,
+		DevMode:    false,
 	})
 	if err != nil {
 		f.Fatal(err)
 	}
 	srv := &http.Server{Handler: app}
 
-	cameraID := testStore.Canyon("LCC").Cameras[0].ID
+	cameraID := mustCanyon(f, testStore, "LCC").Cameras[0].ID
 
 	f.Fuzz(func(t *testing.T, userAgent string, ifNoneMatch string) {
 		defer func() {
@@ -354,17 +355,18 @@ func FuzzStaticFiles(f *testing.F) {
 		w.Header().Set("Content-Type", "image/jpeg")
 		w.Header().Set("ETag", "\"test-etag\"")
 		if r.Method == "GET" {
-			w.Write([]byte("test image"))
+			w.Write(testImage("test image"))
 		}
 	}))
 	defer imageServer.Close()
 
 	canyons := &store.Canyons{
-		LCC: store.Canyon{
+		{
+			ID:   "LCC",
 			Name: "LCC",
 			ETag: "\"test-lcc-etag\"",
 		},
-		BCC: store.Canyon{Name: "BCC"},
+		{ID: "BCC", Name: "BCC"},
 	}
 
 	testStore := store.NewStore(canyons)
@@ -381,11 +383,10 @@ func FuzzStaticFiles(f *testing.F) {
 	}
 
 	app, err := Start(ServerConfig{
-		Store:         testStore,
-		StaticFS:      staticFS,
-		TemplateFS:    tmplFS,
-		DevMode:       false,
-		SentryEnabled: false,
+		Store:      testStore,
+		StaticFS:   staticFS,
+		TemplateFS: tmplFS,
+		DevMode:    false,
 	})
 	if err != nil {
 		f.Fatal(err)
@@ -11,24 +11,46 @@ import (
 	"testing/fstest"
 	"time"
 
+	"github.com/labstack/echo/v4"
 	"github.com/stefanpenner/lcc-live/web/store"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// testImage returns payload behind a minimal 1x1 JPEG header, so the store
+// accepts it as an image while tests can still tell images apart
+func testImage(payload string) []byte {
+	header := []byte{
+		0xFF, 0xD8, // SOI
+		0xFF, 0xE0, 0x00, 0x10, 'J', 'F', 'I', 'F', 0x00, 0x01, 0x01, 0x00, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, // APP0
+		0xFF, 0xC0, 0x00, 0x0B, 0x08, 0x00, 0x01, 0x00, 0x01, 0x01, 0x01, 0x11, 0x00, // SOF0: 1x1, 1 component
+	}
+	return append(header, payload...)
+}
+
+// mustCanyon returns the store's canyon with the given ID, failing the test
+// if it doesn't exist
+func mustCanyon(tb testing.TB, s *store.Store, id string) *store.Canyon {
+	tb.Helper()
+	canyon, ok := s.Canyon(id)
+	require.True(tb, ok, "canyon %s not found", id)
+	return canyon
+}
+
 func setupTestServer(t *testing.T) *http.Server {
 	// Create a test HTTP server that serves mock images
 	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "image/jpeg")
 		w.Header().Set("ETag", "\"test-etag\"")
 		if r.Method == "GET" {
-			w.Write([]byte("test image"))
+			w.Write(testImage("test image"))
 		}
 	}))
 	t.Cleanup(func() { imageServer.Close() })
 
 	canyons := &store.Canyons{
-		LCC: store.Canyon{
+		{
+			ID:   "LCC",
 			Name: "Little Cottonwood Canyon",
 			ETag: "\"test-lcc-etag\"",
 			Status: store.Camera{
@@ -47,7 +69,8 @@ func setupTestServer(t *testing.T) *http.Server {
 				},
 			},
 		},
-		BCC: store.Canyon{
+		{
+			ID:   "BCC",
 			Name: "Big Cottonwood Canyon",
 			ETag: "\"test-bcc-etag\"",
 			Status: store.Camera{
@@ -97,11 +120,10 @@ func setupTestServer(t *testing.T) *http.Server {
 	}
 
 	app, err := Start(ServerConfig{
-		Store:         testStore,
-		StaticFS:      staticFS,
-		TemplateFS:    tmplFS,
-		DevMode:       false,
-		SentryEnabled: false,
+		Store:      testStore,
+		StaticFS:   staticFS,
+		TemplateFS: tmplFS,
+		DevMode:    false,
 	})
 	require.NoError(t, err)
 
@@ -144,19 +166,20 @@ func TestHealthCheckStates(t *testing.T) {
 					w.Header().Set("Content-Type", "image/jpeg")
 					w.Header().Set("ETag", "\"test-etag\"")
 					if r.Method == "GET" {
-						w.Write([]byte("test image"))
+						w.Write(testImage("test image"))
 					}
 				}))
 				t.Cleanup(imageServer.Close)
 
 				canyons := &store.Canyons{
-					LCC: store.Canyon{
+					{
+						ID:   "LCC",
 						Name: "Little Cottonwood Canyon",
 						Cameras: []store.Camera{
 							{Kind: "webcam", Src: imageServer.URL + "/test.jpg", Alt: "Test Camera", Canyon: "LCC"},
 						},
 					},
-					BCC: store.Canyon{Name: "Big Cottonwood Canyon"},
+					{ID: "BCC", Name: "Big Cottonwood Canyon"},
 				}
 				// Don't fetch images - store should not be ready
 				return store.NewStore(canyons)
@@ -171,19 +194,20 @@ func TestHealthCheckStates(t *testing.T) {
 					w.Header().Set("Content-Type", "image/jpeg")
 					w.Header().Set("ETag", "\"test-etag\"")
 					if r.Method == "GET" {
-						w.Write([]byte("test image"))
+						w.Write(testImage("test image"))
 					}
 				}))
 				t.Cleanup(imageServer.Close)
 
 				canyons := &store.Canyons{
-					LCC: store.Canyon{
+					{
+						ID:   "LCC",
 						Name: "Little Cottonwood Canyon",
 						Cameras: []store.Camera{
 							{Kind: "webcam", Src: imageServer.URL + "/test.jpg", Alt: "Test Camera", Canyon: "LCC"},
 						},
 					},
-					BCC: store.Canyon{Name: "Big Cottonwood Canyon"},
+					{ID: "BCC", Name: "Big Cottonwood Canyon"},
 				}
 				testStore := store.NewStore(canyons)
 				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -198,8 +222,8 @@ func TestHealthCheckStates(t *testing.T) {
 			name: "no cameras configured",
 			setupStore: func() *store.Store {
 				canyons := &store.Canyons{
-					LCC: store.Canyon{Name: "Little Cottonwood Canyon"},
-					BCC: store.Canyon{Name: "Big Cottonwood Canyon"},
+					{ID: "LCC", Name: "Little Cottonwood Canyon"},
+					{ID: "BCC", Name: "Big Cottonwood Canyon"},
 				}
 				testStore := store.NewStore(canyons)
 				testStore.FetchImages(context.Background())
@@ -214,11 +238,10 @@ func TestHealthCheckStates(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			testStore := tt.setupStore()
 			app, err := Start(ServerConfig{
-				Store:         testStore,
-				StaticFS:      staticFS,
-				TemplateFS:    tmplFS,
-				DevMode:       false,
-				SentryEnabled: false,
+				Store:      testStore,
+				StaticFS:   staticFS,
+				TemplateFS: tmplFS,
+				DevMode:    false,
 			})
 			require.NoError(t, err)
 
@@ -316,14 +339,15 @@ func TestImageRoute_GET_Success(t *testing.T) {
 		w.Header().Set("Content-Type", "image/jpeg")
 		w.Header().Set("ETag", "\"mock-etag\"")
 		if r.Method == "GET" {
-			w.Write([]byte("fake image data"))
+			w.Write(testImage("fake image data"))
 		}
 	}))
 	defer imageServer.Close()
 
 	// Create store with camera pointing to test server
 	canyons := &store.Canyons{
-		LCC: store.Canyon{
+		{
+			ID:   "LCC",
 			Name: "Little Cottonwood Canyon",
 			ETag: "\"test-lcc-etag\"",
 			Cameras: []store.Camera{
@@ -335,7 +359,7 @@ func TestImageRoute_GET_Success(t *testing.T) {
 				},
 			},
 		},
-		BCC: store.Canyon{Name: "BCC"},
+		{ID: "BCC", Name: "BCC"},
 	}
 
 	testStore := store.NewStore(canyons)
@@ -352,17 +376,16 @@ func TestImageRoute_GET_Success(t *testing.T) {
 	staticFS := fstest.MapFS{}
 
 	app, err := Start(ServerConfig{
-		Store:         testStore,
-		StaticFS:      staticFS,
-		TemplateFS:    tmplFS,
-		DevMode:       false,
-		SentryEnabled: false,
+		Store:      testStore,
+		StaticFS:   staticFS,
+		TemplateFS: tmplFS,
+		DevMode:    false,
 	})
 	require.NoError(t, err)
 	srv := &http.Server{Handler: app}
 
 	// Get the camera ID
-	cameraID := testStore.Canyon("LCC").Cameras[0].ID
+	cameraID := mustCanyon(t, testStore, "LCC").Cameras[0].ID
 
 	req := httptest.NewRequest("GET", "/image/"+cameraID, nil)
 	rec := httptest.NewRecorder()
@@ -370,7 +393,7 @@ func TestImageRoute_GET_Success(t *testing.T) {
 	srv.Handler.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
-	assert.Equal(t, "fake image data", rec.Body.String())
+	assert.Equal(t, string(testImage("fake image data")), rec.Body.String())
 	assert.Equal(t, "image/jpeg", rec.Header().Get("Content-Type"))
 	assert.NotEmpty(t, rec.Header().Get("ETag"))
 	assert.Equal(t, "public, max-age=3, stale-while-revalidate=120", rec.Header().Get("Cache-Control"))
@@ -382,13 +405,14 @@ func TestImageRoute_HEAD_Success(t *testing.T) {
 		w.Header().Set("Content-Type", "image/jpeg")
 		w.Header().Set("ETag", "\"mock-etag\"")
 		if r.Method == "GET" {
-			w.Write([]byte("fake image data"))
+			w.Write(testImage("fake image data"))
 		}
 	}))
 	defer imageServer.Close()
 
 	canyons := &store.Canyons{
-		LCC: store.Canyon{
+		{
+			ID:   "LCC",
 			Name: "Little Cottonwood Canyon",
 			ETag: "\"test-lcc-etag\"",
 			Cameras: []store.Camera{
@@ -400,7 +424,7 @@ func TestImageRoute_HEAD_Success(t *testing.T) {
 				},
 			},
 		},
-		BCC: store.Canyon{Name: "BCC"},
+		{ID: "BCC", Name: "BCC"},
 	}
 
 	testStore := store.NewStore(canyons)
@@ -414,16 +438,15 @@ func TestImageRoute_HEAD_Success(t *testing.T) {
 	staticFS := fstest.MapFS{}
 
 	app, err := Start(ServerConfig{
-		Store:         testStore,
-		StaticFS:      staticFS,
-		TemplateFS:    tmplFS,
-		DevMode:       false,
-		SentryEnabled: false,
+		Store:      testStore,
+		StaticFS:   staticFS,
+		TemplateFS: tmplFS,
+		DevMode:    false,
 	})
 	require.NoError(t, err)
 	srv := &http.Server{Handler: app}
 
-	cameraID := testStore.Canyon("LCC").Cameras[0].ID
+	cameraID := mustCanyon(t, testStore, "LCC").Cameras[0].ID
 
 	req := httptest.NewRequest("HEAD", "/image/"+cameraID, nil)
 	rec := httptest.NewRecorder()
@@ -442,13 +465,14 @@ func TestImageRoute_NotModified(t *testing.T) {
 		w.Header().Set("Content-Type", "image/jpeg")
 		w.Header().Set("ETag", "\"mock-etag\"")
 		if r.Method == "GET" {
-			w.Write([]byte("fake image data"))
+			w.Write(testImage("fake image data"))
 		}
 	}))
 	defer imageServer.Close()
 
 	canyons := &store.Canyons{
-		LCC: store.Canyon{
+		{
+			ID:   "LCC",
 			Name: "Little Cottonwood Canyon",
 			ETag: "\"test-lcc-etag\"",
 			Cameras: []store.Camera{
@@ -460,7 +484,7 @@ func TestImageRoute_NotModified(t *testing.T) {
 				},
 			},
 		},
-		BCC: store.Canyon{Name: "BCC"},
+		{ID: "BCC", Name: "BCC"},
 	}
 
 	testStore := store.NewStore(canyons)
@@ -474,16 +498,15 @@ func TestImageRoute_NotModified(t *testing.T) {
 	staticFS := fstest.MapFS{}
 
 	app, err := Start(ServerConfig{
-		Store:         testStore,
-		StaticFS:      staticFS,
-		TemplateFS:    tmplFS,
-		DevMode:       false,
-		SentryEnabled: false,
+		Store:      testStore,
+		StaticFS:   staticFS,
+		TemplateFS: tmplFS,
+		DevMode:    false,
 	})
 	require.NoError(t, err)
 	srv := &http.Server{Handler: app}
 
-	cameraID := testStore.Canyon("LCC").Cameras[0].ID
+	cameraID := mustCanyon(t, testStore, "LCC").Cameras[0].ID
 
 	// Get the ETag from the first request
 	snapshot, _ := testStore.Get(cameraID)
@@ -521,13 +544,14 @@ func TestImageRoute_ETagCaching(t *testing.T) {
 		w.Header().Set("Content-Type", "image/jpeg")
 		w.Header().Set("ETag", "\"stable-etag\"")
 		if r.Method == "GET" {
-			w.Write([]byte("test image content"))
+			w.Write(testImage("test image content"))
 		}
 	}))
 	defer imageServer.Close()
 
 	canyons := &store.Canyons{
-		LCC: store.Canyon{
+		{
+			ID:   "LCC",
 			Name: "LCC",
 			ETag: "\"test-lcc-etag\"",
 			Cameras: []store.Camera{
@@ -539,7 +563,7 @@ func TestImageRoute_ETagCaching(t *testing.T) {
 				},
 			},
 		},
-		BCC: store.Canyon{Name: "BCC"},
+		{ID: "BCC", Name: "BCC"},
 	}
 
 	testStore := store.NewStore(canyons)
@@ -553,16 +577,15 @@ func TestImageRoute_ETagCaching(t *testing.T) {
 	staticFS := fstest.MapFS{}
 
 	app, err := Start(ServerConfig{
-		Store:         testStore,
-		StaticFS:      staticFS,
-		TemplateFS:    tmplFS,
-		DevMode:       false,
-		SentryEnabled: false,
+		Store:      testStore,
+		StaticFS:   staticFS,
+		TemplateFS: tmplFS,
+		DevMode:    false,
 	})
 	require.NoError(t, err)
 	srv := &http.Server{Handler: app}
 
-	cameraID := testStore.Canyon("LCC").Cameras[0].ID
+	cameraID := mustCanyon(t, testStore, "LCC").Cameras[0].ID
 
 	// First request - should return full image
 	req1 := httptest.NewRequest("GET", "/image/"+cameraID, nil)
@@ -588,13 +611,14 @@ func TestImageRoute_CacheHeaders(t *testing.T) {
 		w.Header().Set("Content-Type", "image/jpeg")
 		w.Header().Set("ETag", "\"test-etag\"")
 		if r.Method == "GET" {
-			w.Write([]byte("image data"))
+			w.Write(testImage("image data"))
 		}
 	}))
 	defer imageServer.Close()
 
 	canyons := &store.Canyons{
-		LCC: store.Canyon{
+		{
+			ID:   "LCC",
 			Name: "LCC",
 			ETag: "\"test-lcc-etag\"",
 			Cameras: []store.Camera{
@@ -606,7 +630,7 @@ func TestImageRoute_CacheHeaders(t *testing.T) {
 				},
 			},
 		},
-		BCC: store.Canyon{Name: "BCC"},
+		{ID: "BCC", Name: "BCC"},
 	}
 
 	testStore := store.NewStore(canyons)
@@ -620,16 +644,15 @@ func TestImageRoute_CacheHeaders(t *testing.T) {
 	staticFS := fstest.MapFS{}
 
 	app, err := Start(ServerConfig{
-		Store:         testStore,
-		StaticFS:      staticFS,
-		TemplateFS:    tmplFS,
-		DevMode:       false,
-		SentryEnabled: false,
+		Store:      testStore,
+		StaticFS:   staticFS,
+		TemplateFS: tmplFS,
+		DevMode:    false,
 	})
 	require.NoError(t, err)
 	srv := &http.Server{Handler: app}
 
-	cameraID := testStore.Canyon("LCC").Cameras[0].ID
+	cameraID := mustCanyon(t, testStore, "LCC").Cameras[0].ID
 
 	req := httptest.NewRequest("GET", "/image/"+cameraID, nil)
 	rec := httptest.NewRecorder()
@@ -647,13 +670,14 @@ func TestImageRoute_LastModifiedHeader(t *testing.T) {
 		w.Header().Set("Content-Type", "image/jpeg")
 		w.Header().Set("ETag", "\"test-etag\"")
 		if r.Method == "GET" {
-			w.Write([]byte("image data"))
+			w.Write(testImage("image data"))
 		}
 	}))
 	defer imageServer.Close()
 
 	canyons := &store.Canyons{
-		LCC: store.Canyon{
+		{
+			ID:   "LCC",
 			Name: "LCC",
 			ETag: "\"test-lcc-etag\"",
 			Cameras: []store.Camera{
@@ -665,7 +689,7 @@ func TestImageRoute_LastModifiedHeader(t *testing.T) {
 				},
 			},
 		},
-		BCC: store.Canyon{Name: "BCC"},
+		{ID: "BCC", Name: "BCC"},
 	}
 
 	testStore := store.NewStore(canyons)
@@ -679,16 +703,15 @@ func TestImageRoute_LastModifiedHeader(t *testing.T) {
 	staticFS := fstest.MapFS{}
 
 	app, err := Start(ServerConfig{
-		Store:         testStore,
-		StaticFS:      staticFS,
-		TemplateFS:    tmplFS,
-		DevMode:       false,
-		SentryEnabled: false,
+		Store:      testStore,
+		StaticFS:   staticFS,
+		TemplateFS: tmplFS,
+		DevMode:    false,
 	})
 	require.NoError(t, err)
 	srv := &http.Server{Handler: app}
 
-	cameraID := testStore.Canyon("LCC").Cameras[0].ID
+	cameraID := mustCanyon(t, testStore, "LCC").Cameras[0].ID
 
 	req := httptest.NewRequest("GET", "/image/"+cameraID, nil)
 	rec := httptest.NewRecorder()
@@ -768,13 +791,14 @@ func TestImageRoute_WrongETag(t *testing.T) {
 		w.Header().Set("Content-Type", "image/jpeg")
 		w.Header().Set("ETag", "\"correct-etag\"")
 		if r.Method == "GET" {
-			w.Write([]byte("image data"))
+			w.Write(testImage("image data"))
 		}
 	}))
 	defer imageServer.Close()
 
 	canyons := &store.Canyons{
-		LCC: store.Canyon{
+		{
+			ID:   "LCC",
 			Name: "LCC",
 			ETag: "\"test-lcc-etag\"",
 			Cameras: []store.Camera{
@@ -786,7 +810,7 @@ func TestImageRoute_WrongETag(t *testing.T) {
 				},
 			},
 		},
-		BCC: store.Canyon{Name: "BCC"},
+		{ID: "BCC", Name: "BCC"},
 	}
 
 	testStore := store.NewStore(canyons)
@@ -800,16 +824,15 @@ func TestImageRoute_WrongETag(t *testing.T) {
 	staticFS := fstest.MapFS{}
 
 	app, err := Start(ServerConfig{
-		Store:         testStore,
-		StaticFS:      staticFS,
-		TemplateFS:    tmplFS,
-		DevMode:       false,
-		SentryEnabled: false,
+		Store:      testStore,
+		StaticFS:   staticFS,
+		TemplateFS: tmplFS,
+		DevMode:    false,
 	})
 	require.NoError(t, err)
 	srv := &http.Server{Handler: app}
 
-	cameraID := testStore.Canyon("LCC").Cameras[0].ID
+	cameraID := mustCanyon(t, testStore, "LCC").Cameras[0].ID
 
 	// Request with wrong ETag should return full content
 	req := httptest.NewRequest("GET", "/image/"+cameraID, nil)
@@ -1036,19 +1059,20 @@ func TestCameraRoute(t *testing.T) {
 		w.Header().Set("Content-Type", "image/jpeg")
 		w.Header().Set("ETag", "\"test-etag\"")
 		if r.Method == "GET" {
-			w.Write([]byte("test image data"))
+			w.Write(testImage("test image data"))
 		}
 	}))
 	t.Cleanup(imageServer.Close)
 
 	canyons := &store.Canyons{
-		LCC: store.Canyon{
+		{
+			ID:   "LCC",
 			Name: "Little Cottonwood Canyon",
 			Cameras: []store.Camera{
 				{Kind: "webcam", Src: imageServer.URL + "/test.jpg", Alt: "Test Camera", Canyon: "LCC"},
 			},
 		},
-		BCC: store.Canyon{Name: "Big Cottonwood Canyon"},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
 	}
 
 	testStore := store.NewStore(canyons)
@@ -1065,15 +1089,14 @@ func TestCameraRoute(t *testing.T) {
 	staticFS := fstest.MapFS{}
 
 	app, err := Start(ServerConfig{
-		Store:         testStore,
-		StaticFS:      staticFS,
-		TemplateFS:    tmplFS,
-		DevMode:       false,
-		SentryEnabled: false,
+		Store:      testStore,
+		StaticFS:   staticFS,
+		TemplateFS: tmplFS,
+		DevMode:    false,
 	})
 	require.NoError(t, err)
 
-	camera := testStore.Canyon("LCC").Cameras[0]
+	camera := mustCanyon(t, testStore, "LCC").Cameras[0]
 	cameraID := camera.ID
 	// Use slug instead of ID since camera has Alt field (will redirect otherwise)
 	cameraSlug := slugify(camera.Alt)
@@ -1163,3 +1186,45 @@ func TestCameraRoute(t *testing.T) {
 		assert.Empty(t, rec2.Body.String())
 	})
 }
+
+func TestStart_RequestTimeout(t *testing.T) {
+	// Each subtest gets its own app so no request shares pooled contexts
+	newApp := func(t *testing.T) *echo.Echo {
+		app, err := Start(ServerConfig{
+			Store:          store.NewStore(&store.Canyons{{ID: "LCC", Name: "Little Cottonwood Canyon"}}),
+			StaticFS:       fstest.MapFS{},
+			TemplateFS:     fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+			RequestTimeout: 50 * time.Millisecond,
+		})
+		require.NoError(t, err)
+
+		// An artificially slow handler that only gives up once cancelled
+		app.GET("/slow", func(c echo.Context) error {
+			select {
+			case <-c.Request().Context().Done():
+				return c.Request().Context().Err()
+			case <-time.After(5 * time.Second):
+				return c.String(http.StatusOK, "finally")
+			}
+		})
+		return app
+	}
+
+	t.Run("slow handler times out", func(t *testing.T) {
+		app := newApp(t)
+		start := time.Now()
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Contains(t, rec.Body.String(), "request timed out")
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("fast handler is unaffected", func(t *testing.T) {
+		app := newApp(t)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/robots.txt", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// serverTimingKey holds the request's *serverTiming when timing is enabled
+const serverTimingKey = "_server_timing"
+
+// serverTiming collects the Server-Timing metrics for one response
+type serverTiming struct {
+	start   time.Time
+	metrics []string
+}
+
+// ServerTimingMiddleware adds a Server-Timing header to every response, so
+// latency shows up in browser devtools and synthetic monitors. Routes add
+// their own phases with timePhase and markTiming; total is always included.
+func ServerTimingMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			timing := &serverTiming{start: time.Now()}
+			c.Set(serverTimingKey, timing)
+			c.Response().Before(func() {
+				metrics := append(timing.metrics, formatTiming("total", time.Since(timing.start)))
+				c.Response().Header().Set("Server-Timing", strings.Join(metrics, ", "))
+			})
+			return next(c)
+		}
+	}
+}
+
+// formatTiming formats a Server-Timing metric with its duration in ms
+func formatTiming(name string, d time.Duration) string {
+	return name + ";dur=" + strconv.FormatFloat(float64(d.Microseconds())/1000, 'f', -1, 64)
+}
+
+// timePhase records a Server-Timing metric for the phase that started at
+// start. It's a no-op unless timing is enabled.
+func timePhase(c echo.Context, name string, start time.Time) {
+	if timing, ok := c.Get(serverTimingKey).(*serverTiming); ok {
+		timing.metrics = append(timing.metrics, formatTiming(name, time.Since(start)))
+	}
+}
+
+// markTiming records a duration-less Server-Timing metric, e.g. whether an
+// image was a cache hit. It's a no-op unless timing is enabled.
+func markTiming(c echo.Context, name, description string) {
+	if timing, ok := c.Get(serverTimingKey).(*serverTiming); ok {
+		timing.metrics = append(timing.metrics, name+";desc="+description)
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseServerTiming parses a Server-Timing header into metric name ->
+// parameters (e.g. "dur", "desc"), failing on malformed entries
+func parseServerTiming(t *testing.T, header string) map[string]map[string]string {
+	t.Helper()
+	metrics := map[string]map[string]string{}
+	for _, metric := range strings.Split(header, ",") {
+		parts := strings.Split(strings.TrimSpace(metric), ";")
+		params := map[string]string{}
+		for _, param := range parts[1:] {
+			key, value, ok := strings.Cut(param, "=")
+			require.True(t, ok, "malformed parameter %q", param)
+			params[key] = value
+		}
+		if dur, ok := params["dur"]; ok {
+			_, err := strconv.ParseFloat(dur, 64)
+			require.NoError(t, err, "dur of %s", parts[0])
+		}
+		metrics[parts[0]] = params
+	}
+	return metrics
+}
+
+func TestServerTiming(t *testing.T) {
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("timed"))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	testStore := store.NewStore(&store.Canyons{
+		{ID: "LCC", Name: "LCC", Cameras: []store.Camera{
+			{Kind: "img", Src: imageServer.URL + "/cam.jpg", Alt: "Timed Camera"},
+		}},
+		{ID: "BCC", Name: "BCC"},
+	})
+	testStore.FetchImages(context.Background())
+
+	newApp := func(t *testing.T, serverTiming bool) http.Handler {
+		t.Helper()
+		app, err := Start(ServerConfig{
+			Store:        testStore,
+			StaticFS:     fstest.MapFS{},
+			TemplateFS:   fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+			ServerTiming: serverTiming,
+		})
+		require.NoError(t, err)
+		return app
+	}
+	app := newApp(t, true)
+
+	t.Run("canyon page", func(t *testing.T) {
+		for _, path := range []string{"/", "/.json"} {
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			metrics := parseServerTiming(t, rec.Header().Get("Server-Timing"))
+			for _, name := range []string{"store", "render", "total"} {
+				assert.Contains(t, metrics, name, path)
+				assert.Contains(t, metrics[name], "dur", path)
+			}
+		}
+	})
+
+	t.Run("image cache hit", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/image/timed-camera", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "miss", parseServerTiming(t, rec.Header().Get("Server-Timing"))["cache"]["desc"])
+
+		req := httptest.NewRequest(http.MethodGet, "/image/timed-camera", nil)
+		req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+		rec = httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusNotModified, rec.Code)
+		metrics := parseServerTiming(t, rec.Header().Get("Server-Timing"))
+		assert.Equal(t, "hit", metrics["cache"]["desc"])
+		assert.Contains(t, metrics, "store")
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newApp(t, false).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("Server-Timing"))
+	})
+}
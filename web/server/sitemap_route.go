@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/xml"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stefanpenner/lcc-live/web/store"
+)
+
+// sitemapCacheControl keeps crawlers from hammering the generated files while
+// still picking up camera changes within the hour
+const sitemapCacheControl = "public, max-age=3600"
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+// baseURL returns the scheme and host the request was made against
+func baseURL(c echo.Context) string {
+	return c.Scheme() + "://" + c.Request().Host
+}
+
+// SitemapRoute serves /sitemap.xml, generated from the store's cameras so it
+// always reflects the current configuration
+func SitemapRoute(s *store.Store) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		base := baseURL(c)
+		set := sitemapURLSet{
+			Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9",
+		}
+
+		for _, canyon := range s.Canyons() {
+			set.URLs = append(set.URLs, sitemapURL{Loc: base + canyonPagePath(s, canyon.ID)})
+		}
+
+		for _, entry := range s.Entries() {
+			if !entry.Camera.IsEnabled() {
+				continue
+			}
+			// Use the canonical URL CameraRoute redirects ID-based URLs to
+			path := entry.Camera.GetSlug()
+			if path == "" {
+				path = entry.Camera.ID
+			}
+			url := sitemapURL{Loc: base + "/camera/" + path}
+			if !entry.FetchedAt.IsZero() {
+				url.LastMod = entry.FetchedAt.UTC().Format("2006-01-02")
+			}
+			set.URLs = append(set.URLs, url)
+		}
+
+		body, err := xml.MarshalIndent(set, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		c.Response().Header().Set("Cache-Control", sitemapCacheControl)
+		return c.Blob(http.StatusOK, echo.MIMEApplicationXMLCharsetUTF8, append([]byte(xml.Header), body...))
+	}
+}
+
+// RobotsRoute serves /robots.txt, allowing everything but internal routes
+// and pointing crawlers at the sitemap
+func RobotsRoute() func(c echo.Context) error {
+	return func(c echo.Context) error {
+		body := "User-agent: *\n" +
+			"Disallow: /_/\n" +
+			"Disallow: /image/\n" +
+			"\n" +
+			"Sitemap: " + baseURL(c) + "/sitemap.xml\n"
+
+		c.Response().Header().Set("Cache-Control", sitemapCacheControl)
+		return c.String(http.StatusOK, body)
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSitemapAndRobots(t *testing.T) {
+	disabled := false
+	canyons := &store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "Little Cottonwood Canyon",
+			Cameras: []store.Camera{
+				{Kind: "img", Src: "http://cam1/a.jpg", Alt: "Snowbird Entry"},
+				{Kind: "img", Src: "http://cam2/b.jpg", Alt: "Alta Base", Slug: "alta"},
+				{Kind: "img", Src: "http://cam3/c.jpg", Alt: "Retired Cam", Enabled: &disabled},
+			},
+		},
+		{
+			ID:   "BCC",
+			Name: "Big Cottonwood Canyon",
+			Cameras: []store.Camera{
+				{Kind: "iframe", Src: "http://cam4/d.html", Alt: "Brighton Lot"},
+			},
+		},
+	}
+	testStore := store.NewStore(canyons)
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+
+	t.Run("sitemap.xml", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil)
+		req.Host = "lcc.live"
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Header().Get("Content-Type"), "application/xml")
+		assert.NotEmpty(t, rec.Header().Get("Cache-Control"))
+
+		var set sitemapURLSet
+		require.NoError(t, xml.Unmarshal(rec.Body.Bytes(), &set), "sitemap must be valid XML")
+
+		locs := make([]string, 0, len(set.URLs))
+		for _, u := range set.URLs {
+			locs = append(locs, u.Loc)
+		}
+		assert.Equal(t, []string{
+			"http://lcc.live/",
+			"http://lcc.live/bcc",
+			"http://lcc.live/camera/snowbird-entry",
+			"http://lcc.live/camera/alta",
+			"http://lcc.live/camera/brighton-lot",
+		}, locs)
+	})
+
+	t.Run("robots.txt", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/robots.txt", nil)
+		req.Host = "lcc.live"
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "User-agent: *")
+		assert.Contains(t, rec.Body.String(), "Sitemap: http://lcc.live/sitemap.xml")
+	})
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// staticPrefix is where StaticRoute serves staticFS
+const staticPrefix = "/s/"
+
+// staticNotFoundCacheControl lets caches absorb repeated requests for a
+// missing asset briefly, without hiding it for long once it's deployed
+const staticNotFoundCacheControl = "public, max-age=60"
+
+// staticNotFoundETag is the ETag of every static 404, which has a fixed body
+const staticNotFoundETag = `"static-not-found"`
+
+// precompressedEncodings are the sibling files StaticRoute looks for next to
+// an asset (app.css.br, app.css.gz), in order of preference
+var precompressedEncodings = []struct{ encoding, ext string }{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows encoding
+func acceptsEncoding(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// staticAsset returns the staticFS name of the file a request is for, if
+// it names one: traversal (..), absolute and directory paths never do
+func staticAsset(staticFS fs.FS, urlPath string) (string, bool) {
+	asset, ok := strings.CutPrefix(urlPath, staticPrefix)
+	if !ok || !fs.ValidPath(asset) || strings.Contains(asset, "\\") {
+		return "", false
+	}
+	info, err := fs.Stat(staticFS, asset)
+	if err != nil || info.IsDir() {
+		return "", false
+	}
+	return asset, true
+}
+
+// staticNotFound answers a request for a missing static asset with a
+// small, briefly cacheable 404
+func staticNotFound(c echo.Context, devMode bool) error {
+	header := c.Response().Header()
+	if devMode {
+		header.Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	} else {
+		header.Set("Cache-Control", staticNotFoundCacheControl)
+	}
+	header.Set("ETag", staticNotFoundETag)
+	if notModified(c, staticNotFoundETag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.String(http.StatusNotFound, "404 page not found")
+}
+
+// staticLookupKey is the context key lookupStatic caches its result under
+const staticLookupKey = "static_lookup"
+
+// staticLookup is the static file a request resolved to
+type staticLookup struct {
+	asset string // the staticFS name, or empty if it isn't a file there
+	// encoding and name identify the precompressed variant to serve, if any
+	encoding string
+	name     string
+}
+
+// lookupStatic resolves the static asset a request is for (see staticAsset
+// and precompressedStatic). The Gzip skipper and StaticRoute both need it,
+// so it's cached on the context and the files are only stat-ed once.
+func lookupStatic(c echo.Context, staticFS fs.FS) staticLookup {
+	if lookup, ok := c.Get(staticLookupKey).(staticLookup); ok {
+		return lookup
+	}
+	var lookup staticLookup
+	if asset, ok := staticAsset(staticFS, c.Request().URL.Path); ok {
+		lookup.asset = asset
+		lookup.encoding, lookup.name = precompressedStatic(staticFS, c.Request())
+	}
+	c.Set(staticLookupKey, lookup)
+	return lookup
+}
+
+// precompressedStatic returns the encoding and staticFS name of a
+// precompressed variant of the requested static asset that the client
+// accepts, or empty strings if there isn't one
+func precompressedStatic(staticFS fs.FS, r *http.Request) (encoding, name string) {
+	asset, ok := strings.CutPrefix(path.Clean(r.URL.Path), staticPrefix)
+	if !ok || !fs.ValidPath(asset) {
+		return "", ""
+	}
+	accept := r.Header.Get("Accept-Encoding")
+	for _, variant := range precompressedEncodings {
+		if !acceptsEncoding(accept, variant.encoding) {
+			continue
+		}
+		if _, err := fs.Stat(staticFS, asset+variant.ext); err != nil {
+			continue
+		}
+		// Only stand in for an asset that exists uncompressed too, so every
+		// client sees the same set of files
+		if _, err := fs.Stat(staticFS, asset); err != nil {
+			return "", ""
+		}
+		return variant.encoding, asset + variant.ext
+	}
+	return "", ""
+}
+
+// StaticRoute serves staticFS under /s/. An asset with a precompressed
+// sibling the client accepts (e.g. app.css.gz) is served from it as-is;
+// others are served plain and compressed on the fly by the Gzip middleware,
+// which skips the precompressed ones. Anything that isn't a file in
+// staticFS, including directories and traversal attempts, is a 404.
+func StaticRoute(staticFS fs.FS, devMode bool) func(c echo.Context) error {
+	files := http.StripPrefix("/s", http.FileServer(http.FS(staticFS)))
+	return func(c echo.Context) error {
+		lookup := lookupStatic(c, staticFS)
+		if lookup.asset == "" {
+			return staticNotFound(c, devMode)
+		}
+		setStaticCacheHeaders(c, devMode)
+		encoding, name := lookup.encoding, lookup.name
+		if encoding == "" {
+			files.ServeHTTP(c.Response(), c.Request())
+			return nil
+		}
+
+		header := c.Response().Header()
+		header.Add("Vary", "Accept-Encoding")
+		header.Set("Content-Encoding", encoding)
+		// Typed by the asset, not the .gz/.br file
+		contentType := mime.TypeByExtension(path.Ext(strings.TrimSuffix(name, path.Ext(name))))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		header.Set("Content-Type", contentType)
+		http.ServeFileFS(c.Response(), c.Request(), staticFS, name)
+		return nil
+	}
+}
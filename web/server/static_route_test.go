@@ -0,0 +1,183 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticRoute_Precompressed(t *testing.T) {
+	css := []byte(strings.Repeat("body { color: #333; }\n", 200))
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, err := zw.Write(css)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	js := []byte(strings.Repeat("console.log('on the fly');\n", 200))
+
+	app, err := Start(ServerConfig{
+		Store: store.NewStore(&store.Canyons{{ID: "LCC", Name: "LCC"}, {ID: "BCC", Name: "BCC"}}),
+		StaticFS: fstest.MapFS{
+			"style.css":    &fstest.MapFile{Data: css},
+			"style.css.gz": &fstest.MapFile{Data: gz.Bytes()},
+			"app.js":       &fstest.MapFile{Data: js},
+		},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+	get := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("serves the .gz sibling as-is", func(t *testing.T) {
+		rec := get("/s/style.css", "br;q=0, gzip, deflate")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+		assert.Contains(t, rec.Header().Values("Vary"), "Accept-Encoding")
+		assert.Equal(t, "text/css; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Equal(t, staticCacheControl, rec.Header().Get("Cache-Control"))
+		assert.Equal(t, gz.Bytes(), rec.Body.Bytes(), "the precompressed bytes, not compressed again")
+	})
+
+	t.Run("plain for clients without gzip", func(t *testing.T) {
+		rec := get("/s/style.css", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("Content-Encoding"))
+		assert.Equal(t, css, rec.Body.Bytes())
+	})
+
+	t.Run("falls back to on-the-fly gzip", func(t *testing.T) {
+		rec := get("/s/app.js", "gzip")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+		zr, err := gzip.NewReader(rec.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		assert.Equal(t, js, body)
+	})
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	assert.True(t, acceptsEncoding("gzip, deflate, br", "br"))
+	assert.True(t, acceptsEncoding("GZIP;q=0.5", "gzip"))
+	assert.False(t, acceptsEncoding("gzip;q=0", "gzip"))
+	assert.False(t, acceptsEncoding("deflate", "gzip"))
+	assert.False(t, acceptsEncoding("", "gzip"))
+}
+
+func TestStaticRoute_NotFound(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "secret.txt"), []byte("top secret"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "static", "fonts"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "static", "style.css"), []byte("body{}"), 0o644))
+
+	app, err := Start(ServerConfig{
+		Store:      store.NewStore(&store.Canyons{{ID: "LCC", Name: "LCC"}, {ID: "BCC", Name: "BCC"}}),
+		StaticFS:   os.DirFS(filepath.Join(root, "static")),
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.URL.RawPath = ""
+		req.URL.Path = target
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("missing asset", func(t *testing.T) {
+		rec := get("/s/missing.css")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, staticNotFoundCacheControl, rec.Header().Get("Cache-Control"), "not cached like a real asset")
+		assert.Equal(t, staticNotFoundETag, rec.Header().Get("ETag"))
+		assert.Empty(t, rec.Header().Get("Content-Encoding"), "misses skip gzip")
+		assert.Equal(t, "404 page not found", rec.Body.String())
+	})
+
+	for _, target := range []string{
+		"/s/../secret.txt",
+		"/s/fonts/../../secret.txt",
+		"/s//secret.txt",
+		"/s/..\\secret.txt",
+		"/s/",
+		"/s/fonts",
+		"/s/fonts/",
+	} {
+		t.Run(target, func(t *testing.T) {
+			rec := get(target)
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+			assert.NotContains(t, rec.Body.String(), "top secret")
+			assert.NotContains(t, rec.Body.String(), "style.css", "no directory listings")
+		})
+	}
+
+	t.Run("revalidated miss", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/s/missing.css", nil)
+		req.Header.Set("If-None-Match", staticNotFoundETag)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+		assert.Empty(t, rec.Body.String())
+	})
+
+	t.Run("assets are still served", func(t *testing.T) {
+		rec := get("/s/style.css")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, staticCacheControl, rec.Header().Get("Cache-Control"))
+	})
+}
+
+// statCountingFS counts Stat calls on the files it serves
+type statCountingFS struct {
+	fstest.MapFS
+	stats atomic.Int32
+}
+
+func (f *statCountingFS) Stat(name string) (fs.FileInfo, error) {
+	f.stats.Add(1)
+	return f.MapFS.Stat(name)
+}
+
+func TestStaticRoute_ResolvesAssetsOnce(t *testing.T) {
+	staticFS := &statCountingFS{MapFS: fstest.MapFS{
+		"app.js": &fstest.MapFile{Data: []byte(strings.Repeat("console.log('once');\n", 200))},
+	}}
+	app, err := Start(ServerConfig{
+		Store:      store.NewStore(&store.Canyons{{ID: "LCC", Name: "LCC"}, {ID: "BCC", Name: "BCC"}}),
+		StaticFS:   staticFS,
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+	stats := func(target string) int32 {
+		staticFS.stats.Store(0)
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		app.ServeHTTP(httptest.NewRecorder(), req)
+		return staticFS.stats.Load()
+	}
+
+	assert.EqualValues(t, 1, stats("/s/missing.js"), "the Gzip skipper and the route share one lookup")
+	assert.EqualValues(t, 2, stats("/s/app.js"), "the asset, then its .gz sibling")
+}
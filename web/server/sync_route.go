@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stefanpenner/lcc-live/web/store"
+)
+
+// syncPath is exempt from the request timeout, since a full cycle can take
+// longer than any page render
+const syncPath = "/_/sync"
+
+// SyncRoute fetches every camera now (POST /_/sync), so recovered cameras
+// show up without waiting for the ticker or a restart, and responds with the
+// cycle's SyncSummary. It answers 409 if a sync is already running. The cycle
+// runs under ctx, the server's lifetime, so it finishes even if the client
+// gives up waiting but still stops on shutdown.
+func SyncRoute(ctx context.Context, s *store.Store) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		result, err := s.Sync(ctx)
+		if errors.Is(err, store.ErrSyncInProgress) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, SyncSummary{
+			At:         time.Now(),
+			DurationMs: result.Duration.Milliseconds(),
+			Changed:    result.Changed,
+			Unchanged:  result.Unchanged,
+			Errors:     result.Errors,
+		})
+	}
+}
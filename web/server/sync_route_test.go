@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncRoute(t *testing.T) {
+	var fetches atomic.Int32
+	var blocked atomic.Bool
+	var waiting atomic.Int32
+	release := make(chan struct{})
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if blocked.Load() {
+			waiting.Add(1)
+			<-release
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			fetches.Add(1)
+			w.Write(testImage("synced"))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	testStore := store.NewStore(&store.Canyons{
+		{ID: "LCC", Name: "LCC", Cameras: []store.Camera{
+			{Kind: "img", Src: imageServer.URL + "/cam.jpg", Alt: "Synced Camera"},
+		}},
+		{ID: "BCC", Name: "BCC"},
+	})
+	testStore.FetchImages(context.Background())
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+		AdminToken: "admin-secret",
+	})
+	require.NoError(t, err)
+	post := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/_/sync", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("requires the admin token", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, post("").Code)
+	})
+
+	t.Run("fetches now and returns the summary", func(t *testing.T) {
+		before := fetches.Load()
+		rec := post("admin-secret")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Header().Get("Cache-Control"), "no-store")
+		assert.Equal(t, before+1, fetches.Load())
+
+		var summary SyncSummary
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &summary))
+		assert.Equal(t, 1, summary.Changed+summary.Unchanged)
+		assert.Zero(t, summary.Errors)
+		assert.False(t, summary.At.IsZero())
+	})
+
+	t.Run("conflicts with a running sync", func(t *testing.T) {
+		blocked.Store(true)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			testStore.FetchImages(context.Background())
+		}()
+		require.Eventually(t, func() bool { return waiting.Load() > 0 }, 5*time.Second, 10*time.Millisecond)
+		assert.Equal(t, http.StatusConflict, post("admin-secret").Code)
+
+		blocked.Store(false)
+		close(release)
+		<-done
+	})
+}
+
+func TestSyncRoute_Concurrent(t *testing.T) {
+	var waiting atomic.Int32
+	release := make(chan struct{})
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		waiting.Add(1)
+		<-release
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("synced"))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	app, err := Start(ServerConfig{
+		Store: store.NewStore(&store.Canyons{
+			{ID: "LCC", Name: "LCC", Cameras: []store.Camera{
+				{Kind: "img", Src: imageServer.URL + "/cam.jpg", Alt: "Synced Camera"},
+			}},
+			{ID: "BCC", Name: "BCC"},
+		}),
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+		AdminToken: "admin-secret",
+		// Shorter than the sync, which must not be cut off by it
+		RequestTimeout: 20 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	codes := make(chan int, 2)
+	for range 2 {
+		go func() {
+			req := httptest.NewRequest(http.MethodPost, "/_/sync", nil)
+			req.Header.Set("Authorization", "Bearer admin-secret")
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+			codes <- rec.Code
+		}()
+	}
+
+	// The loser answers straight away while the winner waits on the origin
+	assert.Equal(t, http.StatusConflict, <-codes)
+	require.Eventually(t, func() bool { return waiting.Load() > 0 }, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	assert.Equal(t, http.StatusOK, <-codes)
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	temp := func(s string) *string { return &s }
+	render := func(t *testing.T, text string, data any) string {
+		tmpl, err := template.New("").Funcs(templateFuncs).Parse(text)
+		require.NoError(t, err)
+		var out strings.Builder
+		require.NoError(t, tmpl.Execute(&out, data))
+		return out.String()
+	}
+
+	for _, tc := range []struct {
+		name string
+		text string
+		data any
+		want string
+	}{
+		{"slugify", `{{slugify .}}`, "Mt. Baldy Cam", "mt-baldy-cam"},
+		{"formatTimeAgo", `{{formatTimeAgo .}}`, time.Now().Add(-2 * time.Hour).Unix(), "2h"},
+		{"timeAgo", `{{timeAgo .}}`, time.Now().Add(-5 * time.Minute), "5m"},
+		{"timeAgo unknown", `{{timeAgo .}}`, time.Time{}, "unknown"},
+		{"isStale", `{{isStale .}}`, time.Now().Unix(), "false"},
+		{"roundTemp", `{{roundTemp .}}`, temp("31.6"), "32"},
+		{"formatTemp", `{{formatTemp .}}`, temp("-3.2"), "-3°F"},
+		{"formatTemp missing", `{{formatTemp .}}`, (*string)(nil), ""},
+		{"imageURL", `<img src="{{imageURL .}}">`, "lcc-camera-1", `<img src="/image/lcc-camera-1">`},
+		{"imageURL with width", `<img src="{{imageURL . 640}}">`, "lcc-camera-1", `<img src="/image/lcc-camera-1?w=640">`},
+		{"json", `<script>const data = {{json .}};</script>`, map[string]any{"name": "</script>", "n": 1}, `<script>const data = {"n":1,"name":"\u003c/script\u003e"};</script>`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, render(t, tc.text, tc.data))
+		})
+	}
+
+	t.Run("formatUnixTime", func(t *testing.T) {
+		ts := time.Date(2024, 1, 15, 9, 5, 0, 0, time.Local).Unix()
+		assert.True(t, strings.HasPrefix(render(t, `{{formatUnixTime .}}`, ts), "Jan 15, 2024 9:05 AM"))
+	})
+
+	t.Run("precipIcon", func(t *testing.T) {
+		assert.Contains(t, render(t, `{{precipIcon .}}`, temp("20")), `<svg class="precip-icon"`)
+	})
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stefanpenner/lcc-live/web/store"
+)
+
+// TimelapseFrame describes a single retained frame
+type TimelapseFrame struct {
+	Timestamp int64  `json:"timestamp"` // unix seconds
+	ETag      string `json:"etag"`
+	URL       string `json:"url"`
+}
+
+// TimelapseData lists the frames available for a camera, oldest first
+type TimelapseData struct {
+	ID     string           `json:"id"`
+	Frames []TimelapseFrame `json:"frames"`
+}
+
+// TimelapseRoute serves the list of retained frames for a camera. Each frame
+// can be fetched via its URL, /image/:id?t=<unix nanoseconds>.
+func TimelapseRoute(s *store.Store) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		entry, exists := s.Get(c.Param("id"))
+		if !exists || !entry.Camera.IsEnabled() {
+			return errorResponse(c, http.StatusNotFound, "Camera not found")
+		}
+
+		frames, _ := s.Frames(entry.ID)
+		data := TimelapseData{
+			ID:     entry.ID,
+			Frames: make([]TimelapseFrame, 0, len(frames)),
+		}
+		for _, frame := range frames {
+			data.Frames = append(data.Frames, TimelapseFrame{
+				Timestamp: frame.FetchedAt.Unix(),
+				ETag:      frame.Image.ETag,
+				URL:       "/image/" + entry.ID + "?t=" + strconv.FormatInt(frame.FetchedAt.UnixNano(), 10),
+			})
+		}
+
+		// The listing changes as often as the image does
+		c.Response().Header().Set("Cache-Control", cachePolicy(c).Image.CacheControl())
+		return c.JSON(http.StatusOK, data)
+	}
+}
+
+// FrameDiffData says whether a camera's image has changed since a frame the
+// client already has, and by how much
+type FrameDiffData struct {
+	ID      string `json:"id"`
+	From    string `json:"from"`
+	Current string `json:"current"` // the current image's ETag
+	// URL is the current image's content-addressed URL, to fetch it when
+	// Changed
+	URL     string `json:"url"`
+	Changed bool   `json:"changed"`
+	// Similar reports whether the frames look like the same picture, e.g.
+	// when only a timestamp overlay ticked
+	Similar bool `json:"similar"`
+	// ByteDelta is the size difference as a fraction of the earlier frame
+	ByteDelta float64 `json:"byteDelta"`
+	// PerceptualDistance is how many of 64 perceptual hash bits differ (-1
+	// if a frame couldn't be decoded)
+	PerceptualDistance int `json:"perceptualDistance"`
+}
+
+// FrameDiffRoute compares a camera's current image with the frame whose
+// ETag is ?from= (GET /camera/:id/diff), so a client can poll cheaply before
+// refetching the image. from must be the current image or still in the
+// timelapse history; otherwise it's a 404.
+func FrameDiffRoute(s *store.Store) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		from := c.QueryParam("from")
+		if from == "" {
+			return errorResponse(c, http.StatusBadRequest, "Missing from ETag")
+		}
+		entry, exists := s.Get(c.Param("id"))
+		if !exists || !entry.Camera.IsEnabled() {
+			return errorResponse(c, http.StatusNotFound, "Camera not found")
+		}
+
+		diff, ok := s.DiffFrame(entry.ID, from)
+		if !ok {
+			return errorResponse(c, http.StatusNotFound, "Frame not found")
+		}
+
+		// The answer changes as often as the image does
+		c.Response().Header().Set("Cache-Control", cachePolicy(c).Image.CacheControl())
+		return c.JSON(http.StatusOK, FrameDiffData{
+			ID:                 entry.ID,
+			From:               from,
+			Current:            diff.ETag,
+			URL:                "/image/" + entry.ID + "/" + trimETag(diff.ETag),
+			Changed:            diff.Changed,
+			Similar:            diff.Similar,
+			ByteDelta:          diff.ByteDelta,
+			PerceptualDistance: diff.PerceptualDistance,
+		})
+	}
+}
+
+// serveFrame serves a historical timelapse frame by its timestamp in unix
+// nanoseconds. Frames never change once captured, and no two share a
+// timestamp, so they can be cached aggressively.
+func serveFrame(c echo.Context, s *store.Store, id string, t string) error {
+	timestamp, err := strconv.ParseInt(t, 10, 64)
+	if err != nil {
+		return c.String(http.StatusBadRequest, "invalid timestamp")
+	}
+	entry, exists := s.Get(id)
+	if !exists || !entry.Camera.IsEnabled() {
+		return c.String(http.StatusNotFound, "image not found")
+	}
+
+	frame, exists := s.Frame(entry.ID, timestamp)
+	if !exists {
+		return c.String(http.StatusNotFound, "frame not found")
+	}
+
+	c.Response().Header().Set("Cache-Control", "public, max-age=86400, immutable")
+	c.Response().Header().Set("ETag", frame.Image.ETag)
+	if notModified(c, frame.Image.ETag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	if c.Request().Method == http.MethodHead {
+		return c.NoContent(http.StatusOK)
+	}
+	return c.Blob(http.StatusOK, frame.ContentType, frame.Image.Bytes)
+}
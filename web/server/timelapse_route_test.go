@@ -0,0 +1,205 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimelapseRoute(t *testing.T) {
+	var counter atomic.Int32
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage(fmt.Sprintf("frame %d", counter.Add(1))))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	disabled := false
+	canyons := &store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "Little Cottonwood Canyon",
+			Cameras: []store.Camera{
+				{Kind: "webcam", Src: imageServer.URL + "/cam.jpg", Alt: "Timelapse Camera"},
+				{Kind: "webcam", Src: imageServer.URL + "/off.jpg", Alt: "Off Camera", Enabled: &disabled},
+			},
+		},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	}
+	testStore := store.NewStore(canyons)
+	testStore.EnableTimelapse(5)
+	testStore.FetchImages(context.Background())
+	testStore.FetchImages(context.Background())
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/camera/timelapse-camera/timelapse.json", nil)
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var data TimelapseData
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &data))
+	require.Len(t, data.Frames, 2)
+	assert.NotEmpty(t, data.Frames[1].ETag)
+
+	t.Run("historical frame", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, data.Frames[1].URL, nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, string(testImage("frame 2")), rec.Body.String())
+		assert.Equal(t, data.Frames[1].ETag, rec.Header().Get("ETag"))
+		assert.Contains(t, rec.Header().Get("Cache-Control"), "immutable")
+	})
+
+	t.Run("frames fetched within a second stay apart", func(t *testing.T) {
+		require.NotEqual(t, data.Frames[0].URL, data.Frames[1].URL)
+		req := httptest.NewRequest(http.MethodGet, data.Frames[0].URL, nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, string(testImage("frame 1")), rec.Body.String())
+	})
+
+	t.Run("disabled camera", func(t *testing.T) {
+		for _, target := range []string{"/camera/off-camera/timelapse.json", "/image/off-camera?t=1"} {
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusNotFound, rec.Code, target)
+		}
+	})
+
+	t.Run("unknown frame", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/image/"+data.ID+"?t=1", nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("invalid timestamp", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/image/"+data.ID+"?t=abc", nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("unknown camera", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/camera/nope/timelapse.json", nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("camera page still routes", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/camera/timelapse-camera.json", nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestFrameDiffRoute(t *testing.T) {
+	var counter atomic.Int32
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage(fmt.Sprintf("frame %d", counter.Add(1))))
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	testStore := store.NewStore(&store.Canyons{
+		{
+			ID:   "LCC",
+			Name: "Little Cottonwood Canyon",
+			Cameras: []store.Camera{
+				{Kind: "webcam", Src: imageServer.URL + "/cam.jpg", Alt: "Diff Camera"},
+			},
+		},
+		{ID: "BCC", Name: "Big Cottonwood Canyon"},
+	})
+	testStore.EnableTimelapse(2)
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+
+	var etags []string
+	for range 3 {
+		testStore.FetchImages(context.Background())
+		entry, ok := testStore.Get("diff-camera")
+		require.True(t, ok)
+		etags = append(etags, entry.Image.ETag)
+	}
+	current := etags[2]
+
+	diff := func(t *testing.T, camera, from string) (*httptest.ResponseRecorder, FrameDiffData) {
+		req := httptest.NewRequest(http.MethodGet, "/camera/"+camera+"/diff?from="+url.QueryEscape(from), nil)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		var data FrameDiffData
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &data))
+		}
+		return rec, data
+	}
+
+	t.Run("identical frame", func(t *testing.T) {
+		rec, data := diff(t, "diff-camera", current)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.False(t, data.Changed)
+		assert.True(t, data.Similar)
+		assert.Equal(t, current, data.Current)
+		assert.Zero(t, data.ByteDelta)
+	})
+
+	t.Run("changed frame", func(t *testing.T) {
+		rec, data := diff(t, "diff-camera", etags[1])
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.True(t, data.Changed)
+		assert.Equal(t, etags[1], data.From)
+		assert.Equal(t, current, data.Current)
+		assert.Equal(t, "/image/"+data.ID+"/"+strings.Trim(current, `"`), data.URL)
+		assert.NotEmpty(t, rec.Header().Get("Cache-Control"))
+	})
+
+	t.Run("expired frame", func(t *testing.T) {
+		rec, _ := diff(t, "diff-camera", etags[0])
+		assert.Equal(t, http.StatusNotFound, rec.Code, "only 2 frames are buffered")
+	})
+
+	t.Run("missing from", func(t *testing.T) {
+		rec, _ := diff(t, "diff-camera", "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("unknown camera", func(t *testing.T) {
+		rec, _ := diff(t, "nope", current)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
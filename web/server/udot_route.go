@@ -17,8 +17,9 @@ type UDOTData struct {
 func UDOTRoute(s *store.Store) func(c echo.Context) error {
 	return func(c echo.Context) error {
 		canyonID := c.Param("canyon")
-		if canyonID != "LCC" && canyonID != "BCC" {
-			return c.String(http.StatusBadRequest, "Invalid canyon. Must be LCC or BCC")
+		canyon, ok := s.Canyon(canyonID)
+		if !ok {
+			return errorResponse(c, http.StatusBadRequest, "Invalid canyon: "+canyonID)
 		}
 
 		roadConditions := s.GetRoadConditions(canyonID)
@@ -30,7 +31,6 @@ func UDOTRoute(s *store.Store) func(c echo.Context) error {
 		sortedRoadConditions := SortRoadConditions(filteredRoadConditions)
 
 		// Get weather stations for all cameras in this canyon
-		canyon := s.Canyon(canyonID)
 		weatherStations := s.GetWeatherStationsForCanyon(canyon)
 
 		// Calculate LastUpdated as max of all timestamps, or current time if no data
@@ -23,6 +23,9 @@ type VersionInfo struct {
 	GoVersion string    `json:"go_version"`
 	Uptime    string    `json:"uptime"`
 	StartTime time.Time `json:"-"`
+	// DataHash identifies the loaded camera dataset (see store.DataHash),
+	// so deploy tooling can confirm a reload took effect
+	DataHash string `json:"data_hash,omitempty"`
 }
 
 var startTime = time.Now()
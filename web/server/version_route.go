@@ -4,12 +4,15 @@ import (
 	"net/http"
 
 	"github.com/labstack/echo/v4"
+	"github.com/stefanpenner/lcc-live/web/store"
 )
 
-// VersionRoute returns version information about the service
-func VersionRoute() func(c echo.Context) error {
+// VersionRoute returns version information about the service, including a
+// hash of the camera dataset s has loaded
+func VersionRoute(s *store.Store) func(c echo.Context) error {
 	return func(c echo.Context) error {
 		info := GetVersionInfo()
+		info.DataHash = s.DataHash()
 		return c.JSON(http.StatusOK, info)
 	}
 }
@@ -1,11 +1,14 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"testing/fstest"
 
+	"github.com/stefanpenner/lcc-live/web/store"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -31,6 +34,49 @@ func TestVersionRoute(t *testing.T) {
 	assert.NotEmpty(t, info.Uptime)
 }
 
+func TestVersionRoute_DataHash(t *testing.T) {
+	dataset := func(alt string) *store.Canyons {
+		return &store.Canyons{
+			{ID: "LCC", Name: "Little Cottonwood Canyon", Cameras: []store.Camera{
+				{Kind: "img", Src: "http://127.0.0.1:0/a.jpg", Alt: alt},
+			}},
+			{ID: "BCC", Name: "Big Cottonwood Canyon"},
+		}
+	}
+	testStore := store.NewStore(dataset("Camera A"))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	testStore.FetchImages(ctx)
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+	dataHash := func() string {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_/version", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		var info VersionInfo
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &info))
+		return info.DataHash
+	}
+
+	initial := dataHash()
+	require.NotEmpty(t, initial)
+
+	require.NoError(t, testStore.Reload(dataset("Camera A")))
+	assert.Equal(t, initial, dataHash(), "reloading identical data keeps the hash")
+
+	require.NoError(t, testStore.Reload(dataset("Camera B")))
+	changed := dataHash()
+	assert.NotEqual(t, initial, changed, "reloading different data changes the hash")
+
+	require.NoError(t, testStore.Reload(dataset("Camera B")))
+	assert.Equal(t, changed, dataHash())
+}
+
 func TestVersionHeader(t *testing.T) {
 	srv := setupTestServer(t)
 
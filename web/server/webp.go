@@ -0,0 +1,160 @@
+package server
+
+import (
+	"bytes"
+	"container/list"
+	"image"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gen2brain/webp"
+	"github.com/stefanpenner/lcc-live/web/store"
+	"golang.org/x/sync/singleflight"
+)
+
+// maxWebPImages bounds how many transcoded frames are kept; a couple per
+// camera covers the current frame and its width variants
+const maxWebPImages = 256
+
+// webpQuality is the lossy quality frames are re-encoded at
+const webpQuality = 80
+
+// webpKey identifies a transcoded frame: the camera, the ETag of the frame
+// it was made from, and the format it was made in
+type webpKey struct {
+	id          string
+	etag        string
+	contentType string
+}
+
+type webpImage struct {
+	key   webpKey
+	image *store.Image
+}
+
+// webpCache transcodes JPEG and PNG frames to WebP for clients that accept
+// it, keeping the most recently served results. Concurrent requests for the
+// same frame share one encode.
+type webpCache struct {
+	mu    sync.Mutex
+	order *list.List // of webpImage, most recently used first
+	items map[webpKey]*list.Element
+	limit int
+	group singleflight.Group
+}
+
+func newWebPCache(limit int) *webpCache {
+	return &webpCache{
+		order: list.New(),
+		items: make(map[webpKey]*list.Element),
+		limit: limit,
+	}
+}
+
+// canTranscodeToWebP reports whether frames of contentType are transcoded
+func canTranscodeToWebP(contentType string) bool {
+	media, _, _ := strings.Cut(contentType, ";")
+	switch strings.ToLower(strings.TrimSpace(media)) {
+	case "image/jpeg", "image/png":
+		return true
+	}
+	return false
+}
+
+// acceptsWebP reports whether an Accept header names WebP. Wildcards don't
+// count: clients that send only */* may not decode it.
+func acceptsWebP(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		media, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(media), "image/webp") {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// webpETag derives the transcoded frame's ETag from its source's, so the two
+// are never confused by caches
+func webpETag(etag string) string {
+	return "\"" + trimETag(etag) + "-webp\""
+}
+
+// transcode returns served as WebP, encoding it on a miss
+func (w *webpCache) transcode(id string, served servedImage) (servedImage, error) {
+	key := webpKey{id: id, etag: served.image.ETag, contentType: "image/webp"}
+
+	w.mu.Lock()
+	elem, ok := w.items[key]
+	if ok {
+		w.order.MoveToFront(elem)
+	}
+	w.mu.Unlock()
+
+	var transcoded *store.Image
+	if ok {
+		transcoded = elem.Value.(webpImage).image
+	} else {
+		result, err, _ := w.group.Do(id+"\x00"+key.etag, func() (any, error) {
+			encoded, err := encodeWebP(served.image.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			img := &store.Image{
+				Bytes:  encoded,
+				ETag:   webpETag(served.image.ETag),
+				Width:  served.image.Width,
+				Height: served.image.Height,
+			}
+			w.add(webpImage{key: key, image: img})
+			return img, nil
+		})
+		if err != nil {
+			return servedImage{}, err
+		}
+		transcoded = result.(*store.Image)
+	}
+
+	return servedImage{
+		image:         transcoded,
+		contentType:   key.contentType,
+		contentLength: int64(len(transcoded.Bytes)),
+		width:         served.width,
+	}, nil
+}
+
+// add caches img, evicting the least recently used frames over the limit
+func (w *webpCache) add(img webpImage) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if elem, ok := w.items[img.key]; ok {
+		w.order.MoveToFront(elem)
+		return
+	}
+	w.items[img.key] = w.order.PushFront(img)
+	for w.order.Len() > w.limit {
+		oldest := w.order.Back()
+		w.order.Remove(oldest)
+		delete(w.items, oldest.Value.(webpImage).key)
+	}
+}
+
+// encodeWebP decodes a JPEG or PNG frame and re-encodes it as lossy WebP
+func encodeWebP(data []byte) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := webp.Encode(&buf, src, webp.Options{Quality: webpQuality, Method: webp.DefaultMethod}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gen2brain/webp"
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageRoute_WebP(t *testing.T) {
+	var frame bytes.Buffer
+	require.NoError(t, jpeg.Encode(&frame, image.NewRGBA(image.Rect(0, 0, 64, 48)), nil))
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			if r.URL.Path == "/broken.jpg" {
+				w.Write(testImage("not really a jpeg"))
+				return
+			}
+			w.Write(frame.Bytes())
+		}
+	}))
+	t.Cleanup(imageServer.Close)
+
+	testStore := store.NewStore(&store.Canyons{
+		{ID: "LCC", Name: "LCC", Cameras: []store.Camera{
+			{Kind: "img", Src: imageServer.URL + "/cam.jpg", Alt: "Transcoded"},
+			{Kind: "img", Src: imageServer.URL + "/broken.jpg", Alt: "Broken"},
+		}},
+		{ID: "BCC", Name: "BCC"},
+	})
+	testStore.FetchImages(context.Background())
+
+	app, err := Start(ServerConfig{
+		Store:      testStore,
+		StaticFS:   fstest.MapFS{},
+		TemplateFS: fstest.MapFS{"canyon.html.tmpl": &fstest.MapFile{Data: []byte(`{{.Name}}`)}},
+	})
+	require.NoError(t, err)
+	get := func(path, accept, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept", accept)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+	const browserAccept = "image/avif,image/webp,image/*,*/*;q=0.8"
+
+	original := get("/image/transcoded", "image/*", "")
+	require.Equal(t, http.StatusOK, original.Code)
+	assert.Equal(t, "image/jpeg", original.Header().Get("Content-Type"))
+	assert.Equal(t, frame.Bytes(), original.Body.Bytes())
+	assert.Contains(t, original.Header().Values("Vary"), "Accept")
+
+	t.Run("clients that accept webp get webp", func(t *testing.T) {
+		rec := get("/image/transcoded", browserAccept, "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "image/webp", rec.Header().Get("Content-Type"))
+		assert.Contains(t, rec.Header().Values("Vary"), "Accept")
+		assert.NotEqual(t, original.Header().Get("ETag"), rec.Header().Get("ETag"))
+		assert.Equal(t, webpETag(original.Header().Get("ETag")), rec.Header().Get("ETag"))
+
+		decoded, err := webp.Decode(bytes.NewReader(rec.Body.Bytes()))
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 64, 48), decoded.Bounds())
+
+		// The cached encode is served again, and revalidates by its own ETag
+		again := get("/image/transcoded", browserAccept, "")
+		assert.Equal(t, rec.Body.Bytes(), again.Body.Bytes())
+		assert.Equal(t, http.StatusNotModified, get("/image/transcoded", browserAccept, rec.Header().Get("ETag")).Code)
+		assert.Equal(t, http.StatusOK, get("/image/transcoded", browserAccept, original.Header().Get("ETag")).Code)
+	})
+
+	t.Run("webp refused with q=0 serves the original", func(t *testing.T) {
+		rec := get("/image/transcoded", "image/webp;q=0, image/jpeg", "")
+		assert.Equal(t, "image/jpeg", rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("undecodable frames are served as they are", func(t *testing.T) {
+		rec := get("/image/broken", browserAccept, "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "image/jpeg", rec.Header().Get("Content-Type"))
+		assert.Equal(t, testImage("not really a jpeg"), rec.Body.Bytes())
+	})
+}
+
+func TestWebPCache_Bounded(t *testing.T) {
+	var frame bytes.Buffer
+	require.NoError(t, jpeg.Encode(&frame, image.NewRGBA(image.Rect(0, 0, 8, 8)), nil))
+	served := func(etag string) servedImage {
+		return servedImage{
+			image:       &store.Image{Bytes: frame.Bytes(), ETag: etag},
+			contentType: "image/jpeg",
+		}
+	}
+
+	cache := newWebPCache(2)
+	for _, etag := range []string{`"a"`, `"b"`, `"a"`, `"c"`} {
+		transcoded, err := cache.transcode("cam", served(etag))
+		require.NoError(t, err)
+		assert.Equal(t, webpETag(etag), transcoded.image.ETag)
+		assert.Equal(t, "image/webp", transcoded.contentType)
+	}
+
+	// "b" was least recently used when "c" pushed the cache over its limit
+	assert.Equal(t, 2, cache.order.Len())
+	assert.Contains(t, cache.items, webpKey{id: "cam", etag: `"a"`, contentType: "image/webp"})
+	assert.Contains(t, cache.items, webpKey{id: "cam", etag: `"c"`, contentType: "image/webp"})
+	assert.NotContains(t, cache.items, webpKey{id: "cam", etag: `"b"`, contentType: "image/webp"})
+}
+
+func TestAcceptsWebP(t *testing.T) {
+	assert.True(t, acceptsWebP("image/avif,image/webp,image/*,*/*;q=0.8"))
+	assert.True(t, acceptsWebP("IMAGE/WEBP"))
+	assert.False(t, acceptsWebP(""))
+	assert.False(t, acceptsWebP("*/*"))
+	assert.False(t, acceptsWebP("image/*"))
+	assert.False(t, acceptsWebP("image/webp;q=0"))
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// defaultShutdownTimeout bounds the whole shutdown sequence
+const defaultShutdownTimeout = 5 * time.Second
+
+// shutdownStep is one stage of an ordered shutdown
+type shutdownStep struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// runShutdown runs steps in order under a single deadline. Every step runs,
+// even after the deadline has passed, so late steps (flushing logs) still get
+// a chance; each sees the shared context and should return promptly once
+// it's done. Errors are collected rather than aborting the sequence.
+func runShutdown(timeout time.Duration, steps []shutdownStep) []error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+	for _, step := range steps {
+		if err := step.stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", step.name, err))
+		}
+	}
+	return errs
+}
+
+// waitContext waits for wait to return, giving up when ctx is done
+func waitContext(ctx context.Context, wait func() error) error {
+	done := make(chan error, 1)
+	go func() { done <- wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
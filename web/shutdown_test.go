@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunShutdown_Order(t *testing.T) {
+	var order []string
+	step := func(name string, err error) shutdownStep {
+		return shutdownStep{name: name, stop: func(ctx context.Context) error {
+			order = append(order, name)
+			return err
+		}}
+	}
+
+	errs := runShutdown(time.Second, []shutdownStep{
+		step("http", nil),
+		step("background", errors.New("boom")),
+		step("flush", nil),
+		step("ui", nil),
+	})
+
+	assert.Equal(t, []string{"http", "background", "flush", "ui"}, order)
+	require.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "background: boom")
+}
+
+func TestRunShutdown_Deadline(t *testing.T) {
+	blocked := make(chan struct{})
+	t.Cleanup(func() { close(blocked) })
+
+	flushed := false
+	start := time.Now()
+	errs := runShutdown(50*time.Millisecond, []shutdownStep{
+		{name: "background", stop: func(ctx context.Context) error {
+			return waitContext(ctx, func() error {
+				<-blocked // a goroutine that never drains
+				return nil
+			})
+		}},
+		{name: "flush", stop: func(ctx context.Context) error {
+			flushed = true
+			return nil
+		}},
+	})
+
+	assert.Less(t, time.Since(start), time.Second, "a stuck step must not hold up shutdown past the deadline")
+	assert.True(t, flushed, "later steps still run after the deadline")
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], context.DeadlineExceeded)
+}
@@ -28,14 +28,20 @@ func setupSmokeServer(t *testing.T) string {
 		w.Header().Set("Content-Type", "image/jpeg")
 		w.Header().Set("ETag", `"mock-etag"`)
 		if r.Method == http.MethodGet {
-			// Minimal valid JPEG (SOI + EOI markers)
-			w.Write([]byte{0xFF, 0xD8, 0xFF, 0xD9})
+			// Minimal decodable JPEG header (SOI + APP0 + 1x1 SOF0 + EOI)
+			w.Write([]byte{
+				0xFF, 0xD8,
+				0xFF, 0xE0, 0x00, 0x10, 'J', 'F', 'I', 'F', 0x00, 0x01, 0x01, 0x00, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00,
+				0xFF, 0xC0, 0x00, 0x0B, 0x08, 0x00, 0x01, 0x00, 0x01, 0x01, 0x01, 0x11, 0x00,
+				0xFF, 0xD9,
+			})
 		}
 	}))
 	t.Cleanup(imageServer.Close)
 
 	canyons := &store.Canyons{
-		LCC: store.Canyon{
+		{
+			ID:   "LCC",
 			Name: "Little Cottonwood Canyon",
 			ETag: `"smoke-lcc"`,
 			Status: store.Camera{
@@ -47,7 +53,8 @@ func setupSmokeServer(t *testing.T) string {
 				{Kind: "img", Src: imageServer.URL + "/lcc-cam2.jpg", Alt: "LCC Camera 2", Canyon: "LCC"},
 			},
 		},
-		BCC: store.Canyon{
+		{
+			ID:   "BCC",
 			Name: "Big Cottonwood Canyon",
 			ETag: `"smoke-bcc"`,
 			Status: store.Camera{
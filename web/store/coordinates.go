@@ -0,0 +1,103 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// sameCoordinate reports whether two optional coordinates are equal
+func sameCoordinate(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// applyCoordinates sets each camera's Latitude and Longitude from its
+// weather station, skipping stations that don't report a position. Canyons
+// whose cameras moved get a new ETag; it reports whether any did.
+func (c *Canyons) applyCoordinates(stations map[int]*WeatherStation) (bool, error) {
+	changed := false
+	for i := range *c {
+		canyon := &(*c)[i]
+		moved := false
+		for j := range canyon.Cameras {
+			camera := &canyon.Cameras[j]
+			if camera.WeatherStationId == nil {
+				continue
+			}
+			station, ok := stations[*camera.WeatherStationId]
+			if !ok || station.Latitude == nil || station.Longitude == nil {
+				continue
+			}
+			if sameCoordinate(camera.Latitude, station.Latitude) && sameCoordinate(camera.Longitude, station.Longitude) {
+				continue
+			}
+			latitude, longitude := *station.Latitude, *station.Longitude
+			camera.Latitude, camera.Longitude = &latitude, &longitude
+			moved = true
+		}
+		if !moved {
+			continue
+		}
+		canyon.ETag = ""
+		if err := c.setETag(canyon); err != nil {
+			return false, fmt.Errorf("failed to compute %s ETag: %w", canyon.ID, err)
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// weatherStations returns the current station index. It's replaced
+// wholesale rather than modified, so callers may read it without the lock.
+func (s *Store) weatherStations() map[int]*WeatherStation {
+	s.weatherStationsMu.RLock()
+	defer s.weatherStationsMu.RUnlock()
+	return s.weatherStationsById
+}
+
+// UpdateCameraCoordinates fills in camera positions from the indexed weather
+// stations. Canyons whose cameras moved get a new ETag and LastModified, so
+// cached canyon pages and JSON refresh; the rest are left untouched.
+func (s *Store) UpdateCameraCoordinates() error {
+	stations := s.weatherStations()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Copy on write: handlers may still hold the current canyons
+	updated := make(Canyons, len(*s.canyons))
+	for i, canyon := range *s.canyons {
+		canyon.Cameras = slices.Clone(canyon.Cameras)
+		updated[i] = canyon
+	}
+	changed, err := updated.applyCoordinates(stations)
+	if err != nil || !changed {
+		return err
+	}
+	if err := updated.stampLastModified(*s.canyons, time.Now()); err != nil {
+		return err
+	}
+
+	cameras := make(map[string]*Camera)
+	for i := range updated {
+		canyon := &updated[i]
+		if canyon.Status.Src != "" {
+			cameras[canyon.Status.ID] = &canyon.Status
+		}
+		for j := range canyon.Cameras {
+			cameras[canyon.Cameras[j].ID] = &canyon.Cameras[j]
+		}
+	}
+	for _, entry := range s.entries {
+		if camera, ok := cameras[entry.ID]; ok {
+			entry.Write(func(e *Entry) {
+				e.Camera = camera
+			})
+		}
+	}
+	s.canyons = &updated
+	return nil
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_UpdateCameraCoordinates(t *testing.T) {
+	stationID, otherStationID := 7, 8
+	store := NewStore(&Canyons{
+		{ID: "LCC", Name: "LCC", Cameras: []Camera{
+			{Kind: "webcam", Src: "https://example.com/a.jpg", Alt: "Located", WeatherStationId: &stationID},
+			{Kind: "webcam", Src: "https://example.com/b.jpg", Alt: "Unlocated", WeatherStationId: &otherStationID},
+		}},
+		{ID: "BCC", Name: "BCC", Cameras: []Camera{
+			{Kind: "webcam", Src: "https://example.com/c.jpg", Alt: "No Station"},
+		}},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel() // mark the store ready without fetching
+	store.FetchImages(ctx)
+
+	lcc, _ := store.Canyon("LCC")
+	bcc, _ := store.Canyon("BCC")
+	lccETag, bccETag := lcc.ETag, bcc.ETag
+	lccModified := lcc.LastModified
+	assert.Nil(t, lcc.Cameras[0].Latitude)
+
+	latitude, longitude := 40.57, -111.65
+	store.StoreWeatherStationsById([]WeatherStation{
+		{Id: stationID, Latitude: &latitude, Longitude: &longitude},
+		{Id: otherStationID}, // no position reported
+	})
+
+	updated, _ := store.Canyon("LCC")
+	require.NotNil(t, updated.Cameras[0].Latitude)
+	assert.Equal(t, latitude, *updated.Cameras[0].Latitude)
+	assert.Equal(t, longitude, *updated.Cameras[0].Longitude)
+	assert.Nil(t, updated.Cameras[1].Latitude, "stations without a position leave the camera unlocated")
+	assert.NotEqual(t, lccETag, updated.ETag)
+	assert.False(t, updated.LastModified.Before(lccModified))
+	assert.Nil(t, lcc.Cameras[0].Latitude, "canyons already handed out aren't modified")
+
+	unchanged, _ := store.Canyon("BCC")
+	assert.Equal(t, bccETag, unchanged.ETag)
+	assert.Equal(t, bcc.LastModified, unchanged.LastModified)
+
+	entry, ok := store.Get("located")
+	require.True(t, ok)
+	require.NotNil(t, entry.Camera.Latitude)
+	assert.Equal(t, latitude, *entry.Camera.Latitude)
+
+	t.Run("same positions keep the ETag", func(t *testing.T) {
+		require.NoError(t, store.UpdateCameraCoordinates())
+		again, _ := store.Canyon("LCC")
+		assert.Equal(t, updated.ETag, again.ETag)
+		assert.Equal(t, updated.LastModified, again.LastModified)
+	})
+
+	t.Run("reload keeps positions", func(t *testing.T) {
+		require.NoError(t, store.Reload(&Canyons{
+			{ID: "LCC", Name: "LCC", Cameras: []Camera{
+				{Kind: "webcam", Src: "https://example.com/a.jpg", Alt: "Located", WeatherStationId: &stationID},
+			}},
+			{ID: "BCC", Name: "BCC"},
+		}))
+		reloaded, _ := store.Canyon("LCC")
+		require.NotNil(t, reloaded.Cameras[0].Latitude)
+		assert.Equal(t, latitude, *reloaded.Cameras[0].Latitude)
+	})
+}
@@ -0,0 +1,161 @@
+package store
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// imageCacheExt is the file extension used for persisted image files
+const imageCacheExt = ".img"
+
+// imageCacheFileName builds the on-disk name for a camera image.
+// Files are keyed by camera ID (URL-safe base64, since IDs contain '/') with
+// the image ETag embedded: <key>.<etag>.img
+func imageCacheFileName(cameraID, etag string) string {
+	key := base64.RawURLEncoding.EncodeToString([]byte(cameraID))
+	return key + "." + strings.Trim(etag, "\"") + imageCacheExt
+}
+
+// parseImageCacheFileName is the inverse of imageCacheFileName
+func parseImageCacheFileName(name string) (cameraID, etag string, ok bool) {
+	if !strings.HasSuffix(name, imageCacheExt) {
+		return "", "", false
+	}
+	key, etag, found := strings.Cut(strings.TrimSuffix(name, imageCacheExt), ".")
+	if !found || etag == "" {
+		return "", "", false
+	}
+	id, err := base64.RawURLEncoding.DecodeString(key)
+	if err != nil {
+		return "", "", false
+	}
+	return string(id), "\"" + etag + "\"", true
+}
+
+// SaveImageCache writes every entry's current image to dir so it can be
+// restored with LoadImageCache on the next start. Previously cached files
+// are replaced.
+func (s *Store) SaveImageCache(dir string) (int, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create image cache directory: %w", err)
+	}
+
+	// Drop files from the previous run so stale ETags don't accumulate
+	existing, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read image cache directory: %w", err)
+	}
+	for _, f := range existing {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), imageCacheExt) {
+			_ = os.Remove(filepath.Join(dir, f.Name()))
+		}
+	}
+
+	saved := 0
+	for _, entry := range s.allEntries() {
+		snapshot := entry.ShallowSnapshot()
+		if snapshot.HTTPHeaders.Status != http.StatusOK || len(snapshot.Image.Bytes) == 0 {
+			continue
+		}
+
+		path := filepath.Join(dir, imageCacheFileName(snapshot.ID, snapshot.Image.ETag))
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, snapshot.Image.Bytes, 0644); err != nil {
+			return saved, fmt.Errorf("failed to write cached image for %s: %w", snapshot.ID, err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			_ = os.Remove(tmp)
+			return saved, fmt.Errorf("failed to write cached image for %s: %w", snapshot.ID, err)
+		}
+		// Preserve FetchedAt via the file's mtime so Last-Modified survives restarts
+		if !snapshot.FetchedAt.IsZero() {
+			_ = os.Chtimes(path, snapshot.FetchedAt, snapshot.FetchedAt)
+		}
+		saved++
+	}
+
+	return saved, nil
+}
+
+// LoadImageCache populates entries from images previously written by
+// SaveImageCache. Files for unknown cameras, or whose bytes no longer match
+// their embedded ETag, are ignored. If any image is restored the store is
+// marked ready, so requests are served immediately while the first fetch
+// cycle refreshes everything in the background.
+func (s *Store) LoadImageCache(dir string) (int, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read image cache directory: %w", err)
+	}
+
+	loaded := 0
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		cameraID, etag, ok := parseImageCacheFileName(f.Name())
+		if !ok {
+			continue
+		}
+		s.mu.RLock()
+		entry, exists := s.index[cameraID]
+		s.mu.RUnlock()
+		if !exists || !entry.Camera.IsFetchable() {
+			continue
+		}
+
+		path := filepath.Join(dir, f.Name())
+		imageBytes, err := os.ReadFile(path)
+		if err != nil || int64(len(imageBytes)) > s.options.MaxImageSize {
+			continue
+		}
+		if !sameContent(etag, contentETag(imageBytes)) {
+			continue // corrupt or truncated file
+		}
+		s.observeETagVersion(etag)
+
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+
+		contentType, width, height, err := decodeImageHeader(imageBytes)
+		if err != nil {
+			continue
+		}
+		var hash *uint64
+		if entry.Camera.ChangeThreshold > 0 || s.timelapseFrames > 0 {
+			hash = hashImage(imageBytes)
+		}
+		entry.Write(func(entry *Entry) {
+			entry.FetchedAt = info.ModTime()
+			entry.HTTPHeaders = &HTTPHeaders{
+				Status:        http.StatusOK,
+				ContentType:   contentType,
+				ContentLength: int64(len(imageBytes)),
+			}
+			entry.Image = &Image{
+				Bytes:          imageBytes,
+				ETag:           etag,
+				Src:            entry.Image.Src,
+				Width:          width,
+				Height:         height,
+				PerceptualHash: hash,
+			}
+		})
+		loaded++
+	}
+
+	if loaded > 0 {
+		s.checkReadiness()
+	}
+
+	return loaded, nil
+}
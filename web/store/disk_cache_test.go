@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDiskCacheTestCanyons(src string) *Canyons {
+	return &Canyons{
+		{
+			ID:   "LCC",
+			Name: "LCC",
+			Cameras: []Camera{
+				{Kind: "webcam", Src: src, Alt: "Test Camera"},
+				{Kind: "iframe", Src: "http://example.com/iframe.html", Alt: "Iframe Camera"},
+			},
+		},
+		{ID: "BCC", Name: "BCC"},
+	}
+}
+
+func TestStore_ImageCache_RoundTrip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("cached"))
+		}
+	}))
+	defer server.Close()
+
+	src := server.URL + "/test.jpg"
+	original := NewStore(newDiskCacheTestCanyons(src))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	original.FetchImages(ctx)
+
+	dir := t.TempDir()
+	saved, err := original.SaveImageCache(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 1, saved, "only the fetched webcam should be persisted")
+
+	want, exists := original.Get(original.entries[0].ID)
+	require.True(t, exists)
+
+	// A fresh store, as after a restart, with no FetchImages call
+	restored := NewStore(newDiskCacheTestCanyons(src))
+	assert.False(t, restored.IsReady())
+
+	loaded, err := restored.LoadImageCache(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 1, loaded)
+	assert.True(t, restored.IsReady(), "restoring images should mark the store ready")
+
+	got, exists := restored.Get(want.ID)
+	require.True(t, exists)
+	assert.Equal(t, want.Image.Bytes, got.Image.Bytes)
+	assert.Equal(t, want.Image.ETag, got.Image.ETag)
+	assert.Equal(t, http.StatusOK, got.HTTPHeaders.Status)
+	assert.Equal(t, "image/jpeg", got.HTTPHeaders.ContentType)
+	assert.Equal(t, int64(len(want.Image.Bytes)), got.HTTPHeaders.ContentLength)
+	assert.WithinDuration(t, want.FetchedAt, got.FetchedAt, time.Second)
+}
+
+func TestStore_ImageCache_SkipsCorruptAndUnknownFiles(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(newDiskCacheTestCanyons("http://cam1/test.jpg"))
+	id := s.entries[0].ID
+
+	// ETag that doesn't match the bytes
+	require.NoError(t, os.WriteFile(filepath.Join(dir, imageCacheFileName(id, "\"123\"")), []byte("corrupt"), 0644))
+	// Camera that's no longer configured
+	require.NoError(t, os.WriteFile(filepath.Join(dir, imageCacheFileName("unknown", "\"123\"")), []byte("data"), 0644))
+	// Unrelated file
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("hello"), 0644))
+
+	loaded, err := s.LoadImageCache(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 0, loaded)
+	assert.False(t, s.IsReady())
+}
+
+func TestStore_ImageCache_MissingDirectory(t *testing.T) {
+	s := NewStore(newDiskCacheTestCanyons("http://cam1/test.jpg"))
+
+	loaded, err := s.LoadImageCache(filepath.Join(t.TempDir(), "does-not-exist"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, loaded)
+}
+
+func TestImageCacheFileName_RoundTrip(t *testing.T) {
+	id := "aHR0cHM6Ly9leGFtcGxlLmNvbS9jYW0/Pz4+.jpg/+"
+	name := imageCacheFileName(id, "\"1234567890\"")
+	assert.NotContains(t, name, "/")
+
+	gotID, gotETag, ok := parseImageCacheFileName(name)
+	require.True(t, ok)
+	assert.Equal(t, id, gotID)
+	assert.Equal(t, "\"1234567890\"", gotETag)
+}
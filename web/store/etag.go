@@ -0,0 +1,58 @@
+package store
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// contentETag is the quoted xxhash of an image's bytes
+func contentETag(imageBytes []byte) string {
+	return "\"" + strconv.FormatUint(xxhash.Sum64(imageBytes), 10) + "\""
+}
+
+// versionedETag appends a version to a content ETag: "<hash>-<version>"
+func versionedETag(contentTag string, version uint64) string {
+	return strings.TrimSuffix(contentTag, "\"") + "-" + strconv.FormatUint(version, 10) + "\""
+}
+
+// splitETag returns an image ETag's content ETag and its version, 0 if it
+// has none
+func splitETag(etag string) (contentTag string, version uint64) {
+	hash, v, found := strings.Cut(strings.Trim(etag, "\""), "-")
+	if found {
+		version, _ = strconv.ParseUint(v, 10, 64)
+	}
+	return "\"" + hash + "\"", version
+}
+
+// sameContent reports whether an image ETag, versioned or not, is for the
+// bytes behind contentTag
+func sameContent(etag, contentTag string) bool {
+	hash, _ := splitETag(etag)
+	return hash == contentTag
+}
+
+// nextImageETag returns the ETag for a changed image. With VersionedETags
+// the content hash gets the next value of a store-wide counter, so the ETag
+// is unique even when two cameras (or one camera cycling between frames)
+// serve identical bytes, and it increases with every change.
+func (s *Store) nextImageETag(contentTag string) string {
+	if !s.options.VersionedETags {
+		return contentTag
+	}
+	return versionedETag(contentTag, s.etagVersion.Add(1))
+}
+
+// observeETagVersion keeps the counter ahead of a restored ETag's version,
+// so images fetched after a restart don't reuse it
+func (s *Store) observeETagVersion(etag string) {
+	_, version := splitETag(etag)
+	for {
+		current := s.etagVersion.Load()
+		if version <= current || s.etagVersion.CompareAndSwap(current, version) {
+			return
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_VersionedETags(t *testing.T) {
+	var frame atomic.Value
+	frame.Store("a")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.Write(testImage(frame.Load().(string)))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	canyons := func() *Canyons {
+		return &Canyons{{ID: "LCC", Cameras: []Camera{
+			{Kind: "img", Src: server.URL + "/one.jpg", Alt: "One"},
+			{Kind: "img", Src: server.URL + "/two.jpg", Alt: "Two"},
+		}}}
+	}
+	store := NewStoreWithOptions(canyons(), StoreOptions{VersionedETags: true})
+	etag := func(i int) (string, uint64) {
+		entry, ok := store.Get(store.entries[i].ID)
+		require.True(t, ok)
+		contentTag, version := splitETag(entry.Image.ETag)
+		assert.Equal(t, contentETag(entry.Image.Bytes), contentTag, "the hash is kept")
+		return entry.Image.ETag, version
+	}
+
+	store.FetchImages(context.Background())
+	one, oneVersion := etag(0)
+	two, _ := etag(1)
+	assert.NotEqual(t, one, two, "identical bytes from different cameras")
+
+	t.Run("unchanged images keep their etag", func(t *testing.T) {
+		store.FetchImages(context.Background())
+		again, _ := etag(0)
+		assert.Equal(t, one, again)
+	})
+
+	t.Run("every change increases the version", func(t *testing.T) {
+		frame.Store("b")
+		store.FetchImages(context.Background())
+		changed, changedVersion := etag(0)
+		assert.Greater(t, changedVersion, oneVersion)
+
+		frame.Store("a")
+		store.FetchImages(context.Background())
+		back, backVersion := etag(0)
+		assert.Greater(t, backVersion, changedVersion)
+		assert.NotEqual(t, one, back, "a frame that comes back gets a new etag")
+		assert.NotEqual(t, changed, back)
+	})
+
+	t.Run("restored etags keep the counter ahead", func(t *testing.T) {
+		dir := t.TempDir()
+		_, err := store.SaveImageCache(dir)
+		require.NoError(t, err)
+		_, highest := etag(0) // the last change
+
+		restored := NewStoreWithOptions(canyons(), StoreOptions{VersionedETags: true})
+		_, err = restored.LoadImageCache(dir)
+		require.NoError(t, err)
+		_, version := splitETag(restored.nextImageETag(contentETag(testImage("c"))))
+		assert.Greater(t, version, highest)
+	})
+}
+
+func TestStore_ETagsUnversionedByDefault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.Write(testImage("same"))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	store := NewStore(&Canyons{{ID: "LCC", Cameras: []Camera{{Kind: "img", Src: server.URL + "/one.jpg", Alt: "One"}}}})
+	store.FetchImages(context.Background())
+
+	entry, ok := store.Get(store.entries[0].ID)
+	require.True(t, ok)
+	assert.Equal(t, contentETag(testImage("same")), entry.Image.ETag)
+}
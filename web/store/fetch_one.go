@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrCameraNotFound is returned by FetchOne for an unknown camera
+	ErrCameraNotFound = errors.New("camera not found")
+	// ErrNotFetchable is returned by FetchOne for iframe and disabled cameras
+	ErrNotFetchable = errors.New("camera is not fetchable")
+	// ErrFetchFailed is returned by FetchOne when the origin didn't yield an
+	// image; the camera keeps its previous one
+	ErrFetchFailed = errors.New("fetch failed")
+)
+
+// FetchOne fetches a single camera (by ID or slug) now, using the same
+// HEAD/GET and metrics as FetchImages. Concurrent calls for the same camera
+// share one upstream fetch; a caller whose ctx ends stops waiting, but the
+// shared fetch carries on under the first caller's context. Unlike Get, it
+// doesn't wait for the first FetchImages.
+func (s *Store) FetchOne(ctx context.Context, cameraID string) error {
+	_, err := s.Probe(ctx, cameraID)
+	return err
+}
+
+// Probe is FetchOne that also returns the fetch's outcome, including the
+// origin's HTTP status and, for a failed fetch, why it failed
+func (s *Store) Probe(ctx context.Context, cameraID string) (CameraSyncResult, error) {
+	entry, exists := s.lookupNow(cameraID)
+	if !exists {
+		return CameraSyncResult{}, ErrCameraNotFound
+	}
+	if !entry.Camera.IsFetchable() || !entry.Camera.IsEnabled() {
+		return CameraSyncResult{}, ErrNotFetchable
+	}
+
+	results := s.fetchGroup.DoChan(entry.ID, func() (any, error) {
+		result := CameraSyncResult{
+			ID:     entry.ID,
+			Name:   entry.Camera.Alt,
+			Canyon: entry.Camera.Canyon,
+			Status: SyncCancelled,
+		}
+		s.fetchEntry(ctx, entry, &result)
+		switch result.Status {
+		case SyncError:
+			return result, fmt.Errorf("%s: %w: %w", metricsName(entry.Camera), ErrFetchFailed, result.Err)
+		case SyncCancelled:
+			return result, context.Cause(ctx)
+		}
+		return result, nil
+	})
+
+	select {
+	case r := <-results:
+		result, _ := r.Val.(CameraSyncResult)
+		return result, r.Err
+	case <-ctx.Done():
+		return CameraSyncResult{}, ctx.Err()
+	}
+}
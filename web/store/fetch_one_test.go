@@ -0,0 +1,146 @@
+package store
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_FetchOne(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken.jpg" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("on demand"))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	store := NewStore(&Canyons{
+		{
+			ID:   "LCC",
+			Name: "LCC",
+			Cameras: []Camera{
+				{Kind: "webcam", Src: server.URL + "/cold.jpg", Alt: "Cold"},
+				{Kind: "webcam", Src: server.URL + "/broken.jpg", Alt: "Broken"},
+				{Kind: "iframe", Src: server.URL + "/embed.html", Alt: "Embed"},
+			},
+		},
+		{ID: "BCC", Name: "BCC"},
+	})
+	ctx := context.Background()
+
+	// No FetchImages: the camera starts cold
+	entry, _ := store.lookupNow("cold")
+	require.Empty(t, entry.ShallowSnapshot().Image.ETag)
+
+	require.NoError(t, store.FetchOne(ctx, "cold"))
+	snapshot := entry.ShallowSnapshot()
+	assert.Equal(t, testImage("on demand"), snapshot.Image.Bytes)
+	assert.Equal(t, http.StatusOK, snapshot.HTTPHeaders.Status)
+
+	assert.ErrorIs(t, store.FetchOne(ctx, "broken"), ErrFetchFailed)
+	assert.ErrorIs(t, store.FetchOne(ctx, "embed"), ErrNotFetchable)
+	assert.ErrorIs(t, store.FetchOne(ctx, "nope"), ErrCameraNotFound)
+}
+
+func TestStore_Probe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.jpg" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("probed"))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	store := NewStore(&Canyons{
+		{
+			ID:   "LCC",
+			Name: "LCC",
+			Cameras: []Camera{
+				{Kind: "webcam", Src: server.URL + "/ok.jpg", Alt: "Ok"},
+				{Kind: "webcam", Src: server.URL + "/missing.jpg", Alt: "Missing"},
+			},
+		},
+		{ID: "BCC", Name: "BCC"},
+	})
+	ctx := context.Background()
+
+	result, err := store.Probe(ctx, "ok")
+	require.NoError(t, err)
+	assert.Equal(t, SyncChanged, result.Status)
+	assert.Equal(t, http.StatusOK, result.HTTPStatus)
+	assert.Equal(t, "Ok", result.Name)
+
+	result, err = store.Probe(ctx, "missing")
+	assert.ErrorIs(t, err, ErrFetchFailed)
+	assert.ErrorContains(t, err, "status 404")
+	assert.Equal(t, SyncError, result.Status)
+	assert.Equal(t, http.StatusNotFound, result.HTTPStatus)
+}
+
+func TestStore_FetchOne_SingleFlight(t *testing.T) {
+	var heads, gets atomic.Int32
+	arrived := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "HEAD" {
+			heads.Add(1)
+			select {
+			case arrived <- struct{}{}:
+			default:
+			}
+			<-release
+			return
+		}
+		gets.Add(1)
+		w.Write(testImage("shared"))
+	}))
+	t.Cleanup(server.Close)
+
+	store := NewStore(&Canyons{
+		{
+			ID:      "LCC",
+			Name:    "LCC",
+			Cameras: []Camera{{Kind: "webcam", Src: server.URL + "/cam.jpg", Alt: "Busy"}},
+		},
+		{ID: "BCC", Name: "BCC"},
+	})
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = store.FetchOne(context.Background(), "busy")
+		}()
+	}
+
+	// Hold the first fetch open until every caller has had time to join it
+	<-arrived
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), heads.Load(), "concurrent calls share one upstream fetch")
+	assert.Equal(t, int32(1), gets.Load())
+}
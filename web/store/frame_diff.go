@@ -0,0 +1,176 @@
+package store
+
+import (
+	"bytes"
+	"image"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+const (
+	// perceptualHashSize is the side of the grid averaged into a perceptual
+	// hash, one bit per cell
+	perceptualHashSize = 8
+	// perceptualHashSamples bounds the pixels sampled per cell side, so
+	// hashing a large frame stays cheap
+	perceptualHashSamples = 16
+	// maxPerceptualDistance is how many hash bits may differ for two frames
+	// to count as the same picture
+	maxPerceptualDistance = 2
+)
+
+// perceptualHash computes an average hash of an encoded image: each bit of
+// an 8x8 grid is set if that cell is brighter than the whole frame. Small
+// changes, like a ticking timestamp overlay, leave it unchanged.
+func perceptualHash(data []byte) (uint64, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	bounds := img.Bounds()
+	cellW := max(bounds.Dx()/perceptualHashSize, 1)
+	cellH := max(bounds.Dy()/perceptualHashSize, 1)
+	stepX := max(cellW/perceptualHashSamples, 1)
+	stepY := max(cellH/perceptualHashSamples, 1)
+
+	var cells [perceptualHashSize * perceptualHashSize]float64
+	var total float64
+	for row := range perceptualHashSize {
+		for col := range perceptualHashSize {
+			x0, y0 := bounds.Min.X+col*cellW, bounds.Min.Y+row*cellH
+			var sum float64
+			var n int
+			for y := y0; y < min(y0+cellH, bounds.Max.Y); y += stepY {
+				for x := x0; x < min(x0+cellW, bounds.Max.X); x += stepX {
+					r, g, b, _ := img.At(x, y).RGBA()
+					sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+					n++
+				}
+			}
+			if n > 0 {
+				cells[row*perceptualHashSize+col] = sum / float64(n)
+			}
+			total += cells[row*perceptualHashSize+col]
+		}
+	}
+
+	mean := total / float64(len(cells))
+	var hash uint64
+	for i, luminance := range cells {
+		if luminance > mean {
+			hash |= 1 << i
+		}
+	}
+	return hash, nil
+}
+
+// hashImage returns the perceptual hash of an encoded image, or nil if it
+// can't be decoded
+func hashImage(data []byte) *uint64 {
+	hash, err := perceptualHash(data)
+	if err != nil {
+		return nil
+	}
+	return &hash
+}
+
+// similarFrame reports whether next, whose perceptual hash is nextHash, is
+// a near-identical copy of current: its size is within threshold (a
+// fraction, e.g. 0.02 for 2%) of current's and their perceptual hashes match
+func similarFrame(current *Image, next []byte, nextHash *uint64, threshold float64) bool {
+	if current == nil || len(current.Bytes) == 0 || threshold <= 0 {
+		return false
+	}
+	if byteDelta(current.Bytes, next) > threshold {
+		return false
+	}
+	distance, ok := hashDistance(current.PerceptualHash, nextHash)
+	return ok && distance <= maxPerceptualDistance
+}
+
+// byteDelta is the size difference between two encoded frames, as a
+// fraction of the first's size
+func byteDelta(from, to []byte) float64 {
+	if len(from) == 0 {
+		return 0
+	}
+	return math.Abs(float64(len(to)-len(from))) / float64(len(from))
+}
+
+// hashDistance is how many bits differ between two perceptual hashes, from
+// 0 (the same picture) to 64. It's false if either is missing.
+func hashDistance(a, b *uint64) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+	return bits.OnesCount64(*a ^ *b), true
+}
+
+// FrameDiff compares a camera's current image with an earlier frame
+type FrameDiff struct {
+	// ETag is the current image's
+	ETag string
+	// Changed reports whether the current image differs from the earlier
+	// frame at all
+	Changed bool
+	// ByteDelta is the size difference, as a fraction of the earlier
+	// frame's size
+	ByteDelta float64
+	// PerceptualDistance is how many of the 64 perceptual hash bits differ,
+	// or -1 if either frame has no hash
+	PerceptualDistance int
+	// Similar reports whether the perceptual hashes match, the test
+	// ChangeThreshold applies to near-identical frames
+	Similar bool
+}
+
+// DiffFrame compares a camera's (by ID or slug) current image with the
+// earlier frame whose ETag is from, which must be the current image or still
+// in its timelapse history. The bool is false if the camera doesn't exist
+// or that frame isn't retained.
+func (s *Store) DiffFrame(cameraID, from string) (FrameDiff, bool) {
+	entry, exists := s.lookup(cameraID)
+	if !exists {
+		return FrameDiff{}, false
+	}
+
+	from = strings.Trim(from, `"`)
+	var current, earlier *Image
+	entry.Read(func(entry *Entry) {
+		current = entry.Image
+		if strings.Trim(current.ETag, `"`) == from {
+			earlier = current
+			return
+		}
+		if entry.frames == nil {
+			return
+		}
+		frames := entry.frames.snapshot()
+		for i := len(frames) - 1; i >= 0; i-- {
+			if strings.Trim(frames[i].Image.ETag, `"`) == from {
+				earlier = frames[i].Image
+				return
+			}
+		}
+	})
+	if from == "" || earlier == nil {
+		return FrameDiff{}, false
+	}
+
+	diff := FrameDiff{ETag: current.ETag}
+	if earlier.ETag == current.ETag {
+		diff.Similar = true
+		return diff, true
+	}
+	// Compare the hashes computed when the images were stored, so a diff
+	// never decodes a frame
+	diff.Changed = true
+	diff.ByteDelta = byteDelta(earlier.Bytes, current.Bytes)
+	diff.PerceptualDistance = -1
+	if distance, ok := hashDistance(earlier.PerceptualHash, current.PerceptualHash); ok {
+		diff.PerceptualDistance = distance
+		diff.Similar = distance <= maxPerceptualDistance
+	}
+	return diff, true
+}
@@ -0,0 +1,177 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gradientFrame encodes a 320x240 gradient JPEG with a small "timestamp"
+// block whose shade is tick, and optionally inverted
+func gradientFrame(t *testing.T, tick uint8, inverted bool) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 320, 240))
+	for y := range 240 {
+		for x := range 320 {
+			v := uint8((x + y) * 255 / (320 + 240))
+			if inverted {
+				v = 255 - v
+			}
+			img.Set(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	for y := 4; y < 12; y++ {
+		for x := 4; x < 40; x++ {
+			img.Set(x, y, color.RGBA{tick, tick, tick, 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 80}))
+	return buf.Bytes()
+}
+
+func TestStore_FetchImages_ChangeThreshold(t *testing.T) {
+	first := gradientFrame(t, 0, false)
+	overlayTicked := gradientFrame(t, 255, false)
+	differentScene := gradientFrame(t, 0, true)
+	require.NotEqual(t, first, overlayTicked)
+
+	var frame atomic.Pointer[[]byte]
+	frame.Store(&first)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(*frame.Load())
+		}
+	}))
+	defer server.Close()
+
+	newStore := func(threshold float64) *Store {
+		return NewStore(&Canyons{
+			{ID: "LCC", Name: "LCC", Cameras: []Camera{
+				{Kind: "webcam", Src: server.URL + "/cam.jpg", Alt: "Overlay Cam", ChangeThreshold: threshold},
+			}},
+			{ID: "BCC", Name: "BCC"},
+		})
+	}
+	fetch := func(store *Store, next []byte) (EntrySnapshot, SyncStatus) {
+		frame.Store(&next)
+		var status SyncStatus
+		unsubscribe := store.OnSync(func(result SyncResult) {
+			status = result.Cameras[0].Status
+		})
+		defer unsubscribe()
+		store.FetchImages(context.Background())
+		entry, ok := store.Get("overlay-cam")
+		require.True(t, ok)
+		return entry, status
+	}
+
+	t.Run("near-identical frames are unchanged under the threshold", func(t *testing.T) {
+		store := newStore(0.05)
+		before, _ := fetch(store, first)
+		after, status := fetch(store, overlayTicked)
+		assert.Equal(t, before.Image.ETag, after.Image.ETag)
+		assert.Equal(t, before.FetchedAt, after.FetchedAt)
+		assert.Equal(t, first, after.Image.Bytes, "the current frame is kept")
+		assert.Equal(t, SyncUnchanged, status)
+	})
+
+	t.Run("a different scene still changes", func(t *testing.T) {
+		store := newStore(0.05)
+		before, _ := fetch(store, first)
+		after, status := fetch(store, differentScene)
+		assert.NotEqual(t, before.Image.ETag, after.Image.ETag)
+		assert.Equal(t, SyncChanged, status)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		store := newStore(0)
+		before, _ := fetch(store, first)
+		after, status := fetch(store, overlayTicked)
+		assert.NotEqual(t, before.Image.ETag, after.Image.ETag)
+		assert.Equal(t, SyncChanged, status)
+		assert.Nil(t, after.Image.PerceptualHash, "nothing compares the frames, so they aren't decoded")
+	})
+}
+
+func TestStore_DiffFrame(t *testing.T) {
+	first := gradientFrame(t, 0, false)
+	overlayTicked := gradientFrame(t, 255, false)
+	differentScene := gradientFrame(t, 0, true)
+
+	var frame atomic.Pointer[[]byte]
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(*frame.Load())
+		}
+	}))
+	defer server.Close()
+
+	store := NewStore(&Canyons{
+		{ID: "LCC", Name: "LCC", Cameras: []Camera{
+			{Kind: "webcam", Src: server.URL + "/cam.jpg", Alt: "Diff Cam"},
+		}},
+		{ID: "BCC", Name: "BCC"},
+	})
+	store.EnableTimelapse(2)
+	fetch := func(next []byte) string {
+		frame.Store(&next)
+		store.FetchImages(context.Background())
+		entry, ok := store.Get("diff-cam")
+		require.True(t, ok)
+		require.NotNil(t, entry.Image.PerceptualHash, "frames are hashed once, when stored")
+		return entry.Image.ETag
+	}
+
+	firstETag := fetch(first)
+	t.Run("identical frames", func(t *testing.T) {
+		diff, ok := store.DiffFrame("diff-cam", firstETag)
+		require.True(t, ok)
+		assert.Equal(t, FrameDiff{ETag: firstETag, Similar: true}, diff)
+
+		_, ok = store.DiffFrame("diff-cam", strings.Trim(firstETag, `"`))
+		assert.True(t, ok, "the ETag may be given unquoted")
+	})
+
+	tickedETag := fetch(overlayTicked)
+	t.Run("overlay tick", func(t *testing.T) {
+		diff, ok := store.DiffFrame("diff-cam", firstETag)
+		require.True(t, ok)
+		assert.Equal(t, tickedETag, diff.ETag)
+		assert.True(t, diff.Changed)
+		assert.True(t, diff.Similar, "the picture is the same")
+		assert.LessOrEqual(t, diff.PerceptualDistance, maxPerceptualDistance)
+	})
+
+	fetch(differentScene)
+	t.Run("different scene", func(t *testing.T) {
+		diff, ok := store.DiffFrame("diff-cam", tickedETag)
+		require.True(t, ok)
+		assert.True(t, diff.Changed)
+		assert.False(t, diff.Similar)
+		assert.Greater(t, diff.PerceptualDistance, maxPerceptualDistance)
+	})
+
+	t.Run("expired or unknown frames", func(t *testing.T) {
+		_, ok := store.DiffFrame("diff-cam", firstETag)
+		assert.False(t, ok, "the first frame has left the 2-frame history")
+		_, ok = store.DiffFrame("diff-cam", `"nope"`)
+		assert.False(t, ok)
+		_, ok = store.DiffFrame("diff-cam", "")
+		assert.False(t, ok)
+		_, ok = store.DiffFrame("no-such-cam", tickedETag)
+		assert.False(t, ok)
+	})
+}
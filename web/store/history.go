@@ -0,0 +1,85 @@
+package store
+
+import (
+	"time"
+
+	"github.com/stefanpenner/lcc-live/web/metrics"
+)
+
+// maxAvailabilityHistory bounds the transitions kept per camera
+const maxAvailabilityHistory = 100
+
+// AvailabilityTransition records a camera going up or down
+type AvailabilityTransition struct {
+	Timestamp time.Time `json:"timestamp"`
+	Available bool      `json:"available"`
+}
+
+// recordAvailability appends a transition when the camera's availability
+// differs from its last recorded state. The first observation always counts.
+// The caller must not hold the entry's lock.
+func (e *Entry) recordAvailability(available bool, at time.Time) {
+	e.Write(func(entry *Entry) {
+		if n := len(entry.history); n > 0 && entry.history[n-1].Available == available {
+			return
+		}
+		if len(entry.history) == maxAvailabilityHistory {
+			// Copy rather than reslice so the dropped transition is released
+			entry.history = append(entry.history[:0:0], entry.history[1:]...)
+		}
+		entry.history = append(entry.history, AvailabilityTransition{Timestamp: at, Available: available})
+	})
+}
+
+// recordAvailability updates each fetched camera's availability history
+// from a sync's results. Cancelled fetches say nothing about the camera.
+func (s *Store) recordAvailability(entries []*Entry, results []CameraSyncResult, at time.Time) {
+	for i, r := range results {
+		switch r.Status {
+		case SyncChanged, SyncUnchanged:
+			entries[i].recordAvailability(true, at)
+		case SyncError:
+			entries[i].recordAvailability(false, at)
+		}
+	}
+}
+
+// recordCanyonAvailability sets each canyon's availability ratio from a
+// cycle's results. Canyons with no fetched cameras keep their last ratio.
+func recordCanyonAvailability(result SyncResult) {
+	type tally struct{ up, fetched int }
+	canyons := make(map[string]*tally)
+	for _, r := range result.Cameras {
+		if r.Status == SyncCancelled {
+			continue
+		}
+		t := canyons[r.Canyon]
+		if t == nil {
+			t = &tally{}
+			canyons[r.Canyon] = t
+		}
+		t.fetched++
+		if r.Status != SyncError {
+			t.up++
+		}
+	}
+	for canyon, t := range canyons {
+		metrics.CanyonAvailabilityRatio.WithLabelValues(canyon).Set(float64(t.up) / float64(t.fetched))
+	}
+}
+
+// History returns a camera's (by ID or slug) availability transitions,
+// oldest first. The bool reports whether the camera exists.
+func (s *Store) History(cameraID string) ([]AvailabilityTransition, bool) {
+	entry, exists := s.lookup(cameraID)
+	if !exists {
+		return nil, false
+	}
+
+	var history []AvailabilityTransition
+	entry.Read(func(entry *Entry) {
+		history = make([]AvailabilityTransition, len(entry.history))
+		copy(history, entry.history)
+	})
+	return history, true
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_History(t *testing.T) {
+	var down atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("image"))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	store := NewStore(&Canyons{
+		{
+			ID:      "LCC",
+			Name:    "LCC",
+			Cameras: []Camera{{Kind: "webcam", Src: server.URL + "/cam.jpg", Alt: "Flaky"}},
+		},
+		{ID: "BCC", Name: "BCC"},
+	})
+	ctx := context.Background()
+
+	store.FetchImages(ctx) // up
+	store.FetchImages(ctx) // still up, not a transition
+	down.Store(true)
+	store.FetchImages(ctx) // down
+	store.FetchImages(ctx) // still down
+	down.Store(false)
+	store.FetchImages(ctx) // up again
+
+	history, exists := store.History("flaky")
+	require.True(t, exists)
+	require.Len(t, history, 3)
+	assert.True(t, history[0].Available)
+	assert.False(t, history[1].Available)
+	assert.True(t, history[2].Available)
+	assert.False(t, history[1].Timestamp.Before(history[0].Timestamp))
+	assert.False(t, history[2].Timestamp.Before(history[1].Timestamp))
+
+	_, exists = store.History("nope")
+	assert.False(t, exists)
+}
+
+func TestStore_History_SurvivesReload(t *testing.T) {
+	var down atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("image"))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	canyons := func() *Canyons {
+		return &Canyons{
+			{
+				ID:      "LCC",
+				Name:    "LCC",
+				Cameras: []Camera{{Kind: "webcam", Src: server.URL + "/cam.jpg", Alt: "Flaky"}},
+			},
+			{ID: "BCC", Name: "BCC"},
+		}
+	}
+	store := NewStore(canyons())
+	ctx := context.Background()
+	store.FetchImages(ctx) // up
+	down.Store(true)
+	store.FetchImages(ctx) // down
+
+	before, exists := store.History("flaky")
+	require.True(t, exists)
+	require.Len(t, before, 2)
+
+	require.NoError(t, store.Reload(canyons()))
+	after, exists := store.History("flaky")
+	require.True(t, exists)
+	assert.Equal(t, before, after, "a reload keeps the timeline of surviving cameras")
+}
+
+func TestEntry_RecordAvailability_Bounded(t *testing.T) {
+	entry := &Entry{}
+	start := time.Unix(1700000000, 0)
+	for i := range maxAvailabilityHistory + 10 {
+		entry.recordAvailability(i%2 == 0, start.Add(time.Duration(i)*time.Second))
+	}
+
+	require.Len(t, entry.history, maxAvailabilityHistory)
+	// The oldest transitions were dropped
+	assert.Equal(t, start.Add(10*time.Second), entry.history[0].Timestamp)
+}
@@ -2,19 +2,29 @@
 package store
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/fs"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/mitchellh/hashstructure"
 )
 
 // Image represents a cached camera image with metadata
 type Image struct {
-	Src   string
-	ETag  string
-	Bytes []byte
+	Src    string
+	ETag   string
+	Bytes  []byte
+	Width  int // pixel dimensions, 0 if the format couldn't be decoded
+	Height int
+	// PerceptualHash is computed once, when the image is stored, if the
+	// camera compares frames (a ChangeThreshold or timelapse history); nil
+	// otherwise or if the image couldn't be decoded
+	PerceptualHash *uint64
 }
 
 // HTTPHeaders contains HTTP response metadata for cached images
@@ -31,8 +41,92 @@ type Camera struct {
 	Kind             string `json:"kind"`
 	Src              string `json:"src"`
 	Alt              string `json:"alt"`
+	Slug             string `json:"slug,omitempty"` // overrides the slug derived from Alt
 	Canyon           string `json:"canyon"`
 	WeatherStationId *int   `json:"weatherStationId,omitempty"`
+	Enabled          *bool  `json:"enabled,omitempty"` // nil means enabled
+	// BrowserUserAgent sends a Chrome User-Agent for origins that block others
+	BrowserUserAgent bool `json:"browserUserAgent,omitempty"`
+	// Headers are sent with every fetch of the camera, e.g. a Referer,
+	// cookie or API key its origin requires; a User-Agent here overrides
+	// the default. They're never marshalled, so secrets stay out of API
+	// responses.
+	Headers map[string]string `json:"headers,omitempty"`
+	// InsecureTLS skips certificate verification for this camera when the
+	// store verifies TLS (e.g. an origin with a self-signed certificate)
+	InsecureTLS bool `json:"insecureTLS,omitempty"`
+	// ChangeThreshold suppresses micro-changes for cameras whose image
+	// changes every cycle (e.g. a timestamp overlay): a new frame within
+	// this fraction of the current one's size (e.g. 0.02 for 2%) that looks
+	// the same keeps the current image and ETag. It costs a decode per new
+	// frame; 0 disables it.
+	ChangeThreshold float64 `json:"changeThreshold,omitempty"`
+	// Sources are other sizes of the same image the origin publishes, e.g. a
+	// thumbnail; Src stays the default. ImageRoute picks one by ?w= and
+	// Accept.
+	Sources []ImageSource `json:"sources,omitempty"`
+	// Latitude and Longitude locate the camera, taken from its UDOT weather
+	// station by UpdateCameraCoordinates. Nil until the position is known.
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	// Width and Height are the latest image's pixel dimensions. They aren't
+	// configured in data.json; routes fill them in when serving a camera.
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	// Status is "live", "stale" or "down". Like Width and Height it isn't
+	// configured; canyon JSON fills it in.
+	Status string `json:"status,omitempty"`
+}
+
+// MarshalJSON encodes the camera without its Headers, which may hold
+// credentials
+func (c Camera) MarshalJSON() ([]byte, error) {
+	type camera Camera // without this method
+	public := camera(c)
+	public.Headers = nil
+	return json.Marshal(public)
+}
+
+// ImageSource is an alternate URL for a camera's image at a given size
+type ImageSource struct {
+	Src string `json:"src"`
+	// MaxWidth is the widest display the source is meant for, usually its
+	// pixel width
+	MaxWidth int `json:"maxWidth"`
+}
+
+// knownKinds are the camera kinds data.json may use. An empty kind (as on
+// status cameras) is treated like an image.
+var knownKinds = map[string]bool{
+	"":       true,
+	"img":    true,
+	"image":  true,
+	"webcam": true,
+	"status": true,
+	"iframe": true,
+	"mjpeg":  true,
+}
+
+// IsFetchable reports whether the camera's kind has an image for the store
+// to fetch. Iframe cameras are embedded as-is and never fetched.
+func (c *Camera) IsFetchable() bool {
+	return c.Kind != "iframe"
+}
+
+// IsEnabled reports whether the camera should be fetched and served.
+// Disabled cameras stay configured (and listed in /_/cameras) but are
+// skipped by FetchImages and hidden from the public routes.
+func (c *Camera) IsEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+// GetSlug returns the camera's URL slug: the explicit Slug if set,
+// otherwise one derived from Alt
+func (c *Camera) GetSlug() string {
+	if c.Slug != "" {
+		return c.Slug
+	}
+	return slugify(c.Alt)
 }
 
 // RoadCondition represents road condition data from UDOT API
@@ -168,10 +262,26 @@ func (e *Event) UnmarshalJSON(data []byte) error {
 
 // Canyon represents a canyon with its cameras and status
 type Canyon struct {
+	ID      string   `json:"-"` // e.g. "LCC"; taken from the canyon's key in data.json
 	Name    string   `json:"name"`
+	Title   string   `json:"title,omitempty"` // e.g. "Little Cottonwood Canyon"
 	ETag    string   `json:"etag"`
 	Status  Camera   `json:"status"`
 	Cameras []Camera `json:"cameras"`
+	// LastModified is when the canyon's configuration (its ETag) last
+	// changed, set by the store on creation and Reload
+	LastModified time.Time `json:"-" hash:"ignore"`
+}
+
+// EnabledCameras returns the canyon's cameras, excluding disabled ones
+func (c *Canyon) EnabledCameras() []Camera {
+	cameras := make([]Camera, 0, len(c.Cameras))
+	for _, camera := range c.Cameras {
+		if camera.IsEnabled() {
+			cameras = append(cameras, camera)
+		}
+	}
+	return cameras
 }
 
 // GetETag returns the canyon's ETag for cache validation
@@ -179,10 +289,97 @@ func (c *Canyon) GetETag() string {
 	return c.ETag
 }
 
-// Canyons represents the collection of all canyons
-type Canyons struct {
-	LCC Canyon `json:"lcc"`
-	BCC Canyon `json:"bcc"`
+// GetTitle returns the canyon's display title, falling back to its name
+func (c *Canyon) GetTitle() string {
+	if c.Title != "" {
+		return c.Title
+	}
+	return c.Name
+}
+
+// Path returns the canyon's page path, e.g. "/bcc"
+func (c *Canyon) Path() string {
+	return "/" + strings.ToLower(c.ID)
+}
+
+// Canyons is the ordered collection of all canyons. In JSON it's an object
+// keyed by lowercase canyon ID ({"lcc": {...}, "bcc": {...}}); key order is
+// preserved, and the first canyon is the one served at "/".
+type Canyons []Canyon
+
+// Get returns the canyon with the given ID
+func (c Canyons) Get(id string) (*Canyon, bool) {
+	for i := range c {
+		if c[i].ID == id {
+			return &c[i], true
+		}
+	}
+	return nil, false
+}
+
+// UnmarshalJSON decodes the keyed object form, keeping the file's key order
+func (c *Canyons) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("canyons must be a JSON object keyed by canyon ID")
+	}
+
+	canyons := Canyons{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := tok.(string) // object keys are always strings
+		id := strings.ToUpper(key)
+		if id == "" {
+			return fmt.Errorf("canyon ID must not be empty")
+		}
+		if _, exists := canyons.Get(id); exists {
+			return fmt.Errorf("duplicate canyon ID %q", key)
+		}
+
+		var canyon Canyon
+		if err := dec.Decode(&canyon); err != nil {
+			return fmt.Errorf("canyon %q: %w", key, err)
+		}
+		canyon.ID = id
+		canyons = append(canyons, canyon)
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+
+	*c = canyons
+	return nil
+}
+
+// MarshalJSON encodes the canyons in the keyed object form, in order
+func (c Canyons) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i := range c {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(strings.ToLower(c[i].ID))
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(&c[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
 }
 
 // Load loads canyon data from a JSON file
@@ -191,28 +388,113 @@ func (c *Canyons) Load(f fs.FS, filepath string) error {
 	if err != nil {
 		return fmt.Errorf("failed to read %s: %w", filepath, err)
 	}
+	return c.parse(data, "file "+filepath)
+}
+
+// LoadReader loads canyon data from JSON read from r, e.g. a remote
+// config's response body. source names it in errors.
+func (c *Canyons) LoadReader(r io.Reader, source string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", source, err)
+	}
+	return c.parse(data, source)
+}
 
+// parse decodes canyon JSON, in either shape, and precomputes ETags
+func (c *Canyons) parse(data []byte, source string) error {
 	if len(data) == 0 {
-		return fmt.Errorf("file %s is empty", filepath)
+		return fmt.Errorf("%s is empty", source)
 	}
 
 	// Try to validate JSON structure before unmarshaling
 	if !json.Valid(data) {
-		return fmt.Errorf("invalid JSON in file %s", filepath)
+		return fmt.Errorf("invalid JSON in %s", source)
 	}
 
-	if err := json.Unmarshal(data, c); err != nil {
-		return fmt.Errorf("failed to parse JSON from %s: %w", filepath, err)
+	// Accept the flat legacy shape too, to ease migrating old files
+	var err error
+	if isFlatCanyonsJSON(data) {
+		err = c.unmarshalFlat(data)
+	} else {
+		err = json.Unmarshal(data, c)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse JSON from %s: %w", source, err)
 	}
 
 	// precompute etags
-	if err := c.setETag(&c.LCC); err != nil {
-		return fmt.Errorf("failed to compute LCC ETag: %w", err)
+	for i := range *c {
+		canyon := &(*c)[i]
+		if err := c.setETag(canyon); err != nil {
+			return fmt.Errorf("failed to compute %s ETag: %w", canyon.ID, err)
+		}
 	}
-	if err := c.setETag(&c.BCC); err != nil {
-		return fmt.Errorf("failed to compute BCC ETag: %w", err)
+
+	return nil
+}
+
+// isFlatCanyonsJSON reports whether data is the flat legacy shape,
+// {"cameras": [...]}, rather than the keyed {"lcc": {...}} one
+func isFlatCanyonsJSON(data []byte) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil || len(fields) != 1 {
+		return false
+	}
+	cameras, ok := fields["cameras"]
+	return ok && bytes.HasPrefix(bytes.TrimSpace(cameras), []byte("["))
+}
+
+// unmarshalFlat decodes the flat shape, grouping cameras into canyons
+// by their "canyon" field in first-seen order. A "status" camera becomes its
+// canyon's status image, as in the keyed shape.
+func (c *Canyons) unmarshalFlat(data []byte) error {
+	var flat struct {
+		Cameras []Camera `json:"cameras"`
+	}
+	if err := json.Unmarshal(data, &flat); err != nil {
+		return err
 	}
 
+	canyons := Canyons{}
+	for i, camera := range flat.Cameras {
+		id := strings.ToUpper(camera.Canyon)
+		if id == "" {
+			return fmt.Errorf("camera %d (%q) has no canyon", i, camera.Alt)
+		}
+		canyon, ok := canyons.Get(id)
+		if !ok {
+			canyons = append(canyons, Canyon{ID: id, Name: id})
+			canyon = &canyons[len(canyons)-1]
+		}
+		if camera.Kind == "status" && canyon.Status.Src == "" {
+			canyon.Status = camera
+			continue
+		}
+		canyon.Cameras = append(canyon.Cameras, camera)
+	}
+
+	*c = canyons
+	return nil
+}
+
+// stampLastModified sets each canyon's LastModified, keeping the previous
+// configuration's timestamp for canyons whose ETag is unchanged. Canyons
+// built in code rather than loaded from a file get their ETag here.
+func (c *Canyons) stampLastModified(previous Canyons, now time.Time) error {
+	for i := range *c {
+		canyon := &(*c)[i]
+		if canyon.ETag == "" {
+			if err := c.setETag(canyon); err != nil {
+				return fmt.Errorf("failed to compute %s ETag: %w", canyon.ID, err)
+			}
+		}
+		if old, ok := previous.Get(canyon.ID); ok && old.ETag == canyon.ETag && !old.LastModified.IsZero() {
+			canyon.LastModified = old.LastModified
+		} else {
+			canyon.LastModified = now
+		}
+	}
 	return nil
 }
 
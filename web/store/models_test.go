@@ -1,6 +1,7 @@
 package store
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
@@ -93,8 +94,8 @@ func TestCanyons_Load(t *testing.T) {
 			} else {
 				require.NoError(t, err)
 				// Verify ETags were computed
-				assert.NotEmpty(t, canyons.LCC.ETag)
-				assert.NotEmpty(t, canyons.BCC.ETag)
+				assert.NotEmpty(t, canyons[0].ETag)
+				assert.NotEmpty(t, canyons[1].ETag)
 			}
 		})
 	}
@@ -131,7 +132,8 @@ func TestCanyons_SetETag(t *testing.T) {
 
 func TestCanyons_String(t *testing.T) {
 	canyons := Canyons{
-		LCC: Canyon{
+		{
+			ID:   "LCC",
 			Name: "Little Cottonwood Canyon",
 			Status: Camera{
 				ID:     "lcc-status",
@@ -144,7 +146,7 @@ func TestCanyons_String(t *testing.T) {
 	}
 
 	// Set ETag
-	err := canyons.setETag(&canyons.LCC)
+	err := canyons.setETag(&canyons[0])
 	require.NoError(t, err)
 
 	// Test String method
@@ -194,8 +196,126 @@ func TestCanyons_LoadFromRealFile(t *testing.T) {
 	require.NoError(t, err)
 
 	// Verify data was loaded correctly
-	assert.Equal(t, "Little Cottonwood Canyon", canyons.LCC.Name)
-	assert.Equal(t, "Big Cottonwood Canyon", canyons.BCC.Name)
-	assert.NotEmpty(t, canyons.LCC.ETag)
-	assert.NotEmpty(t, canyons.BCC.ETag)
+	assert.Equal(t, "Little Cottonwood Canyon", canyons[0].Name)
+	assert.Equal(t, "Big Cottonwood Canyon", canyons[1].Name)
+	assert.NotEmpty(t, canyons[0].ETag)
+	assert.NotEmpty(t, canyons[1].ETag)
+}
+
+func TestCamera_IsEnabled(t *testing.T) {
+	enabled, disabled := true, false
+
+	assert.True(t, (&Camera{}).IsEnabled(), "cameras default to enabled")
+	assert.True(t, (&Camera{Enabled: &enabled}).IsEnabled())
+	assert.False(t, (&Camera{Enabled: &disabled}).IsEnabled())
+
+	var camera Camera
+	require.NoError(t, json.Unmarshal([]byte(`{"src":"http://cam","enabled":false}`), &camera))
+	assert.False(t, camera.IsEnabled())
+
+	canyon := Canyon{Cameras: []Camera{{Src: "a"}, camera, {Src: "c", Enabled: &enabled}}}
+	visible := canyon.EnabledCameras()
+	require.Len(t, visible, 2)
+	assert.Equal(t, "a", visible[0].Src)
+	assert.Equal(t, "c", visible[1].Src)
+}
+
+func TestCanyons_JSONOrderAndIDs(t *testing.T) {
+	data := `{
+		"lcc": {"name": "LCC", "cameras": [{"src": "http://lcc"}]},
+		"bcc": {"name": "BCC", "cameras": []},
+		"afc": {"name": "AFC", "title": "American Fork Canyon", "cameras": [{"src": "http://afc"}]}
+	}`
+
+	var canyons Canyons
+	require.NoError(t, json.Unmarshal([]byte(data), &canyons))
+	require.Len(t, canyons, 3)
+	assert.Equal(t, "LCC", canyons[0].ID)
+	assert.Equal(t, "BCC", canyons[1].ID)
+	assert.Equal(t, "AFC", canyons[2].ID)
+	assert.Equal(t, "/afc", canyons[2].Path())
+	assert.Equal(t, "American Fork Canyon", canyons[2].GetTitle())
+	assert.Equal(t, "LCC", canyons[0].GetTitle(), "title falls back to name")
+
+	afc, ok := canyons.Get("AFC")
+	require.True(t, ok)
+	assert.Equal(t, "http://afc", afc.Cameras[0].Src)
+	_, ok = canyons.Get("PC")
+	assert.False(t, ok)
+
+	// Round-trips to the same keyed shape, in order
+	encoded, err := json.Marshal(canyons)
+	require.NoError(t, err)
+	assert.Regexp(t, `^\{"lcc":\{.*\},"bcc":\{.*\},"afc":\{.*\}\}$`, string(encoded))
+	var decoded Canyons
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, canyons, decoded)
+}
+
+func TestCanyons_UnmarshalErrors(t *testing.T) {
+	var canyons Canyons
+	assert.Error(t, json.Unmarshal([]byte(`[]`), &canyons))
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"lcc": {}, "LCC": {}}`), &canyons), "duplicate canyon ID")
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"": {}}`), &canyons), "must not be empty")
+}
+
+func TestCanyons_LoadShapes(t *testing.T) {
+	load := func(t *testing.T, data string) Canyons {
+		t.Helper()
+		var canyons Canyons
+		require.NoError(t, canyons.Load(fstest.MapFS{"data.json": {Data: []byte(data)}}, "data.json"))
+		return canyons
+	}
+
+	t.Run("keyed", func(t *testing.T) {
+		canyons := load(t, `{
+			"lcc": {"name": "LCC", "status": {"kind": "status", "src": "http://lcc/status"}, "cameras": [{"kind": "img", "src": "http://lcc/1"}]},
+			"bcc": {"name": "BCC", "cameras": [{"kind": "img", "src": "http://bcc/1"}]}
+		}`)
+		require.Len(t, canyons, 2)
+		assert.Equal(t, "LCC", canyons[0].ID)
+		assert.Equal(t, "http://lcc/status", canyons[0].Status.Src)
+		assert.Equal(t, "http://lcc/1", canyons[0].Cameras[0].Src)
+		assert.Equal(t, "BCC", canyons[1].ID)
+	})
+
+	t.Run("flat", func(t *testing.T) {
+		canyons := load(t, `{"cameras": [
+			{"kind": "img", "src": "http://lcc/1", "alt": "LCC 1", "canyon": "lcc"},
+			{"kind": "status", "src": "http://bcc/status", "alt": "BCC Status", "canyon": "BCC"},
+			{"kind": "img", "src": "http://bcc/1", "alt": "BCC 1", "canyon": "BCC"},
+			{"kind": "img", "src": "http://lcc/2", "alt": "LCC 2", "canyon": "LCC"}
+		]}`)
+		require.Len(t, canyons, 2)
+
+		assert.Equal(t, "LCC", canyons[0].ID)
+		assert.Equal(t, "LCC", canyons[0].Name)
+		require.Len(t, canyons[0].Cameras, 2)
+		assert.Equal(t, "http://lcc/1", canyons[0].Cameras[0].Src)
+		assert.Equal(t, "http://lcc/2", canyons[0].Cameras[1].Src)
+		assert.Empty(t, canyons[0].Status.Src)
+		assert.NotEmpty(t, canyons[0].ETag)
+
+		assert.Equal(t, "BCC", canyons[1].ID)
+		assert.Equal(t, "http://bcc/status", canyons[1].Status.Src)
+		require.Len(t, canyons[1].Cameras, 1)
+		assert.Equal(t, "http://bcc/1", canyons[1].Cameras[0].Src)
+
+		s, err := NewStoreWithError(&canyons, StoreOptions{})
+		require.NoError(t, err)
+		_, ok := s.Canyon("BCC")
+		assert.True(t, ok)
+	})
+
+	t.Run("flat camera without canyon", func(t *testing.T) {
+		var canyons Canyons
+		err := canyons.Load(fstest.MapFS{"data.json": {Data: []byte(`{"cameras": [{"src": "http://x"}]}`)}}, "data.json")
+		assert.ErrorContains(t, err, "has no canyon")
+	})
+
+	t.Run("canyon keyed cameras is not flat", func(t *testing.T) {
+		canyons := load(t, `{"cameras": {"name": "Cameras", "cameras": []}}`)
+		require.Len(t, canyons, 1)
+		assert.Equal(t, "CAMERAS", canyons[0].ID)
+	})
 }
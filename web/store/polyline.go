@@ -0,0 +1,44 @@
+package store
+
+import "errors"
+
+// ErrInvalidPolyline is returned for truncated or malformed encoded polylines
+var ErrInvalidPolyline = errors.New("invalid encoded polyline")
+
+// DecodePolyline decodes a Google-encoded polyline (precision 5) into
+// [longitude, latitude] pairs, the coordinate order GeoJSON uses. An empty
+// string decodes to no coordinates.
+func DecodePolyline(encoded string) ([][2]float64, error) {
+	var coordinates [][2]float64
+	var lat, lng int
+	for i := 0; i < len(encoded); {
+		var deltas [2]int
+		for j := range deltas {
+			var result, shift int
+			for {
+				if i >= len(encoded) || shift > 30 {
+					return nil, ErrInvalidPolyline
+				}
+				b := int(encoded[i]) - 63
+				i++
+				if b < 0 || b > 63 {
+					return nil, ErrInvalidPolyline
+				}
+				result |= (b & 0x1f) << shift
+				shift += 5
+				if b < 0x20 {
+					break
+				}
+			}
+			if result&1 != 0 {
+				deltas[j] = ^(result >> 1)
+			} else {
+				deltas[j] = result >> 1
+			}
+		}
+		lat += deltas[0]
+		lng += deltas[1]
+		coordinates = append(coordinates, [2]float64{float64(lng) / 1e5, float64(lat) / 1e5})
+	}
+	return coordinates, nil
+}
@@ -0,0 +1,32 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodePolyline(t *testing.T) {
+	// The example from Google's polyline algorithm documentation
+	coordinates, err := DecodePolyline("_p~iF~ps|U_ulLnnqC_mqNvxq`@")
+	require.NoError(t, err)
+	assert.Equal(t, [][2]float64{
+		{-120.2, 38.5},
+		{-120.95, 40.7},
+		{-126.453, 43.252},
+	}, coordinates)
+
+	t.Run("empty", func(t *testing.T) {
+		coordinates, err := DecodePolyline("")
+		require.NoError(t, err)
+		assert.Empty(t, coordinates)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, encoded := range []string{"_p~iF", "_p~iF~ps|U_", "\x01\x02", "~~~~~~~~~~~~~~~~"} {
+			_, err := DecodePolyline(encoded)
+			assert.ErrorIs(t, err, ErrInvalidPolyline, encoded)
+		}
+	})
+}
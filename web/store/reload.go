@@ -0,0 +1,120 @@
+package store
+
+import (
+	"time"
+
+	"github.com/stefanpenner/lcc-live/web/metrics"
+)
+
+// metricsName is the camera label used by per-camera metrics
+func metricsName(camera *Camera) string {
+	if camera.Alt != "" {
+		return camera.Alt
+	}
+	return camera.ID
+}
+
+// SameConfig reports whether canyons, as freshly loaded, match the store's
+// current configuration, canyon by canyon. Camera positions from the weather
+// stations are part of a canyon's ETag, so canyons are first indexed and
+// given positions just as Reload would. An invalid config is an error.
+func (s *Store) SameConfig(canyons *Canyons) (bool, error) {
+	if _, err := buildIndex(canyons); err != nil {
+		return false, err
+	}
+	if _, err := canyons.applyCoordinates(s.weatherStations()); err != nil {
+		return false, err
+	}
+	current := s.Canyons()
+	if len(*canyons) != len(current) {
+		return false, nil
+	}
+	for i, canyon := range *canyons {
+		if canyon.ID != current[i].ID || canyon.ETag != current[i].ETag {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Reload replaces the store's camera configuration. Cameras that are still
+// configured (same Src, and so the same ID) keep their cached image and
+// timelapse frames; new cameras are fetched on the next FetchImages. Metric
+// series for cameras that were removed or renamed are deleted, and canyons
+// whose data changed get a new LastModified. The new configuration is
+// swapped in at once, so lookups never see a mix of the two. On error the
+// current configuration is left untouched.
+func (s *Store) Reload(canyons *Canyons) error {
+	s.reloading.Add(1)
+	defer s.reloading.Add(-1)
+
+	idx, err := buildIndex(canyons)
+	if err != nil {
+		return err
+	}
+	// Keep the positions UDOT reported; stations only arrive when they change
+	if _, err := canyons.applyCoordinates(s.weatherStations()); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	previousCanyons := *s.canyons
+	s.mu.RUnlock()
+	// Canyons whose data didn't change keep their Last-Modified
+	if err := canyons.stampLastModified(previousCanyons, time.Now()); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	previous := s.entries
+	for _, entry := range idx.entries {
+		if old, exists := s.index[entry.ID]; exists {
+			old.Read(func(old *Entry) {
+				entry.Image = old.Image
+				entry.HTTPHeaders = old.HTTPHeaders
+				entry.FetchedAt = old.FetchedAt
+				entry.AttemptedAt = old.AttemptedAt
+				entry.ResolvedURL = old.ResolvedURL
+				entry.frames = old.frames
+				entry.history = old.history
+				entry.variants = retainVariants(old.variants, entry.Camera.Sources)
+			})
+		}
+		if entry.frames == nil && s.timelapseFrames > 0 && entry.Camera.IsFetchable() {
+			entry.frames = newFrameRing(s.timelapseFrames)
+		}
+	}
+	s.entries = idx.entries
+	s.index = idx.index
+	s.nameIndex = idx.nameIndex
+	s.canyons = canyons
+	s.mu.Unlock()
+
+	// Drop series whose camera/canyon labels no longer exist
+	current := make(map[[2]string]bool, len(idx.entries))
+	for _, entry := range idx.entries {
+		current[[2]string{metricsName(entry.Camera), entry.Camera.Canyon}] = true
+	}
+	for _, entry := range previous {
+		name, canyon := metricsName(entry.Camera), entry.Camera.Canyon
+		if !current[[2]string{name, canyon}] {
+			metrics.DeleteCameraSeries(name, canyon)
+		}
+	}
+
+	for _, canyon := range previousCanyons {
+		if _, exists := canyons.Get(canyon.ID); !exists {
+			metrics.CamerasTotal.DeleteLabelValues(canyon.ID)
+			metrics.CanyonAvailabilityRatio.DeleteLabelValues(canyon.ID)
+		}
+	}
+
+	s.recordCameraCounts()
+	return nil
+}
+
+// IsReloading reports whether a Reload is in progress, so routes can tell a
+// camera that's mid-migration from one that doesn't exist
+func (s *Store) IsReloading() bool {
+	return s.reloading.Load() > 0
+}
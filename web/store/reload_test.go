@@ -0,0 +1,176 @@
+package store
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stefanpenner/lcc-live/web/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hasCameraSeries reports whether collector has a series labeled camera
+func hasCameraSeries(t *testing.T, collector prometheus.Collector, camera string) bool {
+	return hasSeries(t, collector, "camera", camera)
+}
+
+// hasSeries reports whether collector has a series with label name=value
+func hasSeries(t *testing.T, collector prometheus.Collector, name, value string) bool {
+	ch := make(chan prometheus.Metric, 100)
+	go func() {
+		collector.Collect(ch)
+		close(ch)
+	}()
+
+	found := false
+	for m := range ch {
+		var pb dto.Metric
+		require.NoError(t, m.Write(&pb))
+		for _, label := range pb.GetLabel() {
+			if label.GetName() == name && label.GetValue() == value {
+				found = true
+			}
+		}
+	}
+	return found
+}
+
+func TestStore_Reload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("image " + r.URL.Path))
+		}
+	}))
+	defer server.Close()
+
+	kept := Camera{Kind: "webcam", Src: server.URL + "/kept.jpg", Alt: "Reload Kept"}
+	dropped := Camera{Kind: "webcam", Src: server.URL + "/dropped.jpg", Alt: "Reload Dropped"}
+	added := Camera{Kind: "webcam", Src: server.URL + "/added.jpg", Alt: "Reload Added"}
+
+	store := NewStore(&Canyons{
+		{ID: "LCC", Name: "LCC", Cameras: []Camera{kept, dropped}},
+		{ID: "BCC", Name: "BCC"},
+	})
+	store.EnableTimelapse(3)
+	store.FetchImages(context.Background())
+
+	before, exists := store.Get("reload-kept")
+	require.True(t, exists)
+	require.True(t, hasCameraSeries(t, metrics.CameraAvailability, dropped.Alt))
+	require.True(t, hasCameraSeries(t, metrics.CameraFetchTotal, dropped.Alt))
+
+	require.NoError(t, store.Reload(&Canyons{
+		{ID: "LCC", Name: "LCC", Cameras: []Camera{kept, added}},
+		{ID: "BCC", Name: "BCC"},
+	}))
+
+	t.Run("removed camera is gone, along with its series", func(t *testing.T) {
+		_, exists := store.Get("reload-dropped")
+		assert.False(t, exists)
+		assert.False(t, hasCameraSeries(t, metrics.CameraAvailability, dropped.Alt))
+		assert.False(t, hasCameraSeries(t, metrics.CameraFetchTotal, dropped.Alt))
+		assert.True(t, hasCameraSeries(t, metrics.CameraAvailability, kept.Alt))
+	})
+
+	t.Run("kept camera retains its image", func(t *testing.T) {
+		after, exists := store.Get("reload-kept")
+		require.True(t, exists)
+		assert.Equal(t, before.Image.ETag, after.Image.ETag)
+		assert.Equal(t, before.AttemptedAt, after.AttemptedAt)
+		frames, _ := store.Frames("reload-kept")
+		assert.Len(t, frames, 1)
+	})
+
+	t.Run("added camera is fetched on the next cycle", func(t *testing.T) {
+		entry, exists := store.Get("reload-added")
+		require.True(t, exists)
+		assert.Empty(t, entry.Image.Bytes)
+		assert.True(t, entry.AttemptedAt.IsZero())
+
+		store.FetchImages(context.Background())
+		entry, _ = store.Get("reload-added")
+		assert.Equal(t, testImage("image /added.jpg"), entry.Image.Bytes)
+		frames, _ := store.Frames("reload-added")
+		assert.Len(t, frames, 1, "timelapse applies to added cameras")
+	})
+
+	t.Run("invalid config leaves the store untouched", func(t *testing.T) {
+		err := store.Reload(&Canyons{
+			{ID: "LCC", Name: "LCC", Cameras: []Camera{
+				{Src: server.URL + "/a.jpg", Alt: "Same"},
+				{Src: server.URL + "/b.jpg", Alt: "Same"},
+			}},
+			{ID: "BCC", Name: "BCC"},
+		})
+		require.Error(t, err)
+		_, exists := store.Get("reload-kept")
+		assert.True(t, exists)
+	})
+}
+
+func TestStore_Reload_CanyonLastModified(t *testing.T) {
+	canyons := func(lccSrc string) *Canyons {
+		return &Canyons{
+			{ID: "LCC", Name: "LCC", Cameras: []Camera{{Kind: "webcam", Src: lccSrc, Alt: "LCC Cam"}}},
+			{ID: "BCC", Name: "BCC", Cameras: []Camera{{Kind: "webcam", Src: "http://bcc/1.jpg", Alt: "BCC Cam"}}},
+		}
+	}
+	lastModified := func(s *Store, id string) time.Time {
+		canyon, ok := s.Canyon(id)
+		require.True(t, ok)
+		return canyon.LastModified
+	}
+
+	s := NewStore(canyons("http://lcc/1.jpg"))
+	lcc, bcc := lastModified(s, "LCC"), lastModified(s, "BCC")
+	require.False(t, lcc.IsZero())
+	require.False(t, bcc.IsZero())
+
+	time.Sleep(time.Millisecond)
+	require.NoError(t, s.Reload(canyons("http://lcc/1.jpg")))
+	assert.Equal(t, lcc, lastModified(s, "LCC"), "identical reload keeps the timestamp")
+	assert.Equal(t, bcc, lastModified(s, "BCC"))
+
+	require.NoError(t, s.Reload(canyons("http://lcc/2.jpg")))
+	assert.True(t, lastModified(s, "LCC").After(lcc), "changed canyon gets a new timestamp")
+	assert.Equal(t, bcc, lastModified(s, "BCC"), "unchanged canyon keeps its timestamp")
+}
+
+func TestStore_SameConfig(t *testing.T) {
+	const config = `{
+		"lcc": {"id": "LCC", "name": "LCC", "cameras": [
+			{"kind": "webcam", "src": "https://example.com/a.jpg", "alt": "Located", "weatherStationId": 7}
+		]},
+		"bcc": {"id": "BCC", "name": "BCC", "cameras": []}
+	}`
+	load := func(t *testing.T, data string) *Canyons {
+		var canyons Canyons
+		require.NoError(t, canyons.LoadReader(strings.NewReader(data), "test"))
+		return &canyons
+	}
+
+	store := NewStore(load(t, config))
+	latitude, longitude := 40.57, -111.65
+	store.StoreWeatherStationsById([]WeatherStation{{Id: 7, Latitude: &latitude, Longitude: &longitude}})
+	lcc, _ := store.Canyon("LCC")
+	require.NotNil(t, lcc.Cameras[0].Latitude)
+
+	same, err := store.SameConfig(load(t, config))
+	require.NoError(t, err)
+	assert.True(t, same, "positions from the weather stations aren't a config change")
+
+	same, err = store.SameConfig(load(t, strings.Replace(config, `"name": "LCC"`, `"name": "Renamed"`, 1)))
+	require.NoError(t, err)
+	assert.False(t, same)
+
+	same, err = store.SameConfig(load(t, `{"lcc": {"id": "LCC", "name": "LCC", "cameras": []}}`))
+	require.NoError(t, err)
+	assert.False(t, same)
+}
@@ -0,0 +1,44 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxRemoteConfigSize caps a remote camera config; a larger one is
+// truncated and so fails to parse
+const maxRemoteConfigSize = 5 << 20
+
+// LoadCanyonsURL fetches canyon data from an HTTP(S) URL, in either shape
+// Load accepts. An empty config is an error, so a bad deploy of the remote
+// file can't take every camera down.
+func LoadCanyonsURL(ctx context.Context, client *http.Client, url string) (*Canyons, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: status %d", url, resp.StatusCode)
+	}
+
+	canyons := &Canyons{}
+	if err := canyons.LoadReader(io.LimitReader(resp.Body, maxRemoteConfigSize), url); err != nil {
+		return nil, err
+	}
+	if len(*canyons) == 0 {
+		return nil, errors.New(url + " has no canyons")
+	}
+	return canyons, nil
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadCanyonsURL(t *testing.T) {
+	bodies := map[string]string{
+		"/valid.json":   `{"lcc": {"id": "LCC", "name": "Little Cottonwood", "cameras": [{"kind": "img", "src": "https://example.com/a.jpg", "alt": "A"}]}}`,
+		"/flat.json":    `{"cameras": [{"kind": "img", "src": "https://example.com/a.jpg", "alt": "A", "canyon": "LCC"}]}`,
+		"/invalid.json": `{"lcc": `,
+		"/empty.json":   `{}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := bodies[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	for _, path := range []string{"/valid.json", "/flat.json"} {
+		t.Run(path, func(t *testing.T) {
+			canyons, err := LoadCanyonsURL(context.Background(), server.Client(), server.URL+path)
+			require.NoError(t, err)
+			require.Len(t, *canyons, 1)
+			assert.Equal(t, "LCC", (*canyons)[0].ID)
+			assert.NotEmpty(t, (*canyons)[0].ETag)
+		})
+	}
+
+	for path, wantErr := range map[string]string{
+		"/invalid.json": "invalid JSON in " + server.URL + "/invalid.json",
+		"/empty.json":   "has no canyons",
+		"/missing.json": "status 404",
+	} {
+		t.Run(path, func(t *testing.T) {
+			_, err := LoadCanyonsURL(context.Background(), server.Client(), server.URL+path)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), wantErr)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package store
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// sensitiveHeaderWords mark custom header names whose values are redacted
+// in logs
+var sensitiveHeaderWords = []string{"auth", "cookie", "key", "token", "secret", "password", "session"}
+
+// setRequestHeaders sets the User-Agent and the camera's custom Headers on
+// a fetch request. A custom User-Agent overrides the store's.
+func (s *Store) setRequestHeaders(req *http.Request, camera *Camera) {
+	req.Header.Set("User-Agent", s.userAgentFor(camera))
+	for name, value := range camera.Headers {
+		req.Header.Set(name, value)
+	}
+}
+
+// validateHeaders rejects custom headers that can't be sent as-is
+func validateHeaders(headers map[string]string) error {
+	for name, value := range headers {
+		if name == "" || strings.ContainsAny(name, " \t\r\n:") {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("header %q has a line break in its value", name)
+		}
+	}
+	return nil
+}
+
+// redactHeaders formats custom headers for logs, sorted by name, hiding the
+// values of any that look like credentials
+func redactHeaders(headers map[string]string) string {
+	parts := make([]string, 0, len(headers))
+	for _, name := range slices.Sorted(maps.Keys(headers)) {
+		value := headers[name]
+		if isSensitiveHeader(name) {
+			value = "REDACTED"
+		}
+		parts = append(parts, name+"="+value)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// isSensitiveHeader reports whether a header's value may be a credential
+func isSensitiveHeader(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range sensitiveHeaderWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
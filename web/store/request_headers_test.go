@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_FetchImages_CustomHeaders(t *testing.T) {
+	var userAgents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "s3cret" || r.Header.Get("Referer") != "https://udot.example/" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		userAgents = append(userAgents, r.Header.Get("User-Agent"))
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("guarded"))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	headers := map[string]string{"X-Api-Key": "s3cret", "Referer": "https://udot.example/"}
+	store := NewStore(&Canyons{
+		{
+			ID:   "LCC",
+			Name: "LCC",
+			Cameras: []Camera{
+				{Kind: "webcam", Src: server.URL + "/bare.jpg", Alt: "Bare"},
+				{Kind: "webcam", Src: server.URL + "/guarded.jpg", Alt: "Guarded", Headers: headers},
+			},
+		},
+		{ID: "BCC", Name: "BCC"},
+	})
+	store.FetchImages(context.Background())
+
+	bare, _ := store.Get("bare")
+	assert.Empty(t, bare.Image.Bytes, "without the headers the origin refuses")
+	guarded, _ := store.Get("guarded")
+	assert.Equal(t, testImage("guarded"), guarded.Image.Bytes)
+	assert.Equal(t, []string{DefaultUserAgent, DefaultUserAgent}, userAgents, "HEAD and GET keep the default User-Agent")
+
+	data, err := json.Marshal(guarded.Camera)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "s3cret")
+	assert.NotContains(t, string(data), "headers")
+}
+
+func TestCustomHeaders_UserAgentOverride(t *testing.T) {
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("custom agent"))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	store := NewStore(&Canyons{
+		{
+			ID:   "LCC",
+			Name: "LCC",
+			Cameras: []Camera{
+				{Kind: "webcam", Src: server.URL + "/cam.jpg", Alt: "Cam", Headers: map[string]string{"User-Agent": "CameraClient/2"}},
+			},
+		},
+		{ID: "BCC", Name: "BCC"},
+	})
+	store.FetchImages(context.Background())
+	assert.Equal(t, "CameraClient/2", userAgent)
+}
+
+func TestCustomHeaders_Validation(t *testing.T) {
+	for _, headers := range []map[string]string{
+		{"": "empty name"},
+		{"X Api Key": "space in name"},
+		{"X-Api-Key": "line\r\nbreak"},
+	} {
+		_, err := NewStoreWithError(&Canyons{
+			{ID: "LCC", Name: "LCC", Cameras: []Camera{{Kind: "webcam", Src: "https://example.com/a.jpg", Alt: "A", Headers: headers}}},
+		}, StoreOptions{})
+		assert.Error(t, err, "%v", headers)
+	}
+}
+
+func TestRedactHeaders(t *testing.T) {
+	assert.Equal(t,
+		"Authorization=REDACTED, Cookie=REDACTED, Referer=https://udot.example/, X-Api-Key=REDACTED",
+		redactHeaders(map[string]string{
+			"Referer":       "https://udot.example/",
+			"Cookie":        "session=abc",
+			"X-Api-Key":     "s3cret",
+			"Authorization": "Bearer abc",
+		}))
+	assert.Empty(t, redactHeaders(nil))
+}
@@ -1,12 +1,22 @@
 package store
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"image"
+	_ "image/gif" // register decoders for decodeImageHeader
+	"image/jpeg"
+	"image/png"
 	"io"
 	"io/fs"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"regexp"
 	"strconv"
@@ -16,6 +26,7 @@ import (
 	"time"
 
 	"github.com/cespare/xxhash/v2"
+	"golang.org/x/sync/singleflight"
 
 	"github.com/stefanpenner/lcc-live/web/logger"
 	"github.com/stefanpenner/lcc-live/web/metrics"
@@ -23,20 +34,286 @@ import (
 
 const (
 	// HTTP client timeout for fetching images
-	httpClientTimeout = 5 * time.Second
+	defaultHTTPClientTimeout = 5 * time.Second
 	// Timeout for HEAD requests to check image changes
-	headRequestTimeout = 2 * time.Second
+	defaultHeadRequestTimeout = 2 * time.Second
 	// Timeout for GET requests to fetch images
-	getRequestTimeout = 2 * time.Second
+	defaultGetRequestTimeout = 2 * time.Second
 	// Maximum image size to prevent OOM from unexpectedly large responses
-	maxImageSize = 10 * 1024 * 1024 // 10MB
-	// User agent to mimic Chrome browser (helps with servers that block non-browser requests)
-	userAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	defaultMaxImageSize = 10 * 1024 * 1024 // 10MB
+	// Maximum redirects followed per request (e.g. camera -> signed CDN URL)
+	maxRedirects = 5
+	// User agent to mimic Chrome browser, for cameras that opt in via
+	// Camera.BrowserUserAgent (some servers block non-browser requests)
+	browserUserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	// DefaultUserAgent identifies us to camera origins unless overridden
+	DefaultUserAgent = "lcc.live (+https://lcc.live)"
+	// DefaultFetchConcurrency caps how many cameras FetchImages fetches at once
+	DefaultFetchConcurrency = 32
+	// DefaultReadyMaxWait bounds how long a ReadyMinPercent policy can hold
+	// back readiness after the first fetch cycle starts
+	DefaultReadyMaxWait = 30 * time.Second
+	// DefaultInitialFetchTimeout bounds how long the first fetch cycle can
+	// hold back readiness when there's no ReadyMinPercent policy
+	DefaultInitialFetchTimeout = 15 * time.Second
 )
 
+// StoreOptions tunes how a Store fetches cameras, e.g. longer timeouts for
+// cameras behind high-latency links. Zero fields use the defaults.
+type StoreOptions struct {
+	// HTTPClientTimeout bounds each request, including following redirects
+	HTTPClientTimeout time.Duration
+	// HeadRequestTimeout bounds the HEAD request that checks for changes
+	HeadRequestTimeout time.Duration
+	// GetRequestTimeout bounds the GET request that fetches the image
+	GetRequestTimeout time.Duration
+	// MaxImageSize caps the bytes read per image
+	MaxImageSize int64
+	// FetchConcurrency caps how many cameras are fetched at once
+	FetchConcurrency int
+	// ReadyMinPercent is the share of fetchable cameras (0-100) that must
+	// have an image before the store reports ready; 0 means ready after the
+	// first fetch cycle however it went
+	ReadyMinPercent int
+	// ReadyMaxWait is how long after the first fetch cycle starts the store
+	// goes ready anyway, so an outage can't block it forever
+	ReadyMaxWait time.Duration
+	// InitialFetchTimeout is how long after the first fetch cycle starts the
+	// store goes ready even if the cycle hasn't finished, so a few hanging
+	// origins can't hold up a cold start. Cameras that finish later still
+	// fill in. With a ReadyMinPercent policy, ReadyMaxWait applies instead.
+	InitialFetchTimeout time.Duration
+	// VersionedETags adds a version, bumped on every change, to image ETags
+	// ("<hash>-<version>"), so identical bytes from different cameras, or
+	// a frame that comes back, never reuse an ETag
+	VersionedETags bool
+}
+
+// DefaultStoreOptions returns the options used for any field left unset
+func DefaultStoreOptions() StoreOptions {
+	return StoreOptions{
+		HTTPClientTimeout:   defaultHTTPClientTimeout,
+		HeadRequestTimeout:  defaultHeadRequestTimeout,
+		GetRequestTimeout:   defaultGetRequestTimeout,
+		MaxImageSize:        defaultMaxImageSize,
+		FetchConcurrency:    DefaultFetchConcurrency,
+		ReadyMaxWait:        DefaultReadyMaxWait,
+		InitialFetchTimeout: DefaultInitialFetchTimeout,
+	}
+}
+
+// withDefaults fills in unset (or invalid) fields from DefaultStoreOptions
+func (o StoreOptions) withDefaults() StoreOptions {
+	defaults := DefaultStoreOptions()
+	if o.HTTPClientTimeout <= 0 {
+		o.HTTPClientTimeout = defaults.HTTPClientTimeout
+	}
+	if o.HeadRequestTimeout <= 0 {
+		o.HeadRequestTimeout = defaults.HeadRequestTimeout
+	}
+	if o.GetRequestTimeout <= 0 {
+		o.GetRequestTimeout = defaults.GetRequestTimeout
+	}
+	if o.MaxImageSize <= 0 {
+		o.MaxImageSize = defaults.MaxImageSize
+	}
+	if o.FetchConcurrency <= 0 {
+		o.FetchConcurrency = defaults.FetchConcurrency
+	}
+	o.ReadyMinPercent = min(max(o.ReadyMinPercent, 0), 100)
+	if o.ReadyMaxWait <= 0 {
+		o.ReadyMaxWait = defaults.ReadyMaxWait
+	}
+	if o.InitialFetchTimeout <= 0 {
+		o.InitialFetchTimeout = defaults.InitialFetchTimeout
+	}
+	return o
+}
+
+// errTooManyRedirects is returned by the client's CheckRedirect once
+// maxRedirects is exceeded, usually indicating a redirect loop
+var errTooManyRedirects = errors.New("too many redirects")
+
+// fetchErrorReason classifies a client.Do error for metrics
+func fetchErrorReason(err error) string {
+	if errors.Is(err, errTooManyRedirects) {
+		return "redirect_loop"
+	}
+	return "connection"
+}
+
+// logFetchError logs why a camera fetch failed, at debug level so a flapping
+// origin doesn't flood the logs
+func logFetchError(camera *Camera, url, origin, reason string, err error) {
+	if len(camera.Headers) > 0 {
+		logger.Debug("fetch failed: camera=%q canyon=%s origin=%s url=%s headers=[%s] reason=%s error=%v", camera.Alt, camera.Canyon, origin, url, redactHeaders(camera.Headers), reason, err)
+		return
+	}
+	logger.Debug("fetch failed: camera=%q canyon=%s origin=%s url=%s reason=%s error=%v", camera.Alt, camera.Canyon, origin, url, reason, err)
+}
+
+// decodeImageHeader cheaply validates image bytes by decoding just the
+// header, returning the detected Content-Type and pixel size. An error means
+// the bytes aren't an image we can serve (e.g. an HTML error page or a
+// truncated frame).
+func decodeImageHeader(b []byte) (contentType string, width, height int, err error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return "", 0, 0, err
+	}
+	return "image/" + format, config.Width, config.Height, nil
+}
+
+// readMJPEGFrame reads the first part of a multipart MJPEG stream, using the
+// boundary from the response's Content-Type, without buffering the rest
+func readMJPEGFrame(resp *http.Response, maxSize int64) ([]byte, error) {
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	boundary := params["boundary"]
+	if !strings.HasPrefix(mediaType, "multipart/") || boundary == "" {
+		return nil, fmt.Errorf("not a multipart stream: %q", mediaType)
+	}
+	part, err := multipart.NewReader(resp.Body, boundary).NextPart()
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(io.LimitReader(part, maxSize))
+}
+
+// readImageBody reads up to maxSize bytes of the response body, inflating it
+// first when the origin sent it gzip-encoded without our asking (the
+// transport only decodes gzip it negotiated itself). The limit applies to the
+// decompressed bytes.
+func readImageBody(resp *http.Response, maxSize int64) ([]byte, error) {
+	if resp.Uncompressed || !strings.EqualFold(strings.TrimSpace(resp.Header.Get("Content-Encoding")), "gzip") {
+		return io.ReadAll(io.LimitReader(resp.Body, maxSize))
+	}
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = gz.Close()
+	}()
+	return io.ReadAll(io.LimitReader(gz, maxSize))
+}
+
+// pngToJPEG re-encodes a PNG as a JPEG at the given quality
+func pngToJPEG(b []byte, quality int) ([]byte, error) {
+	img, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// TransportOptions tunes connection pooling for camera fetches. Zero values
+// use the defaults.
+type TransportOptions struct {
+	// Idle keep-alive connections kept per origin; origins usually serve
+	// several cameras, fetched concurrently every sync
+	MaxIdleConnsPerHost int
+	// How long an idle connection is kept before being closed; should
+	// comfortably exceed the sync interval so connections are reused
+	IdleConnTimeout time.Duration
+	// VerifyTLS checks camera certificates, except for cameras marked
+	// InsecureTLS. Off by default, skipping verification for every camera.
+	VerifyTLS bool
+	// RootCAs verifies certificates when VerifyTLS is set (nil uses the
+	// system pool)
+	RootCAs *x509.CertPool
+}
+
+const (
+	defaultMaxIdleConnsPerHost = 8
+	defaultIdleConnTimeout     = 90 * time.Second
+)
+
+// newTransport builds the transport used for camera fetches, with its idle
+// pool sized for the number of distinct origins
+func newTransport(origins int, opts TransportOptions) *http.Transport {
+	if opts.MaxIdleConnsPerHost <= 0 {
+		opts.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	}
+	if opts.IdleConnTimeout <= 0 {
+		opts.IdleConnTimeout = defaultIdleConnTimeout
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	transport.MaxIdleConns = max(origins, 1) * opts.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = opts.IdleConnTimeout
+	// A custom TLSClientConfig disables HTTP/2 unless explicitly requested
+	transport.ForceAttemptHTTP2 = true
+	// Handle camera servers with self-signed or non-standard certificates
+	transport.TLSClientConfig = &tls.Config{
+		InsecureSkipVerify: true, //nolint:gosec // G402: Required for external camera servers with self-signed certs
+	}
+	return transport
+}
+
+// newCameraTransport returns the transport for camera fetches: newTransport
+// as is, or with VerifyTLS one that picks a verifying or non-verifying
+// transport per request, by the camera's InsecureTLS
+func newCameraTransport(origins int, opts TransportOptions) http.RoundTripper {
+	insecure := newTransport(origins, opts)
+	if !opts.VerifyTLS {
+		return insecure
+	}
+	secure := insecure.Clone()
+	secure.TLSClientConfig = &tls.Config{RootCAs: opts.RootCAs}
+	return &tlsSelectingTransport{secure: secure, insecure: insecure}
+}
+
+// insecureTLSKey marks a request's context as allowed to skip certificate
+// verification
+type insecureTLSKey struct{}
+
+// withInsecureTLS records on ctx whether its requests may skip certificate
+// verification. The context follows redirects, so they're judged the same.
+func withInsecureTLS(ctx context.Context, insecure bool) context.Context {
+	if !insecure {
+		return ctx
+	}
+	return context.WithValue(ctx, insecureTLSKey{}, true)
+}
+
+// tlsSelectingTransport routes requests marked by withInsecureTLS to a
+// transport that skips certificate verification, and the rest to one that
+// verifies
+type tlsSelectingTransport struct {
+	secure, insecure *http.Transport
+}
+
+func (t *tlsSelectingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if insecure, _ := req.Context().Value(insecureTLSKey{}).(bool); insecure {
+		return t.insecure.RoundTrip(req)
+	}
+	return t.secure.RoundTrip(req)
+}
+
+// countOrigins returns the number of distinct hosts cameras are fetched from
+func countOrigins(entries []*Entry) int {
+	origins := make(map[string]struct{})
+	for _, entry := range entries {
+		origins[metrics.ExtractOrigin(entry.Camera.Src)] = struct{}{}
+	}
+	return len(origins)
+}
+
 // Store manages camera images and provides concurrent access
 type Store struct {
 	client                     *http.Client
+	userAgent                  string
+	options                    StoreOptions
+	pngJPEGQuality             int // re-encode PNG frames as JPEG at this quality (0 disables)
+	timelapseFrames            int
 	canyons                    *Canyons
 	index                      map[string]*Entry // Maps camera ID -> Entry
 	nameIndex                  map[string]*Entry // Maps camera slug -> Entry
@@ -44,14 +321,21 @@ type Store struct {
 	mu                         sync.RWMutex
 	imagesReady                sync.WaitGroup
 	isWaitingOnFirstImageReady atomic.Bool
-	syncCallback               func(duration time.Duration, changed, unchanged, errors int)
-	syncCallbackMu             sync.Mutex
+	readyDeadlineOnce          sync.Once
+	reloading                  atomic.Int32 // Reload calls in progress
+	syncing                    atomic.Bool  // a FetchImages cycle is running or claimed by Sync
+	syncSubscribers            []syncSubscriber
+	nextSubscriberID           int
+	syncCallbackUnsubscribe    func()
+	syncSubscribersMu          sync.Mutex
 	roadConditions             map[string][]RoadCondition // Maps canyon -> road conditions
 	roadConditionsMu           sync.RWMutex
 	weatherStationsById        map[int]*WeatherStation // Maps station Id -> weather station
 	weatherStationsMu          sync.RWMutex
 	events                     map[string][]Event // Maps canyon -> events
 	eventsMu                   sync.RWMutex
+	fetchGroup                 singleflight.Group // dedupes concurrent fetch cycles and FetchOne calls
+	etagVersion                atomic.Uint64      // last version handed out by nextImageETag
 }
 
 // Entry represents a single camera's cached data
@@ -60,7 +344,13 @@ type Entry struct {
 	Image       *Image
 	HTTPHeaders *HTTPHeaders
 	FetchedAt   time.Time
+	AttemptedAt time.Time // when a fetch last reached the origin; zero if never
 	ID          string
+	ResolvedURL string     // final URL after redirects, for debugging
+	frames      *frameRing // nil unless timelapse is enabled
+	history     []AvailabilityTransition
+	isStatus    bool // a canyon's Status camera; set at creation, never changed
+	variants    []ImageVariant
 	mu          sync.RWMutex
 }
 
@@ -70,8 +360,15 @@ type EntrySnapshot struct {
 	Image       *Image
 	HTTPHeaders *HTTPHeaders
 	FetchedAt   time.Time
+	// AttemptedAt is when a fetch last reached the origin, successful or
+	// not; zero if the camera has never been tried
+	AttemptedAt time.Time
 	ID          string
 	ETag        string
+	ResolvedURL string
+	// Variants are the latest images from the camera's Sources, in
+	// configuration order; sources not yet fetched are missing
+	Variants []ImageVariant
 }
 
 // ShallowSnapshot returns a shallow snapshot of the entry's current state
@@ -103,10 +400,14 @@ func (e *Entry) ShallowSnapshot() EntrySnapshot {
 		Image:       e.Image,
 		HTTPHeaders: e.HTTPHeaders,
 		FetchedAt:   e.FetchedAt,
+		AttemptedAt: e.AttemptedAt,
 		ID:          e.ID,
+		ResolvedURL: e.ResolvedURL,
+		Variants:    e.variants,
 	}
 }
 
+// Read calls fn holding the entry's read lock; fn must not modify it
 func (e *Entry) Read(fn func(*Entry)) {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
@@ -114,6 +415,8 @@ func (e *Entry) Read(fn func(*Entry)) {
 	fn(e)
 }
 
+// Write calls fn holding the entry's write lock, excluding readers and
+// other writers
 func (e *Entry) Write(fn func(*Entry)) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
@@ -136,106 +439,168 @@ func (s *Store) Write(fn func(*Store)) {
 }
 
 // NewStoreFromFile creates a new store by loading canyon data from a file
-func NewStoreFromFile(f fs.FS, filepath string) (*Store, error) {
+func NewStoreFromFile(f fs.FS, filepath string, opts StoreOptions) (*Store, error) {
 	canyons := &Canyons{}
 	err := canyons.Load(f, filepath)
 	if err != nil {
 		return nil, err
 	}
 
-	return NewStore(canyons), err
+	return NewStoreWithError(canyons, opts)
 }
 
-// NewStore creates a new store with the given canyons configuration
+// NewStore creates a new store with the given canyons configuration and the
+// default options, which suit most cameras. It panics if the configuration
+// is invalid; use NewStoreWithError to handle that case.
 func NewStore(canyons *Canyons) *Store {
-	// store initialization doesn't need to be threadsafe, as the store is only
-	// accessed from a single thread during intializations.
-	//
-	// Only subsequent access must be
-	//
+	return NewStoreWithOptions(canyons, StoreOptions{})
+}
+
+// NewStoreWithOptions is NewStore with options; unset ones use the defaults
+func NewStoreWithOptions(canyons *Canyons, opts StoreOptions) *Store {
+	store, err := NewStoreWithError(canyons, opts)
+	if err != nil {
+		panic(err.Error())
+	}
+	return store
+}
+
+// storeIndex holds a store's entries and the indexes over them. It's built
+// as a unit so Reload can swap all three at once.
+type storeIndex struct {
+	entries   []*Entry
+	index     map[string]*Entry // Maps camera ID -> Entry
+	nameIndex map[string]*Entry // Maps camera slug -> Entry
+}
+
+// buildIndex creates an entry for every camera in canyons, returning an error
+// if a camera has an unknown kind, two cameras share a Src or resolve to the
+// same slug, or a camera's name produces an empty slug
+func buildIndex(canyons *Canyons) (*storeIndex, error) {
 	index := make(map[string]*Entry)
 	nameIndex := make(map[string]*Entry)
 	entries := []*Entry{}
 
-	createEntry := func(camera *Camera) {
+	createEntry := func(camera *Camera, isStatus bool) error {
 		camera.ID = base64.StdEncoding.EncodeToString([]byte(camera.Src))
+		// Catch config typos (e.g. "webca") that would otherwise be fetched
+		// as a plain image
+		if !knownKinds[camera.Kind] {
+			return fmt.Errorf("camera '%s' (ID: %s) has unknown kind %q", camera.Alt, camera.ID, camera.Kind)
+		}
+		if err := validateHeaders(camera.Headers); err != nil {
+			return fmt.Errorf("camera '%s' (ID: %s): %w", camera.Alt, camera.ID, err)
+		}
+		// IDs derive from Src, so a repeated Src would shadow the first
+		// camera in the index while both stayed in entries
+		if existingEntry, exists := index[camera.ID]; exists {
+			return fmt.Errorf("duplicate camera src: cameras '%s' and '%s' both use %s",
+				existingEntry.Camera.Alt, camera.Alt, camera.Src)
+		}
 		entry := &Entry{
 			Camera:      camera,
 			Image:       &Image{},
 			HTTPHeaders: &HTTPHeaders{},
 			ID:          camera.ID,
+			isStatus:    isStatus,
 			mu:          sync.RWMutex{},
 		}
 		index[camera.ID] = entry
 
-		// Also index by slug if camera has a name
-		if camera.Alt != "" {
-			slug := slugify(camera.Alt)
+		// Also index by slug if camera has an explicit slug or a name
+		if camera.Slug != "" || camera.Alt != "" {
+			slug := camera.GetSlug()
 			if slug == "" {
 				// Empty slug is invalid - camera name slugifies to nothing
-				panic(fmt.Sprintf("camera '%s' (ID: %s) has name that produces empty slug", camera.Alt, camera.ID))
+				return fmt.Errorf("camera '%s' (ID: %s) has name that produces empty slug", camera.Alt, camera.ID)
 			}
 
 			// Check for slug collisions
 			if existingEntry, exists := nameIndex[slug]; exists {
 				// Slug collision detected
 				existingCamera := existingEntry.Camera
-				panic(fmt.Sprintf("slug collision: cameras '%s' (ID: %s) and '%s' (ID: %s) both slugify to '%s'",
-					existingCamera.Alt, existingCamera.ID, camera.Alt, camera.ID, slug))
+				return fmt.Errorf("slug collision: cameras '%s' (ID: %s) and '%s' (ID: %s) both slugify to '%s'",
+					existingCamera.Alt, existingCamera.ID, camera.Alt, camera.ID, slug)
 			}
 
 			// Check if slug collides with any other camera's ID
 			if existingEntry, idCollision := index[slug]; idCollision && existingEntry != entry {
 				existingCamera := existingEntry.Camera
-				panic(fmt.Sprintf("slug collision: camera '%s' (ID: %s) has slug '%s' that matches another camera's ID (camera '%s', ID: %s)",
-					camera.Alt, camera.ID, slug, existingCamera.Alt, existingCamera.ID))
+				return fmt.Errorf("slug collision: camera '%s' (ID: %s) has slug '%s' that matches another camera's ID (camera '%s', ID: %s)",
+					camera.Alt, camera.ID, slug, existingCamera.Alt, existingCamera.ID)
 			}
 
 			nameIndex[slug] = entry
 		}
 
 		entries = append(entries, entry)
+		return nil
 	}
 
-	// Process status cameras if present
-	if canyons.LCC.Status.Src != "" {
-		canyons.LCC.Status.Canyon = "LCC" //nolint:goconst // Canyon name used for clarity
-		createEntry(&canyons.LCC.Status)
+	// Status cameras first, then regular cameras, each in canyon order
+	for i := range *canyons {
+		canyon := &(*canyons)[i]
+		if canyon.Status.Src != "" {
+			canyon.Status.Canyon = canyon.ID
+			if err := createEntry(&canyon.Status, true); err != nil {
+				return nil, err
+			}
+		}
 	}
-	if canyons.BCC.Status.Src != "" {
-		canyons.BCC.Status.Canyon = "BCC" //nolint:goconst // Canyon name used for clarity
-		createEntry(&canyons.BCC.Status)
+	for i := range *canyons {
+		canyon := &(*canyons)[i]
+		for j := range canyon.Cameras {
+			canyon.Cameras[j].Canyon = canyon.ID
+			if err := createEntry(&canyon.Cameras[j], false); err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	// Process regular cameras
-	for i := range canyons.LCC.Cameras {
-		canyons.LCC.Cameras[i].Canyon = "LCC" //nolint:goconst // Canyon name used for clarity
-		createEntry(&canyons.LCC.Cameras[i])
+	return &storeIndex{entries: entries, index: index, nameIndex: nameIndex}, nil
+}
+
+// NewStoreWithError creates a new store with the given canyons configuration
+// and options (unset ones use the defaults), returning an error if a camera
+// has an unknown kind, two cameras share a Src or resolve to the same slug,
+// or a camera's name produces an empty slug
+func NewStoreWithError(canyons *Canyons, opts StoreOptions) (*Store, error) {
+	// store initialization doesn't need to be threadsafe, as the store is only
+	// accessed from a single thread during intializations.
+	//
+	// Only subsequent access must be
+	//
+	idx, err := buildIndex(canyons)
+	if err != nil {
+		return nil, err
 	}
-	for i := range canyons.BCC.Cameras {
-		canyons.BCC.Cameras[i].Canyon = "BCC" //nolint:goconst // Canyon name used for clarity
-		createEntry(&canyons.BCC.Cameras[i])
+	if err := canyons.stampLastModified(nil, time.Now()); err != nil {
+		return nil, err
 	}
 
-	// Create HTTP client with custom TLS config to handle camera servers
-	// with self-signed or non-standard certificates
-	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: true, //nolint:gosec // G402: Required for external camera servers with self-signed certs
-		},
-	}
+	options := opts.withDefaults()
+
+	transport := newCameraTransport(countOrigins(idx.entries), TransportOptions{})
 
 	store := &Store{
-		entries:            entries,
-		index:              index,
-		nameIndex:          nameIndex,
-		canyons:            canyons,
+		entries:             idx.entries,
+		index:               idx.index,
+		nameIndex:           idx.nameIndex,
+		canyons:             canyons,
+		userAgent:           DefaultUserAgent,
+		options:             options,
 		roadConditions:      make(map[string][]RoadCondition),
 		weatherStationsById: make(map[int]*WeatherStation),
 		events:              make(map[string][]Event),
 		client: &http.Client{
-			Timeout:   httpClientTimeout,
+			Timeout:   options.HTTPClientTimeout,
 			Transport: transport,
+			CheckRedirect: func(_ *http.Request, via []*http.Request) error {
+				if len(via) > maxRedirects {
+					return errTooManyRedirects
+				}
+				return nil
+			},
 		},
 	}
 
@@ -243,266 +608,532 @@ func NewStore(canyons *Canyons) *Store {
 	store.isWaitingOnFirstImageReady.Store(true)
 
 	// Set metrics
-	metrics.StoreEntriesTotal.Set(float64(len(entries)))
-	metrics.CamerasTotal.WithLabelValues("LCC").Set(float64(len(canyons.LCC.Cameras)))
-	metrics.CamerasTotal.WithLabelValues("BCC").Set(float64(len(canyons.BCC.Cameras)))
+	store.recordCameraCounts()
 	metrics.ImagesReady.Set(0)
 
-	return store
+	return store, nil
+}
+
+// recordCameraCounts publishes the number of configured cameras
+func (s *Store) recordCameraCounts() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	metrics.StoreEntriesTotal.Set(float64(len(s.entries)))
+	for _, canyon := range *s.canyons {
+		metrics.CamerasTotal.WithLabelValues(canyon.ID).Set(float64(len(canyon.Cameras)))
+	}
+}
+
+// Canyon returns the canyon with the given ID (e.g. "LCC")
+func (s *Store) Canyon(id string) (*Canyon, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.canyons.Get(id)
 }
 
-// Canyon returns the canyon with the given name
-func (s *Store) Canyon(canyon string) *Canyon {
-	switch canyon {
-	case "LCC":
-		return &s.canyons.LCC
-	case "BCC":
-		return &s.canyons.BCC
-	default:
-		panic("invalid canyon: must be either 'LCC' or 'BCC'")
+// Canyons returns all canyons, in configuration order
+func (s *Store) Canyons() Canyons {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return *s.canyons
+}
+
+// DataHash identifies the loaded camera dataset: a hash of every canyon's
+// ETag, in order. It changes whenever a Reload (or a camera position update)
+// changes any canyon, and is stable across reloads of identical data.
+func (s *Store) DataHash() string {
+	digest := xxhash.New()
+	for _, canyon := range s.Canyons() {
+		digest.WriteString(canyon.ID)
+		digest.WriteString("\x00")
+		digest.WriteString(canyon.ETag)
+		digest.WriteString("\x00")
 	}
+	return strconv.FormatUint(digest.Sum64(), 16)
 }
 
-// FetchImages fetches images for all cameras concurrently
-// TODO: this should return a more detailed summary of what changed, so that we can:
-// 1. provide a /status endpoint
-// 2. provide "camera down" or "camera live" UI
-// 3. provide image updates via push of some sort
+// FetchImages fetches images for all cameras concurrently, then notifies
+// OnSync subscribers with a SyncResult describing what changed. Overlapping
+// calls (e.g. a manual refresh racing the ticker) share the cycle already in
+// progress rather than starting another. Every caller returns once the
+// shared cycle, which runs under the first caller's context, has finished.
 func (s *Store) FetchImages(ctx context.Context) {
+	s.fetchGroup.Do(fetchCycleKey, func() (any, error) {
+		s.syncing.Store(true)
+		defer s.syncing.Store(false)
+		return s.fetchCycle(ctx), nil
+	})
+}
+
+// fetchCycleKey is the single-flight key for a FetchImages cycle. Camera IDs
+// are base64, so it can't collide with a FetchOne key.
+const fetchCycleKey = "*cycle*"
+
+// fetchCycle runs one FetchImages cycle
+func (s *Store) fetchCycle(ctx context.Context) SyncResult {
 	// Start timing for metrics
 	timer := metrics.ImageFetchDuration
 	startTime := time.Now()
 
+	s.startReadyDeadline()
+
 	var wg sync.WaitGroup
-	var (
-		changedCount   int32
-		errorCount     int32
-		unchangedCount int32
-	)
+	entries := s.allEntries()
+	// Each fetch writes only its own slot, so no locking is needed
+	results := make([]CameraSyncResult, len(entries))
+
+	// Feed fetchable entries to a bounded pool of workers, so the number of
+	// concurrent fetches (and goroutines) doesn't grow with the camera count.
+	// The queue depth counts entries no worker has picked up yet.
+	queued := 0
+	for _, entry := range entries {
+		if entry.Camera.IsFetchable() && entry.Camera.IsEnabled() {
+			queued++
+		}
+	}
+	metrics.FetchQueueDepth.Add(float64(queued))
+
+	jobs := make(chan int)
+	workers := min(s.options.FetchConcurrency, len(entries))
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				metrics.FetchQueueDepth.Dec()
+				metrics.FetchWorkersBusy.Inc()
+				s.fetchEntry(ctx, entries[i], &results[i])
+				metrics.FetchWorkersBusy.Dec()
+			}
+		}()
+	}
 
-	for i := range s.entries {
-		entry := s.entries[i]
+	for i := range entries {
+		entry := entries[i]
 
-		if entry.Camera.Kind == "iframe" {
+		if !entry.Camera.IsFetchable() || !entry.Camera.IsEnabled() {
 			continue
 		}
-		wg.Add(1)
 
-		go func(entry *Entry) {
-			defer wg.Done()
+		results[i] = CameraSyncResult{
+			ID:     entry.ID,
+			Name:   entry.Camera.Alt,
+			Canyon: entry.Camera.Canyon,
+			Status: SyncCancelled,
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	s.checkReadiness()
+	duration := time.Since(startTime)
+	s.recordAvailability(entries, results, startTime.Add(duration))
 
-			// Track concurrent fetches
-			metrics.ConcurrentFetches.Inc()
-			defer metrics.ConcurrentFetches.Dec()
+	result := newSyncResult(duration, results)
+	changedCount, unchangedCount, errorCount := result.Changed, result.Unchanged, result.Errors
+	recordCanyonAvailability(result)
 
-			// Check if context is already cancelled before starting work
-			if ctx.Err() != nil {
-				return
-			}
+	// Record metrics
+	timer.Observe(duration.Seconds())
+	metrics.StoreFetchCyclesTotal.Inc()
+	metrics.ImageFetchTotal.WithLabelValues("success").Add(float64(changedCount))
+	metrics.ImageFetchTotal.WithLabelValues("unchanged").Add(float64(unchangedCount))
+	metrics.ImageFetchTotal.WithLabelValues("error").Add(float64(errorCount))
+	metrics.FetchCycleDurationSeconds.Set(duration.Seconds())
 
-			// lock while reading
-			// let's simply copy the structs we need for the long-lived function,
-			// then unlock immediately after copying when we update, we will relock
-			var src string
-			var headers HTTPHeaders
-			var camera *Camera
-
-			entry.Read(func(entry *Entry) {
-				src = entry.Camera.Src // Copy
-				camera = entry.Camera  // Copy pointer (safe to use for reading)
-				// TODO: explore option of an explicit copy via Copy() or Snapshot(), vs the current implicit approach
-				headers = *entry.HTTPHeaders // Copy
-			})
+	// Update memory usage metrics
+	metrics.RecordMemoryUsage()
 
-			// Extract origin and camera info for metrics
-			origin := metrics.ExtractOrigin(src)
-			cameraName := camera.Alt
-			if cameraName == "" {
-				cameraName = camera.ID
-			}
-			canyon := camera.Canyon
-
-			// Start timing for per-camera metrics
-			cameraStartTime := time.Now()
-
-			headCtx, cancel := context.WithTimeout(ctx, headRequestTimeout)
-			defer cancel()
-			headReq, err := http.NewRequestWithContext(headCtx, "HEAD", src, nil)
-			if err != nil {
-				atomic.AddInt32(&errorCount, 1)
-				metrics.ImageFetchErrorsTotal.WithLabelValues("head_request").Inc()
-				metrics.CameraFetchTotal.WithLabelValues(cameraName, canyon, "error").Inc()
-				metrics.OriginFetchTotal.WithLabelValues(origin, "error").Inc()
-				metrics.OriginErrorsByType.WithLabelValues(origin, "head_request").Inc()
-				metrics.CameraAvailability.WithLabelValues(cameraName, canyon).Set(0)
-				return
-			}
+	// Print summary
+	summary := logger.FetchSummary{
+		Duration:  duration,
+		Changed:   changedCount,
+		Unchanged: unchangedCount,
+		Errors:    errorCount,
+		Total:     changedCount + unchangedCount + errorCount,
+	}
+	summary.Print()
 
-			// Set User-Agent to mimic Chrome browser
-			headReq.Header.Set("User-Agent", userAgent)
+	s.notifySync(result)
+	return result
+}
 
-			headResp, err := s.client.Do(headReq)
-			if err != nil {
-				// Check if error is due to context cancellation
-				if ctx.Err() != nil {
-					return
-				}
-				atomic.AddInt32(&errorCount, 1)
-				metrics.CameraFetchTotal.WithLabelValues(cameraName, canyon, "error").Inc()
-				metrics.OriginFetchTotal.WithLabelValues(origin, "error").Inc()
-				metrics.OriginErrorsByType.WithLabelValues(origin, "connection").Inc()
-				metrics.CameraAvailability.WithLabelValues(cameraName, canyon).Set(0)
-				return
-			}
+// fetchEntry fetches a single camera's image, recording the outcome in result
+func (s *Store) fetchEntry(ctx context.Context, entry *Entry, result *CameraSyncResult) {
+	// Track concurrent fetches
+	metrics.ConcurrentFetches.Inc()
+	defer metrics.ConcurrentFetches.Dec()
 
-			_ = headResp.Body.Close()
+	// Check if context is already cancelled before starting work
+	if ctx.Err() != nil {
+		return
+	}
 
-			newETag := headResp.Header.Get("ETag")
+	// lock while reading
+	// let's simply copy the structs we need for the long-lived function,
+	// then unlock immediately after copying when we update, we will relock
+	var src string
+	var headers HTTPHeaders
+	var camera *Camera
+	var current *Image
+	var timelapse bool
+
+	entry.Read(func(entry *Entry) {
+		src = entry.Camera.Src // Copy
+		camera = entry.Camera  // Copy pointer (safe to use for reading)
+		// TODO: explore option of an explicit copy via Copy() or Snapshot(), vs the current implicit approach
+		headers = *entry.HTTPHeaders // Copy
+		current = entry.Image        // Images are immutable once stored
+		timelapse = entry.frames != nil
+	})
 
-			if newETag != "" && newETag == headers.ETag {
-				atomic.AddInt32(&unchangedCount, 1)
-				// Record metrics for unchanged image
-				cameraDuration := time.Since(cameraStartTime).Seconds()
-				metrics.CameraFetchDuration.WithLabelValues(cameraName, canyon).Observe(cameraDuration)
-				metrics.CameraFetchTotal.WithLabelValues(cameraName, canyon, "unchanged").Inc()
-				metrics.OriginFetchTotal.WithLabelValues(origin, "success").Inc()
-				metrics.OriginFetchDuration.WithLabelValues(origin).Observe(cameraDuration)
-				metrics.CameraAvailability.WithLabelValues(cameraName, canyon).Set(1)
-				return
-			}
+	// Extract origin and camera info for metrics
+	origin := metrics.ExtractOrigin(src)
+	cameraName := metricsName(camera)
+	canyon := camera.Canyon
+	// Status cameras also report on their own gauge, so a down canyon
+	// status feed can be alerted on separately
+	setAvailability := func(up float64) {
+		metrics.CameraAvailability.WithLabelValues(cameraName, canyon).Set(up)
+		if entry.isStatus {
+			metrics.StatusCameraAvailability.WithLabelValues(cameraName, canyon).Set(up)
+		}
+	}
+	ctx = withInsecureTLS(ctx, camera.InsecureTLS)
 
-			getCtx, cancel := context.WithTimeout(ctx, getRequestTimeout)
-			defer cancel()
-			getReq, err := http.NewRequestWithContext(getCtx, "GET", src, nil)
-			if err != nil {
-				atomic.AddInt32(&errorCount, 1)
-				metrics.CameraFetchTotal.WithLabelValues(cameraName, canyon, "error").Inc()
-				metrics.OriginFetchTotal.WithLabelValues(origin, "error").Inc()
-				metrics.OriginErrorsByType.WithLabelValues(origin, "get_request").Inc()
-				metrics.CameraAvailability.WithLabelValues(cameraName, canyon).Set(0)
-				return
-			}
+	// Start timing for per-camera metrics
+	cameraStartTime := time.Now()
 
-			// Set User-Agent to mimic Chrome browser
-			getReq.Header.Set("User-Agent", userAgent)
+	headCtx, cancel := context.WithTimeout(ctx, s.options.HeadRequestTimeout)
+	defer cancel()
+	headReq, err := http.NewRequestWithContext(headCtx, "HEAD", src, nil)
+	if err != nil {
+		result.Status = SyncError
+		metrics.ImageFetchErrorsTotal.WithLabelValues("head_request").Inc()
+		metrics.CameraFetchTotal.WithLabelValues(cameraName, canyon, "error").Inc()
+		metrics.OriginFetchTotal.WithLabelValues(origin, "error").Inc()
+		metrics.OriginErrorsByType.WithLabelValues(origin, "head_request").Inc()
+		setAvailability(0)
+		result.Err = err
+		logFetchError(camera, src, origin, "head_request", err)
+		return
+	}
 
-			resp, err := s.client.Do(getReq)
-			if err != nil {
-				// Check if error is due to context cancellation
-				if ctx.Err() != nil {
-					return
-				}
-				atomic.AddInt32(&errorCount, 1)
-				metrics.CameraFetchTotal.WithLabelValues(cameraName, canyon, "error").Inc()
-				metrics.OriginFetchTotal.WithLabelValues(origin, "error").Inc()
-				metrics.OriginErrorsByType.WithLabelValues(origin, "connection").Inc()
-				metrics.CameraAvailability.WithLabelValues(cameraName, canyon).Set(0)
-				return
-			}
-			defer func() {
-				_ = resp.Body.Close()
-			}()
-
-			if resp.StatusCode != http.StatusOK {
-				atomic.AddInt32(&errorCount, 1)
-				metrics.CameraFetchTotal.WithLabelValues(cameraName, canyon, "error").Inc()
-				metrics.OriginFetchTotal.WithLabelValues(origin, "error").Inc()
-				metrics.OriginErrorsByType.WithLabelValues(origin, "bad_status").Inc()
-				metrics.CameraAvailability.WithLabelValues(cameraName, canyon).Set(0)
-				return
-			}
+	s.setRequestHeaders(headReq, camera)
 
-			contentType := resp.Header.Get("Content-Type")
-			contentLength := resp.ContentLength
+	entry.Write(func(entry *Entry) {
+		entry.AttemptedAt = time.Now()
+	})
+	headResp, err := s.client.Do(headReq)
+	if err != nil {
+		// Check if error is due to context cancellation
+		if ctx.Err() != nil {
+			return
+		}
+		result.Status = SyncError
+		metrics.CameraFetchTotal.WithLabelValues(cameraName, canyon, "error").Inc()
+		metrics.OriginFetchTotal.WithLabelValues(origin, "error").Inc()
+		metrics.OriginErrorsByType.WithLabelValues(origin, fetchErrorReason(err)).Inc()
+		setAvailability(0)
+		result.Err = err
+		logFetchError(camera, src, origin, fetchErrorReason(err), err)
+		return
+	}
 
-			imageBytes, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize))
-			if err != nil {
-				atomic.AddInt32(&errorCount, 1)
-				metrics.CameraFetchTotal.WithLabelValues(cameraName, canyon, "error").Inc()
-				metrics.OriginFetchTotal.WithLabelValues(origin, "error").Inc()
-				metrics.OriginErrorsByType.WithLabelValues(origin, "read_body").Inc()
-				metrics.CameraAvailability.WithLabelValues(cameraName, canyon).Set(0)
-				return
+	_ = headResp.Body.Close()
+
+	newETag := headResp.Header.Get("ETag")
+
+	if newETag != "" && newETag == headers.ETag {
+		result.Status = SyncUnchanged
+		// Record metrics for unchanged image
+		cameraDuration := time.Since(cameraStartTime).Seconds()
+		metrics.CameraFetchDuration.WithLabelValues(cameraName, canyon).Observe(cameraDuration)
+		metrics.CameraFetchTotal.WithLabelValues(cameraName, canyon, "unchanged").Inc()
+		metrics.OriginFetchTotal.WithLabelValues(origin, "success").Inc()
+		metrics.OriginFetchDuration.WithLabelValues(origin).Observe(cameraDuration)
+		setAvailability(1)
+		// Variants follow the main image, but one still missing is retried
+		if len(camera.Sources) > 0 && len(entry.ShallowSnapshot().Variants) < len(camera.Sources) {
+			s.fetchVariants(ctx, entry, camera)
+		}
+		return
+	}
+
+	getCtx, cancel := context.WithTimeout(ctx, s.options.GetRequestTimeout)
+	defer cancel()
+	getReq, err := http.NewRequestWithContext(getCtx, "GET", src, nil)
+	if err != nil {
+		result.Status = SyncError
+		metrics.CameraFetchTotal.WithLabelValues(cameraName, canyon, "error").Inc()
+		metrics.OriginFetchTotal.WithLabelValues(origin, "error").Inc()
+		metrics.OriginErrorsByType.WithLabelValues(origin, "get_request").Inc()
+		setAvailability(0)
+		result.Err = err
+		logFetchError(camera, src, origin, "get_request", err)
+		return
+	}
+
+	s.setRequestHeaders(getReq, camera)
+
+	resp, err := s.client.Do(getReq)
+	if err != nil {
+		// Check if error is due to context cancellation
+		if ctx.Err() != nil {
+			return
+		}
+		result.Status = SyncError
+		metrics.CameraFetchTotal.WithLabelValues(cameraName, canyon, "error").Inc()
+		metrics.OriginFetchTotal.WithLabelValues(origin, "error").Inc()
+		metrics.OriginErrorsByType.WithLabelValues(origin, fetchErrorReason(err)).Inc()
+		setAvailability(0)
+		result.Err = err
+		logFetchError(camera, src, origin, fetchErrorReason(err), err)
+		return
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	result.HTTPStatus = resp.StatusCode
+	if resp.StatusCode != http.StatusOK {
+		result.Status = SyncError
+		result.Err = fmt.Errorf("status %d", resp.StatusCode)
+		metrics.CameraFetchTotal.WithLabelValues(cameraName, canyon, "error").Inc()
+		metrics.OriginFetchTotal.WithLabelValues(origin, "error").Inc()
+		metrics.OriginErrorsByType.WithLabelValues(origin, "bad_status").Inc()
+		setAvailability(0)
+		logFetchError(camera, resp.Request.URL.String(), origin, "bad_status", result.Err)
+		return
+	}
+
+	contentLength := resp.ContentLength
+
+	var imageBytes []byte
+	if camera.Kind == "mjpeg" {
+		// The stream never ends, so keep just its first frame as the still
+		imageBytes, err = readMJPEGFrame(resp, s.options.MaxImageSize)
+		contentLength = int64(len(imageBytes))
+	} else {
+		imageBytes, err = readImageBody(resp, s.options.MaxImageSize)
+		if resp.Uncompressed || resp.Header.Get("Content-Encoding") != "" {
+			// The upstream length describes the compressed body
+			contentLength = int64(len(imageBytes))
+		}
+	}
+	if err != nil {
+		result.Status = SyncError
+		metrics.CameraFetchTotal.WithLabelValues(cameraName, canyon, "error").Inc()
+		metrics.OriginFetchTotal.WithLabelValues(origin, "error").Inc()
+		metrics.OriginErrorsByType.WithLabelValues(origin, "read_body").Inc()
+		setAvailability(0)
+		result.Err = err
+		logFetchError(camera, resp.Request.URL.String(), origin, "read_body", err)
+		return
+	}
+	// Reject bytes that aren't a decodable image, keeping the previous good
+	// image. Trust the detected format over the upstream Content-Type.
+	contentType, width, height, err := decodeImageHeader(imageBytes)
+	if err != nil {
+		result.Status = SyncError
+		metrics.CameraFetchTotal.WithLabelValues(cameraName, canyon, "error").Inc()
+		metrics.OriginFetchTotal.WithLabelValues(origin, "error").Inc()
+		metrics.OriginErrorsByType.WithLabelValues(origin, "decode_error").Inc()
+		setAvailability(0)
+		result.Err = err
+		logFetchError(camera, resp.Request.URL.String(), origin, "decode_error", err)
+		return
+	}
+	// Optionally store PNGs as (smaller) JPEGs. This costs a decode per fetch,
+	// so the ETag, and what we serve, always describe the stored bytes.
+	if contentType == "image/png" && s.pngJPEGQuality > 0 {
+		if converted, err := pngToJPEG(imageBytes, s.pngJPEGQuality); err == nil && len(converted) < len(imageBytes) {
+			imageBytes, contentType, contentLength = converted, "image/jpeg", int64(len(converted))
+		}
+	}
+	// Changes are detected by content, ignoring any ETag version
+	etag := contentETag(imageBytes)
+	newContent := current == nil || !sameContent(current.ETag, etag)
+	// New frames that may be compared, here or by DiffFrame, are hashed once
+	// and the hash kept on the stored Image
+	var hash *uint64
+	if newContent && (camera.ChangeThreshold > 0 || timelapse) {
+		hash = hashImage(imageBytes)
+	}
+	// A near-identical frame keeps the current image, so a ticking overlay
+	// doesn't bust caches every cycle
+	similar := current != nil && newContent && similarFrame(current, imageBytes, hash, camera.ChangeThreshold)
+	changed := false
+	entry.Write(func(entry *Entry) {
+		// Only update FetchedAt when image content actually changed
+		changed = !sameContent(entry.Image.ETag, etag) && !(similar && entry.Image == current)
+		if changed {
+			entry.FetchedAt = time.Now()
+		}
+		entry.ResolvedURL = resp.Request.URL.String()
+		// replace headers
+		entry.HTTPHeaders = &HTTPHeaders{
+			Status:        http.StatusOK,
+			ContentType:   contentType,
+			ContentLength: contentLength,
+			ETag:          newETag,
+		}
+		// Replace the image only if it changed, so an unchanged frame doesn't
+		// hold a second copy of bytes that snapshots and timelapse frames
+		// already share
+		if changed {
+			entry.Image = &Image{
+				Bytes:          imageBytes,
+				ETag:           s.nextImageETag(etag),
+				Src:            entry.Image.Src,
+				Width:          width,
+				Height:         height,
+				PerceptualHash: hash,
 			}
-			etag := "\"" + strconv.FormatUint(xxhash.Sum64(imageBytes), 10) + "\""
-			entry.Write(func(entry *Entry) {
-				// Only update FetchedAt when image content actually changed
-				if entry.Image.ETag != etag {
-					entry.FetchedAt = time.Now()
-				}
-				// replace headers
-				entry.HTTPHeaders = &HTTPHeaders{
-					Status:        http.StatusOK,
-					ContentType:   contentType,
-					ContentLength: contentLength,
-					ETag:          newETag,
-				}
-				// replace image
-				entry.Image = &Image{
-					Bytes: imageBytes,
-					ETag:  etag,
-					Src:   entry.Image.Src,
-				}
+		}
+		// retain the new frame for timelapse playback
+		if changed && entry.frames != nil {
+			entry.frames.push(Frame{
+				Image:       entry.Image,
+				ContentType: contentType,
+				FetchedAt:   entry.FetchedAt,
 			})
-			atomic.AddInt32(&changedCount, 1)
+		}
+	})
+	result.Status = SyncChanged
+	if similar && !changed {
+		result.Status = SyncUnchanged
+	}
+	s.fetchVariants(ctx, entry, camera)
 
-			// Record success metrics
-			cameraDuration := time.Since(cameraStartTime).Seconds()
-			imageSize := float64(len(imageBytes))
+	// Record success metrics
+	cameraDuration := time.Since(cameraStartTime).Seconds()
+	imageSize := float64(len(imageBytes))
 
-			metrics.CameraFetchDuration.WithLabelValues(cameraName, canyon).Observe(cameraDuration)
-			metrics.CameraFetchTotal.WithLabelValues(cameraName, canyon, "success").Inc()
-			metrics.CameraAvailability.WithLabelValues(cameraName, canyon).Set(1)
-			metrics.CameraLastSuccessTimestamp.WithLabelValues(cameraName, canyon).SetToCurrentTime()
-			metrics.CameraImageSizeBytes.WithLabelValues(cameraName, canyon).Set(imageSize)
+	metrics.CameraFetchDuration.WithLabelValues(cameraName, canyon).Observe(cameraDuration)
+	metrics.CameraFetchTotal.WithLabelValues(cameraName, canyon, "success").Inc()
+	setAvailability(1)
+	metrics.CameraLastSuccessTimestamp.WithLabelValues(cameraName, canyon).SetToCurrentTime()
+	metrics.CameraImageSizeBytes.WithLabelValues(cameraName, canyon).Set(imageSize)
 
-			metrics.OriginFetchTotal.WithLabelValues(origin, "success").Inc()
-			metrics.OriginFetchDuration.WithLabelValues(origin).Observe(cameraDuration)
-			metrics.ImageFetchSizeBytes.Observe(imageSize)
-		}(entry)
+	metrics.OriginFetchTotal.WithLabelValues(origin, "success").Inc()
+	metrics.OriginFetchDuration.WithLabelValues(origin).Observe(cameraDuration)
+	metrics.ImageFetchSizeBytes.Observe(imageSize)
+}
+
+// SetTransportOptions replaces the fetch transport's connection pool and TLS
+// settings. It must be called before the first FetchImages.
+func (s *Store) SetTransportOptions(opts TransportOptions) {
+	s.client.Transport = newCameraTransport(countOrigins(s.allEntries()), opts)
+}
+
+// SetFetchConcurrency sets the maximum number of cameras fetched at once.
+// It must be called before the first FetchImages.
+func (s *Store) SetFetchConcurrency(n int) {
+	if n > 0 {
+		s.options.FetchConcurrency = n
 	}
-	wg.Wait()
-	if s.isWaitingOnFirstImageReady.Load() {
-		s.isWaitingOnFirstImageReady.Store(false)
+}
+
+// SetPNGToJPEGQuality re-encodes PNG camera frames as JPEG at the given
+// quality (1-100) when that makes them smaller, to cut memory. 0 disables it.
+// It must be called before the first FetchImages.
+func (s *Store) SetPNGToJPEGQuality(quality int) {
+	if quality >= 0 && quality <= 100 {
+		s.pngJPEGQuality = quality
+	}
+}
+
+// SetUserAgent sets the User-Agent sent to camera origins. It must be called
+// before the first FetchImages.
+func (s *Store) SetUserAgent(userAgent string) {
+	s.userAgent = userAgent
+}
+
+// userAgentFor returns the User-Agent to send when fetching a camera
+func (s *Store) userAgentFor(camera *Camera) string {
+	if camera.BrowserUserAgent {
+		return browserUserAgent
+	}
+	return s.userAgent
+}
+
+// markImagesReady releases readers blocked in Get. Safe to call repeatedly;
+// only the first call has any effect.
+func (s *Store) markImagesReady() {
+	if s.isWaitingOnFirstImageReady.CompareAndSwap(true, false) {
 		s.imagesReady.Done()
 		metrics.ImagesReady.Set(1)
 	}
-	duration := time.Since(startTime)
-
-	// Record metrics
-	timer.Observe(duration.Seconds())
-	metrics.StoreFetchCyclesTotal.Inc()
-	metrics.ImageFetchTotal.WithLabelValues("success").Add(float64(changedCount))
-	metrics.ImageFetchTotal.WithLabelValues("unchanged").Add(float64(unchangedCount))
-	metrics.ImageFetchTotal.WithLabelValues("error").Add(float64(errorCount))
-	metrics.FetchCycleDurationSeconds.Set(duration.Seconds())
+}
 
-	// Update memory usage metrics
-	metrics.RecordMemoryUsage()
+// checkReadiness marks the store ready if enough cameras have an image to
+// satisfy ReadyMinPercent
+func (s *Store) checkReadiness() {
+	if !s.isWaitingOnFirstImageReady.Load() {
+		return
+	}
+	if s.options.ReadyMinPercent > 0 {
+		available, total := s.imageCoverage()
+		if total > 0 && available*100 < total*s.options.ReadyMinPercent {
+			return
+		}
+	}
+	s.markImagesReady()
+}
 
-	// Print summary
-	summary := logger.FetchSummary{
-		Duration:  duration,
-		Changed:   int(changedCount),
-		Unchanged: int(unchangedCount),
-		Errors:    int(errorCount),
-		Total:     int(changedCount + unchangedCount + errorCount),
+// startReadyDeadline arms the readiness fallback, once: ReadyMaxWait with a
+// ReadyMinPercent policy, otherwise InitialFetchTimeout
+func (s *Store) startReadyDeadline() {
+	wait := s.options.InitialFetchTimeout
+	if s.options.ReadyMinPercent > 0 {
+		wait = s.options.ReadyMaxWait
 	}
-	summary.Print()
+	s.readyDeadlineOnce.Do(func() {
+		time.AfterFunc(wait, func() {
+			if s.IsReady() {
+				return
+			}
+			available, total := s.imageCoverage()
+			logger.Warn("Ready after %s with only %d of %d cameras available; still waiting on: %s",
+				wait, available, total, strings.Join(s.camerasWithoutImage(), ", "))
+			s.markImagesReady()
+		})
+	})
+}
 
-	// Call sync callback if set
-	s.syncCallbackMu.Lock()
-	if s.syncCallback != nil {
-		s.syncCallback(duration, int(changedCount), int(unchangedCount), int(errorCount))
+// camerasWithoutImage names the enabled, fetchable cameras that don't have
+// an image yet
+func (s *Store) camerasWithoutImage() []string {
+	var names []string
+	for _, entry := range s.allEntries() {
+		if !entry.Camera.IsFetchable() || !entry.Camera.IsEnabled() {
+			continue
+		}
+		entry.Read(func(entry *Entry) {
+			if entry.Image.ETag == "" {
+				names = append(names, entry.Camera.Alt)
+			}
+		})
 	}
-	s.syncCallbackMu.Unlock()
+	return names
 }
 
-// SetSyncCallback sets a callback to be called after each sync
-func (s *Store) SetSyncCallback(cb func(duration time.Duration, changed, unchanged, errors int)) {
-	s.syncCallbackMu.Lock()
-	s.syncCallback = cb
-	s.syncCallbackMu.Unlock()
+// imageCoverage counts the enabled, fetchable cameras and how many of them
+// have an image
+func (s *Store) imageCoverage() (available, total int) {
+	for _, entry := range s.allEntries() {
+		if !entry.Camera.IsFetchable() || !entry.Camera.IsEnabled() {
+			continue
+		}
+		total++
+		entry.Read(func(entry *Entry) {
+			if entry.Image.ETag != "" {
+				available++
+			}
+		})
+	}
+	return available, total
 }
 
 // IsReady returns true if the store has completed its initial image fetch
@@ -529,21 +1160,52 @@ func slugify(name string) string {
 }
 
 func (s *Store) Get(cameraID string) (EntrySnapshot, bool) {
+	entry, exists := s.lookup(cameraID)
+	if !exists {
+		return EntrySnapshot{}, false
+	}
+	return entry.ShallowSnapshot(), true
+}
+
+// Entries returns snapshots of every configured camera, including disabled
+// ones, in configuration order. Unlike Get it doesn't wait for images.
+func (s *Store) Entries() []EntrySnapshot {
+	entries := s.allEntries()
+	snapshots := make([]EntrySnapshot, 0, len(entries))
+	for _, entry := range entries {
+		snapshots = append(snapshots, entry.ShallowSnapshot())
+	}
+	return snapshots
+}
+
+// allEntries returns the current entries. The slice is never mutated, since
+// Reload swaps in a new one, so callers may iterate it without holding s.mu.
+func (s *Store) allEntries() []*Entry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.entries
+}
+
+// lookup finds an entry by camera ID, falling back to its slug. Like Get,
+// it blocks until the first image fetch has completed.
+func (s *Store) lookup(cameraID string) (*Entry, bool) {
 	s.imagesReady.Wait()
+	return s.lookupNow(cameraID)
+}
+
+// lookupNow is lookup without waiting for the first image fetch
+func (s *Store) lookupNow(cameraID string) (*Entry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
 	// First try direct ID lookup
-	entry, exists := s.index[cameraID]
-	if exists {
-		return entry.ShallowSnapshot(), true
+	if entry, exists := s.index[cameraID]; exists {
+		return entry, true
 	}
 
 	// Then try slug-based lookup
-	entry, exists = s.nameIndex[cameraID]
-	if exists {
-		return entry.ShallowSnapshot(), true
-	}
-
-	return EntrySnapshot{}, false
+	entry, exists := s.nameIndex[cameraID]
+	return entry, exists
 }
 
 // UpdateRoadConditions updates the road conditions for a canyon
@@ -567,28 +1229,27 @@ func (s *Store) GetRoadConditions(canyon string) []RoadCondition {
 	return result
 }
 
-// StoreWeatherStationsById indexes weather stations by their Id for lookup by cameras
+// StoreWeatherStationsById indexes weather stations by their Id for lookup by
+// cameras, and updates camera coordinates from them
 func (s *Store) StoreWeatherStationsById(stations []WeatherStation) {
-	s.weatherStationsMu.Lock()
-	defer s.weatherStationsMu.Unlock()
-
 	m := make(map[int]*WeatherStation, len(stations))
 	for i := range stations {
 		m[stations[i].Id] = &stations[i]
 	}
+	s.weatherStationsMu.Lock()
 	s.weatherStationsById = m
+	s.weatherStationsMu.Unlock()
 	logger.Muted("Indexed %d weather stations by Id", len(m))
+
+	if err := s.UpdateCameraCoordinates(); err != nil {
+		logger.Error(err, "Failed to update camera coordinates: %v", err)
+	}
 }
 
 // GetWeatherStation returns the weather station data for a camera by its ID
 func (s *Store) GetWeatherStation(cameraID string) *WeatherStation {
-	s.imagesReady.Wait()
-
 	// Get the camera entry
-	entry, exists := s.index[cameraID]
-	if !exists {
-		entry, exists = s.nameIndex[cameraID]
-	}
+	entry, exists := s.lookup(cameraID)
 	if !exists {
 		return nil
 	}
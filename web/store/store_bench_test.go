@@ -2,8 +2,10 @@ package store
 
 import (
 	"context"
+	"crypto/tls"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"testing/fstest"
 )
@@ -13,13 +15,14 @@ func BenchmarkStore_Get(b *testing.B) {
 		w.Header().Set("Content-Type", "image/jpeg")
 		w.Header().Set("ETag", "\"test-etag\"")
 		if r.Method == "GET" {
-			w.Write([]byte("mock image data"))
+			w.Write(testImage("mock image data"))
 		}
 	}))
 	defer server.Close()
 
 	canyons := &Canyons{
-		LCC: Canyon{
+		{
+			ID:   "LCC",
 			Name: "LCC",
 			Cameras: []Camera{
 				{
@@ -30,7 +33,7 @@ func BenchmarkStore_Get(b *testing.B) {
 				},
 			},
 		},
-		BCC: Canyon{Name: "BCC"},
+		{ID: "BCC", Name: "BCC"},
 	}
 
 	store := NewStore(canyons)
@@ -54,13 +57,14 @@ func BenchmarkStore_ShallowSnapshot(b *testing.B) {
 		w.Header().Set("Content-Type", "image/jpeg")
 		w.Header().Set("ETag", "\"test-etag\"")
 		if r.Method == "GET" {
-			w.Write([]byte("mock image data"))
+			w.Write(testImage("mock image data"))
 		}
 	}))
 	defer server.Close()
 
 	canyons := &Canyons{
-		LCC: Canyon{
+		{
+			ID:   "LCC",
 			Name: "LCC",
 			Cameras: []Camera{
 				{
@@ -71,7 +75,7 @@ func BenchmarkStore_ShallowSnapshot(b *testing.B) {
 				},
 			},
 		},
-		BCC: Canyon{Name: "BCC"},
+		{ID: "BCC", Name: "BCC"},
 	}
 
 	store := NewStore(canyons)
@@ -98,7 +102,8 @@ func BenchmarkStore_FetchImages_SingleCamera(b *testing.B) {
 	defer server.Close()
 
 	canyons := &Canyons{
-		LCC: Canyon{
+		{
+			ID:   "LCC",
 			Name: "LCC",
 			Cameras: []Camera{
 				{
@@ -109,7 +114,7 @@ func BenchmarkStore_FetchImages_SingleCamera(b *testing.B) {
 				},
 			},
 		},
-		BCC: Canyon{Name: "BCC"},
+		{ID: "BCC", Name: "BCC"},
 	}
 
 	store := NewStore(canyons)
@@ -142,11 +147,12 @@ func BenchmarkStore_FetchImages_MultipleCamera(b *testing.B) {
 	}
 
 	canyons := &Canyons{
-		LCC: Canyon{
+		{
+			ID:      "LCC",
 			Name:    "LCC",
 			Cameras: cameras,
 		},
-		BCC: Canyon{Name: "BCC"},
+		{ID: "BCC", Name: "BCC"},
 	}
 
 	store := NewStore(canyons)
@@ -158,18 +164,60 @@ func BenchmarkStore_FetchImages_MultipleCamera(b *testing.B) {
 	}
 }
 
+// BenchmarkStore_FetchImages_Transport compares a fetch cycle against a TLS
+// origin serving many cameras using a bare transport (the previous default)
+// and the tuned one
+func BenchmarkStore_FetchImages_Transport(b *testing.B) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Header().Set("ETag", "\""+r.URL.Path+"\"")
+		if r.Method == "GET" {
+			w.Write(make([]byte, 1024*50)) // 50KB image
+		}
+	}))
+	defer server.Close()
+
+	cameras := make([]Camera, 40)
+	for i := range cameras {
+		cameras[i] = Camera{Kind: "webcam", Src: server.URL + "/" + strconv.Itoa(i) + ".jpg", Canyon: "LCC"}
+	}
+
+	for _, bc := range []struct {
+		name      string
+		transport func(*Store) http.RoundTripper
+	}{
+		{"bare", func(*Store) http.RoundTripper {
+			return &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}} //nolint:gosec // test server
+		}},
+		{"tuned", func(s *Store) http.RoundTripper { return s.client.Transport }},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			store := NewStore(&Canyons{{ID: "LCC", Name: "LCC", Cameras: cameras}})
+			store.client.Transport = bc.transport(store)
+			ctx := context.Background()
+			store.FetchImages(ctx)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				store.FetchImages(ctx)
+			}
+		})
+	}
+}
+
 func BenchmarkStore_ConcurrentGetAndFetch(b *testing.B) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "image/jpeg")
 		w.Header().Set("ETag", "\"test-etag\"")
 		if r.Method == "GET" {
-			w.Write([]byte("mock image data"))
+			w.Write(testImage("mock image data"))
 		}
 	}))
 	defer server.Close()
 
 	canyons := &Canyons{
-		LCC: Canyon{
+		{
+			ID:   "LCC",
 			Name: "LCC",
 			Cameras: []Camera{
 				{
@@ -180,7 +228,7 @@ func BenchmarkStore_ConcurrentGetAndFetch(b *testing.B) {
 				},
 			},
 		},
-		BCC: Canyon{Name: "BCC"},
+		{ID: "BCC", Name: "BCC"},
 	}
 
 	store := NewStore(canyons)
@@ -25,13 +25,14 @@ func FuzzStoreCameraID(f *testing.F) {
 		w.Header().Set("Content-Type", "image/jpeg")
 		w.Header().Set("ETag", "\"test-etag\"")
 		if r.Method == "GET" {
-			w.Write([]byte("mock image data"))
+			w.Write(testImage("mock image data"))
 		}
 	}))
 	defer server.Close()
 
 	canyons := &Canyons{
-		LCC: Canyon{
+		{
+			ID:   "LCC",
 			Name: "LCC",
 			Cameras: []Camera{
 				{
@@ -42,7 +43,7 @@ func FuzzStoreCameraID(f *testing.F) {
 				},
 			},
 		},
-		BCC: Canyon{Name: "BCC"},
+		{ID: "BCC", Name: "BCC"},
 	}
 
 	store := NewStore(canyons)
@@ -123,7 +124,8 @@ func FuzzImageData(f *testing.F) {
 		}()
 
 		canyons := &Canyons{
-			LCC: Canyon{
+			{
+				ID:   "LCC",
 				Name: "LCC",
 				Cameras: []Camera{
 					{
@@ -134,7 +136,7 @@ func FuzzImageData(f *testing.F) {
 					},
 				},
 			},
-			BCC: Canyon{Name: "BCC"},
+			{ID: "BCC", Name: "BCC"},
 		}
 
 		store := NewStore(canyons)
@@ -157,10 +159,16 @@ func FuzzImageData(f *testing.F) {
 			t.Error("Image should not be nil")
 		}
 
-		// Verify the stored data matches what we sent
-		if exists && entry.Image != nil && !bytes.Equal(entry.Image.Bytes, imageData) {
-			t.Errorf("Stored image data doesn't match. Expected %d bytes, got %d bytes",
-				len(imageData), len(entry.Image.Bytes))
+		// Decodable images are stored as sent; anything else is rejected
+		if exists && entry.Image != nil {
+			_, _, _, decodeErr := decodeImageHeader(imageData)
+			if decodeErr == nil && !bytes.Equal(entry.Image.Bytes, imageData) {
+				t.Errorf("Stored image data doesn't match. Expected %d bytes, got %d bytes",
+					len(imageData), len(entry.Image.Bytes))
+			}
+			if decodeErr != nil && len(entry.Image.Bytes) != 0 {
+				t.Errorf("Undecodable image data (%v) was stored", decodeErr)
+			}
 		}
 	})
 }
@@ -177,13 +185,14 @@ func FuzzConcurrentAccess(f *testing.F) {
 		w.Header().Set("Content-Type", "image/jpeg")
 		w.Header().Set("ETag", "\"test-etag\"")
 		if r.Method == "GET" {
-			w.Write([]byte("mock image data"))
+			w.Write(testImage("mock image data"))
 		}
 	}))
 	defer server.Close()
 
 	canyons := &Canyons{
-		LCC: Canyon{
+		{
+			ID:   "LCC",
 			Name: "LCC",
 			Cameras: []Camera{
 				{
@@ -194,7 +203,7 @@ func FuzzConcurrentAccess(f *testing.F) {
 				},
 			},
 		},
-		BCC: Canyon{Name: "BCC"},
+		{ID: "BCC", Name: "BCC"},
 	}
 
 	f.Fuzz(func(t *testing.T, numReaders int32, numWriters int32) {
@@ -270,7 +279,7 @@ func FuzzHTTPResponseHeaders(f *testing.F) {
 			w.Header().Set("Content-Type", contentType)
 			w.Header().Set("ETag", etag)
 			if r.Method == "GET" {
-				w.Write([]byte("test data"))
+				w.Write(testImage("test data"))
 			}
 		}))
 		defer server.Close()
@@ -283,7 +292,8 @@ func FuzzHTTPResponseHeaders(f *testing.F) {
 		}()
 
 		canyons := &Canyons{
-			LCC: Canyon{
+			{
+				ID:   "LCC",
 				Name: "LCC",
 				Cameras: []Camera{
 					{
@@ -294,7 +304,7 @@ func FuzzHTTPResponseHeaders(f *testing.F) {
 					},
 				},
 			},
-			BCC: Canyon{Name: "BCC"},
+			{ID: "BCC", Name: "BCC"},
 		}
 
 		store := NewStore(canyons)
@@ -351,7 +361,8 @@ func FuzzCameraURL(f *testing.F) {
 		}()
 
 		canyons := &Canyons{
-			LCC: Canyon{
+			{
+				ID:   "LCC",
 				Name: "LCC",
 				Cameras: []Camera{
 					{
@@ -362,7 +373,7 @@ func FuzzCameraURL(f *testing.F) {
 					},
 				},
 			},
-			BCC: Canyon{Name: "BCC"},
+			{ID: "BCC", Name: "BCC"},
 		}
 
 		store := NewStore(canyons)
@@ -1,26 +1,61 @@
 package store
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	crand "crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math/big"
+	"math/rand/v2"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stefanpenner/lcc-live/web/logger"
+	"github.com/stefanpenner/lcc-live/web/metrics"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// testImage returns payload behind a minimal 1x1 JPEG header: enough for
+// FetchImages' header validation, while keeping the bytes distinguishable
+func testImage(payload string) []byte {
+	header := []byte{
+		0xFF, 0xD8, // SOI
+		0xFF, 0xE0, 0x00, 0x10, 'J', 'F', 'I', 'F', 0x00, 0x01, 0x01, 0x00, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, // APP0
+		0xFF, 0xC0, 0x00, 0x0B, 0x08, 0x00, 0x01, 0x00, 0x01, 0x01, 0x01, 0x11, 0x00, // SOF0: 1x1, 1 component
+	}
+	return append(header, payload...)
+}
+
 func TestStore_Canyon(t *testing.T) {
 	canyons := &Canyons{
-		LCC: Canyon{
+		{
+			ID:   "LCC",
 			Name: "LCC",
 			Cameras: []Camera{
 				{Src: "http://cam1", Canyon: "LCC"},
 			},
 		},
-		BCC: Canyon{
+		{
+			ID:   "BCC",
 			Name: "BCC",
 			Cameras: []Camera{
 				{Src: "http://cam2", Canyon: "BCC"},
@@ -30,31 +65,42 @@ func TestStore_Canyon(t *testing.T) {
 
 	store := NewStore(canyons)
 
-	lcc := store.Canyon("LCC")
+	lcc, ok := store.Canyon("LCC")
+	require.True(t, ok)
 	assert.Equal(t, "LCC", lcc.Name)
 	assert.Len(t, lcc.Cameras, 1)
 	assert.NotEmpty(t, lcc.Cameras[0].ID)
 
-	bcc := store.Canyon("BCC")
+	bcc, ok := store.Canyon("BCC")
+	require.True(t, ok)
 	assert.Equal(t, "BCC", bcc.Name)
 	assert.Len(t, bcc.Cameras, 1)
 	assert.NotEmpty(t, bcc.Cameras[0].ID)
+
+	for _, id := range []string{"PC", "", "lcc/../bcc"} {
+		assert.NotPanics(t, func() {
+			canyon, ok := store.Canyon(id)
+			assert.False(t, ok, id)
+			assert.Nil(t, canyon, id)
+		}, "unknown canyons are reported, not panicked on")
+	}
 }
 
 func TestStore_Fetch_and_Get_Images(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "image/jpeg")
 		w.Header().Set("ETag", "\"test-etag\"")
-		w.Header().Set("Content-Length", "15")
+		w.Header().Set("Content-Length", strconv.Itoa(len(testImage("mock image data"))))
 
 		if r.Method == "GET" {
-			w.Write([]byte("mock image data"))
+			w.Write(testImage("mock image data"))
 		}
 	}))
 	defer server.Close()
 
 	canyons := &Canyons{
-		LCC: Canyon{
+		{
+			ID:   "LCC",
 			Name: "LCC",
 			Cameras: []Camera{
 				{
@@ -65,7 +111,7 @@ func TestStore_Fetch_and_Get_Images(t *testing.T) {
 				},
 			},
 		},
-		BCC: Canyon{Name: "BCC"},
+		{ID: "BCC", Name: "BCC"},
 	}
 
 	store := NewStore(canyons)
@@ -79,7 +125,7 @@ func TestStore_Fetch_and_Get_Images(t *testing.T) {
 
 	require.True(t, exists, "Camera entry should exist")
 	assert.NotNil(t, entry.Image)
-	assert.Equal(t, "mock image data", string(entry.Image.Bytes))
+	assert.Equal(t, testImage("mock image data"), entry.Image.Bytes)
 	assert.Equal(t, "image/jpeg", entry.HTTPHeaders.ContentType)
 	assert.NotEmpty(t, entry.HTTPHeaders.ETag)
 
@@ -92,13 +138,14 @@ func TestStore_ConcurrentReads(t *testing.T) {
 		w.Header().Set("Content-Type", "image/jpeg")
 		w.Header().Set("ETag", "\"test-etag\"")
 		if r.Method == "GET" {
-			w.Write([]byte("mock image data"))
+			w.Write(testImage("mock image data"))
 		}
 	}))
 	defer server.Close()
 
 	canyons := &Canyons{
-		LCC: Canyon{
+		{
+			ID:   "LCC",
 			Name: "LCC",
 			Cameras: []Camera{
 				{
@@ -109,7 +156,7 @@ func TestStore_ConcurrentReads(t *testing.T) {
 				},
 			},
 		},
-		BCC: Canyon{Name: "BCC"},
+		{ID: "BCC", Name: "BCC"},
 	}
 
 	store := NewStore(canyons)
@@ -142,13 +189,14 @@ func TestStore_ConcurrentFetchAndRead(t *testing.T) {
 		w.Header().Set("Content-Type", "image/jpeg")
 		w.Header().Set("ETag", "\"test-etag\"")
 		if r.Method == "GET" {
-			w.Write([]byte("mock image data"))
+			w.Write(testImage("mock image data"))
 		}
 	}))
 	defer server.Close()
 
 	canyons := &Canyons{
-		LCC: Canyon{
+		{
+			ID:   "LCC",
 			Name: "LCC",
 			Cameras: []Camera{
 				{
@@ -159,7 +207,7 @@ func TestStore_ConcurrentFetchAndRead(t *testing.T) {
 				},
 			},
 		},
-		BCC: Canyon{Name: "BCC"},
+		{ID: "BCC", Name: "BCC"},
 	}
 
 	store := NewStore(canyons)
@@ -211,13 +259,14 @@ func TestStore_FetchImages_ETagCaching(t *testing.T) {
 			requestCount++
 			w.Header().Set("Content-Type", "image/jpeg")
 			w.Header().Set("ETag", "\"stable-etag\"")
-			w.Write([]byte("mock image data"))
+			w.Write(testImage("mock image data"))
 		}
 	}))
 	defer server.Close()
 
 	canyons := &Canyons{
-		LCC: Canyon{
+		{
+			ID:   "LCC",
 			Name: "LCC",
 			Cameras: []Camera{
 				{
@@ -228,7 +277,7 @@ func TestStore_FetchImages_ETagCaching(t *testing.T) {
 				},
 			},
 		},
-		BCC: Canyon{Name: "BCC"},
+		{ID: "BCC", Name: "BCC"},
 	}
 
 	store := NewStore(canyons)
@@ -253,7 +302,8 @@ func TestStore_FetchImages_ErrorHandling(t *testing.T) {
 	defer server.Close()
 
 	canyons := &Canyons{
-		LCC: Canyon{
+		{
+			ID:   "LCC",
 			Name: "LCC",
 			Cameras: []Camera{
 				{
@@ -264,7 +314,7 @@ func TestStore_FetchImages_ErrorHandling(t *testing.T) {
 				},
 			},
 		},
-		BCC: Canyon{Name: "BCC"},
+		{ID: "BCC", Name: "BCC"},
 	}
 
 	store := NewStore(canyons)
@@ -285,7 +335,8 @@ func TestStore_FetchImages_ErrorHandling(t *testing.T) {
 
 func TestStore_FetchImages_SkipsIframes(t *testing.T) {
 	canyons := &Canyons{
-		LCC: Canyon{
+		{
+			ID:   "LCC",
 			Name: "LCC",
 			Cameras: []Camera{
 				{
@@ -296,7 +347,7 @@ func TestStore_FetchImages_SkipsIframes(t *testing.T) {
 				},
 			},
 		},
-		BCC: Canyon{Name: "BCC"},
+		{ID: "BCC", Name: "BCC"},
 	}
 
 	store := NewStore(canyons)
@@ -313,3 +364,1007 @@ func TestStore_FetchImages_SkipsIframes(t *testing.T) {
 	// Image should be empty since we skip iframes
 	assert.Empty(t, entry.Image.Bytes)
 }
+
+func TestStore_FetchImages_FollowsRedirects(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/camera.jpg" {
+			http.Redirect(w, r, server.URL+"/signed/camera.jpg?sig=abc", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("mock image data"))
+		}
+	}))
+	defer server.Close()
+
+	canyons := &Canyons{
+		{
+			ID:      "LCC",
+			Name:    "LCC",
+			Cameras: []Camera{{Kind: "webcam", Src: server.URL + "/camera.jpg", Alt: "Redirecting Camera"}},
+		},
+		{ID: "BCC", Name: "BCC"},
+	}
+
+	store := NewStore(canyons)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	store.FetchImages(ctx)
+
+	entry, exists := store.Get(store.entries[0].ID)
+	require.True(t, exists)
+	assert.Equal(t, testImage("mock image data"), entry.Image.Bytes)
+	assert.Equal(t, server.URL+"/signed/camera.jpg?sig=abc", entry.ResolvedURL)
+}
+
+func TestStore_FetchImages_RedirectLoop(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Redirect(w, r, r.URL.Path, http.StatusFound)
+	}))
+	defer server.Close()
+
+	canyons := &Canyons{
+		{
+			ID:      "LCC",
+			Name:    "LCC",
+			Cameras: []Camera{{Kind: "webcam", Src: server.URL + "/loop.jpg", Alt: "Looping Camera"}},
+		},
+		{ID: "BCC", Name: "BCC"},
+	}
+
+	origin := metrics.ExtractOrigin(server.URL)
+	before := testutil.ToFloat64(metrics.OriginErrorsByType.WithLabelValues(origin, "redirect_loop"))
+
+	store := NewStore(canyons)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	store.FetchImages(ctx)
+
+	entry, exists := store.Get(store.entries[0].ID)
+	require.True(t, exists)
+	assert.Empty(t, entry.Image.Bytes)
+	assert.Empty(t, entry.ResolvedURL)
+	// The HEAD request gives up after maxRedirects and no GET is attempted
+	assert.Equal(t, int32(maxRedirects+1), requests.Load())
+	assert.Equal(t, before+1, testutil.ToFloat64(metrics.OriginErrorsByType.WithLabelValues(origin, "redirect_loop")))
+}
+
+func TestStore_FetchImages_SkipsDisabledCameras(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("mock image data"))
+		}
+	}))
+	defer server.Close()
+
+	disabled := false
+	canyons := &Canyons{
+		{
+			ID:   "LCC",
+			Name: "LCC",
+			Cameras: []Camera{
+				{Kind: "webcam", Src: server.URL + "/disabled.jpg", Alt: "Disabled Camera", Enabled: &disabled},
+			},
+		},
+		{ID: "BCC", Name: "BCC"},
+	}
+
+	store := NewStore(canyons)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	store.FetchImages(ctx)
+
+	assert.Equal(t, int32(0), requests.Load(), "disabled cameras must not be fetched")
+
+	// Still configured and retrievable
+	entry, exists := store.Get(store.entries[0].ID)
+	require.True(t, exists)
+	assert.False(t, entry.Camera.IsEnabled())
+	assert.Empty(t, entry.Image.Bytes)
+	assert.Len(t, store.Entries(), 1)
+}
+
+func TestStore_FetchImages_StatusCameraMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down.jpg" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("status"))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	store := NewStore(&Canyons{
+		{
+			ID:      "LCC",
+			Status:  Camera{Kind: "img", Src: server.URL + "/status.jpg", Alt: "LCC Status Metrics"},
+			Cameras: []Camera{{Kind: "img", Src: server.URL + "/cam.jpg", Alt: "LCC Camera Metrics"}},
+		},
+		{
+			ID:     "BCC",
+			Status: Camera{Kind: "img", Src: server.URL + "/down.jpg", Alt: "BCC Status Metrics"},
+		},
+	})
+	store.FetchImages(context.Background())
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.StatusCameraAvailability.WithLabelValues("LCC Status Metrics", "LCC")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.StatusCameraAvailability.WithLabelValues("BCC Status Metrics", "BCC")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.CameraAvailability.WithLabelValues("LCC Status Metrics", "LCC")),
+		"status cameras still report on the shared gauge")
+
+	assert.False(t, hasCameraSeries(t, metrics.StatusCameraAvailability, "LCC Camera Metrics"),
+		"regular cameras aren't on the status gauge")
+	assert.True(t, hasCameraSeries(t, metrics.CameraAvailability, "LCC Camera Metrics"))
+}
+
+func TestStore_FetchImages_CanyonAvailabilityRatio(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down.jpg" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.Method == "GET" {
+			w.Write(testImage("up"))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	canyons := &Canyons{
+		{ID: "RatioA", Cameras: []Camera{
+			{Kind: "img", Src: server.URL + "/up.jpg", Alt: "Ratio Up"},
+			{Kind: "img", Src: server.URL + "/down.jpg", Alt: "Ratio Down"},
+			{Kind: "iframe", Src: server.URL + "/embed", Alt: "Ratio Embed"},
+		}},
+		{ID: "RatioB", Cameras: []Camera{{Kind: "img", Src: server.URL + "/b.jpg", Alt: "Ratio B Up"}}},
+	}
+	store := NewStore(canyons)
+	store.FetchImages(context.Background())
+
+	assert.Equal(t, 0.5, testutil.ToFloat64(metrics.CanyonAvailabilityRatio.WithLabelValues("RatioA")),
+		"1 of 2 fetched cameras up; iframes don't count")
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.CanyonAvailabilityRatio.WithLabelValues("RatioB")))
+
+	t.Run("removed canyons drop their series", func(t *testing.T) {
+		require.NoError(t, store.Reload(&Canyons{(*canyons)[0]}))
+		assert.False(t, hasSeries(t, metrics.CanyonAvailabilityRatio, "canyon", "RatioB"))
+		assert.True(t, hasSeries(t, metrics.CanyonAvailabilityRatio, "canyon", "RatioA"))
+	})
+}
+
+func TestNewStoreWithError_ExplicitSlug(t *testing.T) {
+	canyons := &Canyons{
+		{
+			ID:   "LCC",
+			Name: "LCC",
+			Cameras: []Camera{
+				{Src: "http://cam1", Alt: "LCC Mid", Slug: "lcc-mid"},
+				{Src: "http://cam2", Alt: "LCC Mid!", Slug: "lcc-middle"},
+			},
+		},
+		{ID: "BCC", Name: "BCC"},
+	}
+
+	store, err := NewStoreWithError(canyons, StoreOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, (*canyons)[0].Cameras[0].ID, store.nameIndex["lcc-mid"].ID)
+	assert.Equal(t, (*canyons)[0].Cameras[1].ID, store.nameIndex["lcc-middle"].ID)
+	assert.Equal(t, "lcc-middle", (*canyons)[0].Cameras[1].GetSlug())
+}
+
+func TestNewStoreWithError_SlugCollision(t *testing.T) {
+	newCanyons := func() *Canyons {
+		return &Canyons{
+			{
+				ID:   "LCC",
+				Name: "LCC",
+				Cameras: []Camera{
+					{Src: "http://cam1", Alt: "LCC Mid"},
+					{Src: "http://cam2", Alt: "LCC Mid!"},
+				},
+			},
+			{ID: "BCC", Name: "BCC"},
+		}
+	}
+
+	store, err := NewStoreWithError(newCanyons(), StoreOptions{})
+	require.Error(t, err)
+	assert.Nil(t, store)
+	assert.Contains(t, err.Error(), "slug collision")
+	assert.Contains(t, err.Error(), "lcc-mid")
+
+	assert.Panics(t, func() { NewStore(newCanyons()) }, "NewStore keeps panicking for back-compat")
+}
+
+func TestNewStoreWithError_DuplicateSrc(t *testing.T) {
+	store, err := NewStoreWithError(&Canyons{
+		{
+			ID:      "LCC",
+			Name:    "LCC",
+			Cameras: []Camera{{Src: "http://cam1", Alt: "Upper"}},
+		},
+		{
+			ID:      "BCC",
+			Name:    "BCC",
+			Cameras: []Camera{{Src: "http://cam1", Alt: "Lower"}},
+		},
+	}, StoreOptions{})
+	require.Error(t, err)
+	assert.Nil(t, store)
+	assert.Contains(t, err.Error(), "duplicate camera src")
+	assert.Contains(t, err.Error(), "'Upper' and 'Lower'")
+}
+
+func TestNewStoreWithError_UnknownKind(t *testing.T) {
+	store, err := NewStoreWithError(&Canyons{
+		{
+			ID:      "LCC",
+			Name:    "LCC",
+			Cameras: []Camera{{Kind: "webca", Src: "http://cam1", Alt: "Typo"}},
+		},
+		{ID: "BCC", Name: "BCC"},
+	}, StoreOptions{})
+	require.Error(t, err)
+	assert.Nil(t, store)
+	assert.Contains(t, err.Error(), `unknown kind "webca"`)
+	assert.Contains(t, err.Error(), "'Typo'")
+}
+
+func TestStore_FetchImages_Kinds(t *testing.T) {
+	var mu sync.Mutex
+	fetched := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fetched[r.URL.Path] = true
+		mu.Unlock()
+		if r.URL.Path == "/stream.mjpeg" {
+			w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary=frame")
+			if r.Method == "GET" {
+				fmt.Fprintf(w, "--frame\r\nContent-Type: image/jpeg\r\n\r\n%s\r\n--frame--\r\n", testImage("mjpeg"))
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage(r.URL.Path))
+		}
+	}))
+	defer server.Close()
+
+	store := NewStore(&Canyons{
+		{
+			ID:   "LCC",
+			Name: "LCC",
+			Cameras: []Camera{
+				{Kind: "status", Src: server.URL + "/status.jpg", Alt: "Status"},
+				{Kind: "image", Src: server.URL + "/image.jpg", Alt: "Image"},
+				{Kind: "mjpeg", Src: server.URL + "/stream.mjpeg", Alt: "Stream"},
+				{Kind: "iframe", Src: server.URL + "/embed.html", Alt: "Embed"},
+			},
+		},
+		{ID: "BCC", Name: "BCC"},
+	})
+	store.FetchImages(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.True(t, fetched["/status.jpg"])
+	assert.True(t, fetched["/image.jpg"])
+	assert.True(t, fetched["/stream.mjpeg"])
+	assert.False(t, fetched["/embed.html"], "iframes are never fetched")
+
+	stream, _ := store.Get("stream")
+	assert.Equal(t, testImage("mjpeg"), stream.Image.Bytes)
+	embed, _ := store.Get("embed")
+	assert.Empty(t, embed.Image.Bytes)
+}
+
+func TestStore_FetchImages_GzipEncoded(t *testing.T) {
+	gzipped := func(b []byte) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		_, _ = gz.Write(b)
+		_ = gz.Close()
+		return buf.Bytes()
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Gzip regardless of Accept-Encoding, as misconfigured origins do
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Header().Set("Content-Encoding", "gzip")
+		if r.Method == "GET" {
+			w.Write(gzipped(testImage("gzip")))
+		}
+	}))
+	defer server.Close()
+
+	store := NewStore(&Canyons{
+		{ID: "LCC", Name: "LCC", Cameras: []Camera{{Kind: "webcam", Src: server.URL + "/cam.jpg", Alt: "Gzip"}}},
+		{ID: "BCC", Name: "BCC"},
+	})
+	store.FetchImages(context.Background())
+
+	entry, ok := store.Get("gzip")
+	require.True(t, ok)
+	assert.Equal(t, testImage("gzip"), entry.Image.Bytes)
+	assert.Equal(t, "image/jpeg", entry.HTTPHeaders.ContentType)
+	assert.Equal(t, int64(len(testImage("gzip"))), entry.HTTPHeaders.ContentLength)
+	_, format, err := image.DecodeConfig(bytes.NewReader(entry.Image.Bytes))
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+
+	t.Run("not negotiated by the transport", func(t *testing.T) {
+		// A request carrying its own Accept-Encoding (or none at all) leaves
+		// the gzip body to us
+		resp := &http.Response{
+			Header: http.Header{"Content-Encoding": {"GZIP"}},
+			Body:   io.NopCloser(bytes.NewReader(gzipped(testImage("raw")))),
+		}
+		b, err := readImageBody(resp, 1<<20)
+		require.NoError(t, err)
+		assert.Equal(t, testImage("raw"), b)
+	})
+
+	t.Run("corrupt gzip", func(t *testing.T) {
+		resp := &http.Response{
+			Header: http.Header{"Content-Encoding": {"gzip"}},
+			Body:   io.NopCloser(bytes.NewReader([]byte("not gzip"))),
+		}
+		_, err := readImageBody(resp, 1<<20)
+		assert.Error(t, err)
+	})
+}
+
+func TestStore_FetchImages_LogsFailuresAtDebug(t *testing.T) {
+	var mu sync.Mutex
+	var lines []string
+	logger.Log = func(msg string) {
+		mu.Lock()
+		defer mu.Unlock()
+		lines = append(lines, msg)
+	}
+	logger.SetUIMode(true)
+	t.Cleanup(func() {
+		logger.SetUIMode(false)
+		logger.Log = nil
+		require.NoError(t, logger.SetLevel("info"))
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	store := NewStore(&Canyons{
+		{ID: "LCC", Name: "LCC", Cameras: []Camera{{Kind: "webcam", Src: server.URL + "/broken.jpg", Alt: "Broken Camera"}}},
+		{ID: "BCC", Name: "BCC"},
+	})
+	failureLogged := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, line := range lines {
+			if strings.Contains(line, `"Broken Camera"`) && strings.Contains(line, "reason=bad_status") &&
+				strings.Contains(line, server.URL+"/broken.jpg") {
+				return true
+			}
+		}
+		return false
+	}
+
+	require.NoError(t, logger.SetLevel("info"))
+	store.FetchImages(context.Background())
+	assert.False(t, failureLogged(), "fetch failures aren't logged at info")
+
+	require.NoError(t, logger.SetLevel("debug"))
+	store.FetchImages(context.Background())
+	assert.True(t, failureLogged(), "fetch failures are logged at debug")
+}
+
+func TestStore_ReadinessPolicy(t *testing.T) {
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/up.jpg" && !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage(r.URL.Path))
+		}
+	}))
+	defer server.Close()
+
+	newStore := func(t *testing.T, opts StoreOptions) *Store {
+		t.Helper()
+		healthy.Store(false)
+		s, err := NewStoreWithError(&Canyons{
+			{
+				ID:   "LCC",
+				Name: "LCC",
+				Cameras: []Camera{
+					{Kind: "webcam", Src: server.URL + "/up.jpg", Alt: "Up"},
+					{Kind: "webcam", Src: server.URL + "/down1.jpg", Alt: "Down 1"},
+					{Kind: "webcam", Src: server.URL + "/down2.jpg", Alt: "Down 2"},
+					{Kind: "webcam", Src: server.URL + "/down3.jpg", Alt: "Down 3"},
+					{Kind: "iframe", Src: server.URL + "/embed", Alt: "Embed"},
+				},
+			},
+			{ID: "BCC", Name: "BCC"},
+		}, opts)
+		require.NoError(t, err)
+		return s
+	}
+
+	t.Run("default is ready after the first cycle", func(t *testing.T) {
+		s := newStore(t, StoreOptions{})
+		s.FetchImages(context.Background())
+		assert.True(t, s.IsReady())
+	})
+
+	t.Run("threshold met", func(t *testing.T) {
+		s := newStore(t, StoreOptions{ReadyMinPercent: 25})
+		s.FetchImages(context.Background())
+		assert.True(t, s.IsReady(), "1 of 4 fetchable cameras is 25%")
+	})
+
+	t.Run("threshold met on a later cycle", func(t *testing.T) {
+		s := newStore(t, StoreOptions{ReadyMinPercent: 50, ReadyMaxWait: time.Minute})
+		s.FetchImages(context.Background())
+		assert.False(t, s.IsReady())
+
+		healthy.Store(true)
+		s.FetchImages(context.Background())
+		assert.True(t, s.IsReady())
+	})
+
+	t.Run("max wait fallback", func(t *testing.T) {
+		s := newStore(t, StoreOptions{ReadyMinPercent: 50, ReadyMaxWait: 50 * time.Millisecond})
+		s.FetchImages(context.Background())
+		assert.False(t, s.IsReady())
+		assert.Eventually(t, s.IsReady, 2*time.Second, 10*time.Millisecond)
+
+		_, ok := s.Get("up")
+		assert.True(t, ok, "Get no longer blocks")
+	})
+}
+
+func TestStore_InitialFetchTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow.jpg" {
+			<-release
+		}
+		if r.Method == "GET" {
+			w.Write(testImage(r.URL.Path))
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	})
+
+	s := NewStoreWithOptions(&Canyons{{ID: "LCC", Name: "LCC", Cameras: []Camera{
+		{Kind: "img", Src: server.URL + "/fast.jpg", Alt: "Fast"},
+		{Kind: "img", Src: server.URL + "/slow.jpg", Alt: "Slow"},
+	}}}, StoreOptions{
+		InitialFetchTimeout: 50 * time.Millisecond,
+		HeadRequestTimeout:  10 * time.Second,
+		GetRequestTimeout:   10 * time.Second,
+	})
+	cycleDone := make(chan struct{})
+	go func() {
+		defer close(cycleDone)
+		s.FetchImages(context.Background())
+	}()
+
+	assert.Eventually(t, s.IsReady, time.Second, 10*time.Millisecond, "ready within the initial-fetch deadline")
+	fast, ok := s.Get(s.entries[0].ID)
+	require.True(t, ok, "Get no longer blocks")
+	assert.Equal(t, testImage("/fast.jpg"), fast.Image.Bytes)
+	assert.Equal(t, []string{"Slow"}, s.camerasWithoutImage())
+
+	close(release)
+	<-cycleDone
+	slow, _ := s.Get(s.entries[1].ID)
+	assert.Equal(t, testImage("/slow.jpg"), slow.Image.Bytes, "late cameras still fill in")
+}
+
+func TestStore_FetchImages_QueueMetrics(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		if r.Method == "GET" {
+			w.Write(testImage(r.URL.Path))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	var cameras []Camera
+	for i := range 3 {
+		cameras = append(cameras, Camera{Kind: "img", Src: fmt.Sprintf("%s/%d.jpg", server.URL, i), Alt: fmt.Sprintf("Queued %d", i)})
+	}
+	s := NewStoreWithOptions(&Canyons{{ID: "LCC", Cameras: cameras}}, StoreOptions{FetchConcurrency: 1})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.FetchImages(context.Background())
+	}()
+
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(metrics.FetchWorkersBusy) == 1 && testutil.ToFloat64(metrics.FetchQueueDepth) > 0
+	}, 2*time.Second, 5*time.Millisecond, "the one worker is busy while the rest queue")
+
+	close(release)
+	<-done
+	assert.Zero(t, testutil.ToFloat64(metrics.FetchQueueDepth), "the queue drains")
+	assert.Zero(t, testutil.ToFloat64(metrics.FetchWorkersBusy))
+}
+
+func TestStore_FetchImages_UserAgent(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string][]string{} // path -> "METHOD UA"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.URL.Path] = append(seen[r.URL.Path], r.Method+" "+r.UserAgent())
+		mu.Unlock()
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("image"))
+		}
+	}))
+	defer server.Close()
+
+	canyons := &Canyons{
+		{
+			ID:   "LCC",
+			Name: "LCC",
+			Cameras: []Camera{
+				{Kind: "webcam", Src: server.URL + "/honest.jpg", Alt: "Honest"},
+				{Kind: "webcam", Src: server.URL + "/spoofed.jpg", Alt: "Spoofed", BrowserUserAgent: true},
+			},
+		},
+		{ID: "BCC", Name: "BCC"},
+	}
+
+	store := NewStore(canyons)
+	store.SetUserAgent("lcc.live/test")
+	store.FetchImages(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"HEAD lcc.live/test", "GET lcc.live/test"}, seen["/honest.jpg"])
+	assert.Equal(t, []string{"HEAD " + browserUserAgent, "GET " + browserUserAgent}, seen["/spoofed.jpg"])
+}
+
+func TestStore_FetchImages_ImageDimensions(t *testing.T) {
+	var jpegImage, pngImage bytes.Buffer
+	require.NoError(t, jpeg.Encode(&jpegImage, image.NewRGBA(image.Rect(0, 0, 64, 48)), nil))
+	require.NoError(t, png.Encode(&pngImage, image.NewRGBA(image.Rect(0, 0, 16, 9))))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cam.png" {
+			w.Write(pngImage.Bytes())
+		} else {
+			w.Write(jpegImage.Bytes())
+		}
+	}))
+	defer server.Close()
+
+	canyons := &Canyons{
+		{
+			ID:   "LCC",
+			Name: "LCC",
+			Cameras: []Camera{
+				{Kind: "img", Src: server.URL + "/cam.jpg"},
+				{Kind: "img", Src: server.URL + "/cam.png"},
+			},
+		},
+	}
+	store := NewStore(canyons)
+	store.FetchImages(context.Background())
+
+	for _, tt := range []struct {
+		camera        int
+		width, height int
+	}{
+		{0, 64, 48},
+		{1, 16, 9},
+	} {
+		entry, exists := store.Get((*canyons)[0].Cameras[tt.camera].ID)
+		require.True(t, exists)
+		assert.NotEmpty(t, entry.Image.Bytes)
+		assert.Equal(t, tt.width, entry.Image.Width, entry.Camera.Src)
+		assert.Equal(t, tt.height, entry.Image.Height, entry.Camera.Src)
+	}
+}
+
+func TestStore_FetchImages_PNGToJPEG(t *testing.T) {
+	// Noise compresses poorly as PNG, like a real camera frame
+	rng := rand.New(rand.NewPCG(1, 2))
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for i := range img.Pix {
+		img.Pix[i] = byte(rng.IntN(256))
+	}
+	var pngImage bytes.Buffer
+	require.NoError(t, png.Encode(&pngImage, img))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(pngImage.Bytes())
+	}))
+	defer server.Close()
+
+	newPNGStore := func() *Store {
+		return NewStore(&Canyons{{ID: "LCC", Name: "LCC", Cameras: []Camera{{Kind: "img", Src: server.URL + "/cam.png", Alt: "PNG Camera"}}}})
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		store := newPNGStore()
+		store.FetchImages(context.Background())
+
+		entry, exists := store.Get(store.entries[0].ID)
+		require.True(t, exists)
+		assert.Equal(t, pngImage.Bytes(), entry.Image.Bytes)
+		assert.Equal(t, "image/png", entry.HTTPHeaders.ContentType)
+	})
+
+	t.Run("re-encodes as JPEG", func(t *testing.T) {
+		store := newPNGStore()
+		store.SetPNGToJPEGQuality(75)
+		store.FetchImages(context.Background())
+
+		entry, exists := store.Get(store.entries[0].ID)
+		require.True(t, exists)
+		assert.Equal(t, "image/jpeg", entry.HTTPHeaders.ContentType)
+		assert.Equal(t, int64(len(entry.Image.Bytes)), entry.HTTPHeaders.ContentLength)
+		assert.Less(t, len(entry.Image.Bytes), pngImage.Len(), "stored bytes should shrink")
+		_, format, err := image.DecodeConfig(bytes.NewReader(entry.Image.Bytes))
+		require.NoError(t, err)
+		assert.Equal(t, "jpeg", format)
+		assert.Equal(t, 64, entry.Image.Width)
+
+		// An unchanged frame keeps sharing the stored image
+		store.FetchImages(context.Background())
+		again, _ := store.Get(entry.ID)
+		assert.Same(t, entry.Image, again.Image)
+	})
+}
+
+func TestStore_FetchImages_RejectsUndecodableImages(t *testing.T) {
+	var body atomic.Value
+	body.Store(testImage("good frame"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The upstream Content-Type is ignored in favor of the detected format
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(body.Load().([]byte))
+	}))
+	defer server.Close()
+
+	store := NewStore(&Canyons{{ID: "LCC", Name: "LCC", Cameras: []Camera{{Kind: "img", Src: server.URL + "/cam.jpg", Alt: "Decode Test"}}}})
+	origin := metrics.ExtractOrigin(server.URL)
+	id := store.entries[0].ID
+
+	store.FetchImages(context.Background())
+	entry, exists := store.Get(id)
+	require.True(t, exists)
+	assert.Equal(t, testImage("good frame"), entry.Image.Bytes)
+	assert.Equal(t, "image/jpeg", entry.HTTPHeaders.ContentType)
+
+	for _, bad := range [][]byte{
+		[]byte("<html>503 Service Unavailable</html>"),
+		testImage("good frame")[:10], // truncated before the frame header
+	} {
+		body.Store(bad)
+		before := testutil.ToFloat64(metrics.OriginErrorsByType.WithLabelValues(origin, "decode_error"))
+		store.FetchImages(context.Background())
+
+		entry, exists = store.Get(id)
+		require.True(t, exists)
+		assert.Equal(t, testImage("good frame"), entry.Image.Bytes, "previous good image is retained")
+		assert.Equal(t, "image/jpeg", entry.HTTPHeaders.ContentType)
+		assert.Equal(t, before+1, testutil.ToFloat64(metrics.OriginErrorsByType.WithLabelValues(origin, "decode_error")))
+	}
+}
+
+func TestStore_FetchImages_MJPEG(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary=frame")
+		if r.Method != "GET" {
+			return
+		}
+		// Stream frames until the client hangs up, like a real camera
+		for i := 1; r.Context().Err() == nil; i++ {
+			frame := testImage(fmt.Sprintf("frame %d", i))
+			fmt.Fprintf(w, "--frame\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\n\r\n", len(frame))
+			w.Write(frame)
+			w.Write([]byte("\r\n"))
+			w.(http.Flusher).Flush()
+			time.Sleep(10 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	store := NewStore(&Canyons{{ID: "LCC", Name: "LCC", Cameras: []Camera{{Kind: "mjpeg", Src: server.URL + "/stream", Alt: "Stream Camera"}}}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	store.FetchImages(ctx)
+	assert.Less(t, time.Since(start), 2*time.Second, "fetch should not wait for the stream to end")
+
+	entry, exists := store.Get(store.entries[0].ID)
+	require.True(t, exists)
+	assert.Equal(t, testImage("frame 1"), entry.Image.Bytes)
+	assert.Equal(t, "image/jpeg", entry.HTTPHeaders.ContentType)
+	assert.Equal(t, int64(len(testImage("frame 1"))), entry.HTTPHeaders.ContentLength)
+}
+
+func TestStore_FetchImages_ConcurrencyCap(t *testing.T) {
+	const limit = 4
+	var inFlight, maxInFlight atomic.Int32
+	var maxGauge atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			old := maxInFlight.Load()
+			if n <= old || maxInFlight.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		if g := int64(testutil.ToFloat64(metrics.ConcurrentFetches)); g > maxGauge.Load() {
+			maxGauge.Store(g)
+		}
+		time.Sleep(2 * time.Millisecond)
+		w.Write(testImage("image " + r.URL.Path))
+	}))
+	defer server.Close()
+
+	cameras := make([]Camera, 100)
+	for i := range cameras {
+		cameras[i] = Camera{Kind: "img", Src: server.URL + "/" + strconv.Itoa(i) + ".jpg"}
+	}
+	store := NewStore(&Canyons{{ID: "LCC", Name: "LCC", Cameras: cameras}})
+	store.SetFetchConcurrency(limit)
+	store.FetchImages(context.Background())
+
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(limit))
+	assert.Greater(t, maxInFlight.Load(), int32(1), "fetches should still run concurrently")
+	assert.LessOrEqual(t, maxGauge.Load(), int64(limit))
+	for _, entry := range store.Entries() {
+		assert.NotEmpty(t, entry.Image.Bytes, "every camera is fetched")
+	}
+}
+
+func TestStoreOptions_Defaults(t *testing.T) {
+	store := NewStore(&Canyons{{ID: "LCC", Name: "LCC"}})
+	assert.Equal(t, DefaultStoreOptions(), store.options)
+	assert.Equal(t, defaultHTTPClientTimeout, store.client.Timeout)
+
+	store = NewStoreWithOptions(&Canyons{{ID: "LCC", Name: "LCC"}}, StoreOptions{GetRequestTimeout: 10 * time.Second, FetchConcurrency: -1})
+	assert.Equal(t, 10*time.Second, store.options.GetRequestTimeout)
+	assert.Equal(t, defaultHeadRequestTimeout, store.options.HeadRequestTimeout, "unset fields use defaults")
+	assert.Equal(t, DefaultFetchConcurrency, store.options.FetchConcurrency, "invalid fields use defaults")
+}
+
+func TestStore_FetchImages_CustomOptions(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			time.Sleep(delay) // a camera behind a slow link
+		}
+		w.Write(testImage("slow camera"))
+	}))
+	defer server.Close()
+
+	fetch := func(opts StoreOptions) (EntrySnapshot, SyncStatus) {
+		store := NewStoreWithOptions(&Canyons{{ID: "LCC", Name: "LCC", Cameras: []Camera{{Kind: "img", Src: server.URL + "/cam.jpg", Alt: "Slow Camera"}}}}, opts)
+		var status SyncStatus
+		store.OnSync(func(result SyncResult) { status = result.Cameras[0].Status })
+		store.FetchImages(context.Background())
+		entry, _ := store.Get(store.entries[0].ID)
+		return entry, status
+	}
+
+	t.Run("GET timeout shorter than the camera", func(t *testing.T) {
+		_, status := fetch(StoreOptions{GetRequestTimeout: delay / 4})
+		assert.Equal(t, SyncError, status)
+	})
+
+	t.Run("GET timeout longer than the camera", func(t *testing.T) {
+		entry, status := fetch(StoreOptions{GetRequestTimeout: 5 * delay, HTTPClientTimeout: 10 * delay})
+		assert.Equal(t, SyncChanged, status)
+		assert.Equal(t, testImage("slow camera"), entry.Image.Bytes)
+	})
+
+	t.Run("client timeout caps the GET timeout", func(t *testing.T) {
+		_, status := fetch(StoreOptions{GetRequestTimeout: 5 * delay, HTTPClientTimeout: delay / 4})
+		assert.Equal(t, SyncError, status)
+	})
+
+	t.Run("max image size", func(t *testing.T) {
+		// Truncated to within the JPEG header, so it's rejected as undecodable
+		_, status := fetch(StoreOptions{MaxImageSize: 8})
+		assert.Equal(t, SyncError, status)
+	})
+}
+
+func TestNewTransport(t *testing.T) {
+	transport := newTransport(3, TransportOptions{})
+	assert.Equal(t, defaultMaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 3*defaultMaxIdleConnsPerHost, transport.MaxIdleConns, "pool is sized for every origin")
+	assert.Equal(t, defaultIdleConnTimeout, transport.IdleConnTimeout)
+	assert.True(t, transport.ForceAttemptHTTP2)
+	assert.True(t, transport.TLSClientConfig.InsecureSkipVerify)
+
+	transport = newTransport(2, TransportOptions{MaxIdleConnsPerHost: 16, IdleConnTimeout: time.Minute})
+	assert.Equal(t, 16, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 32, transport.MaxIdleConns)
+	assert.Equal(t, time.Minute, transport.IdleConnTimeout)
+}
+
+// selfSignedCert returns a certificate for 127.0.0.1 that no pool trusts
+func selfSignedCert(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "self-signed camera"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(crand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestStore_FetchImages_TLSVerification(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage(r.URL.Path))
+		}
+	})
+	trusted := httptest.NewTLSServer(handler)
+	defer trusted.Close()
+	selfSigned := httptest.NewUnstartedServer(handler)
+	selfSigned.TLS = &tls.Config{Certificates: []tls.Certificate{selfSignedCert(t)}}
+	selfSigned.StartTLS()
+	defer selfSigned.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(trusted.Certificate())
+
+	newStore := func(opts TransportOptions) *Store {
+		s := NewStore(&Canyons{
+			{
+				ID:   "LCC",
+				Name: "LCC",
+				Cameras: []Camera{
+					{Kind: "img", Src: trusted.URL + "/valid.jpg", Alt: "Valid"},
+					{Kind: "img", Src: selfSigned.URL + "/self-signed.jpg", Alt: "Self Signed"},
+					{Kind: "img", Src: selfSigned.URL + "/exempt.jpg", Alt: "Exempt", InsecureTLS: true},
+				},
+			},
+			{ID: "BCC", Name: "BCC"},
+		})
+		s.SetTransportOptions(opts)
+		s.FetchImages(context.Background())
+		return s
+	}
+	fetched := func(s *Store, id string) bool {
+		entry, ok := s.Get(id)
+		require.True(t, ok)
+		return len(entry.Image.Bytes) > 0
+	}
+
+	t.Run("verified", func(t *testing.T) {
+		s := newStore(TransportOptions{VerifyTLS: true, RootCAs: roots})
+		assert.True(t, fetched(s, "valid"), "a valid certificate verifies")
+		assert.False(t, fetched(s, "self-signed"), "a self-signed certificate is rejected")
+		assert.True(t, fetched(s, "exempt"), "InsecureTLS skips verification")
+	})
+
+	t.Run("lenient by default", func(t *testing.T) {
+		s := newStore(TransportOptions{})
+		assert.True(t, fetched(s, "valid"))
+		assert.True(t, fetched(s, "self-signed"))
+		assert.True(t, fetched(s, "exempt"))
+	})
+}
+
+func TestStore_FetchImages_ReusesConnections(t *testing.T) {
+	var newConns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(testImage("image " + r.URL.Path))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			newConns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	cameras := make([]Camera, 20)
+	for i := range cameras {
+		cameras[i] = Camera{Kind: "img", Src: server.URL + "/" + strconv.Itoa(i) + ".jpg"}
+	}
+	store := NewStore(&Canyons{{ID: "LCC", Name: "LCC", Cameras: cameras}})
+	store.SetFetchConcurrency(4)
+
+	store.FetchImages(context.Background())
+	firstCycle := newConns.Load()
+	assert.LessOrEqual(t, firstCycle, int32(2*4), "each worker reuses its connection across cameras")
+
+	store.FetchImages(context.Background())
+	assert.LessOrEqual(t, newConns.Load()-firstCycle, int32(2), "idle connections are kept between cycles")
+}
+
+// assertExclusive checks that while write holds its lock, neither read nor
+// another write can proceed
+func assertExclusive(t *testing.T, read, write func(fn func())) {
+	t.Helper()
+	inWrite := make(chan struct{})
+	release := make(chan struct{})
+	go write(func() {
+		close(inWrite)
+		<-release
+	})
+	<-inWrite
+
+	var readDone, writeDone atomic.Bool
+	go read(func() { readDone.Store(true) })
+	go write(func() { writeDone.Store(true) })
+	time.Sleep(20 * time.Millisecond)
+	assert.False(t, readDone.Load(), "read ran during a write")
+	assert.False(t, writeDone.Load(), "two writes ran at once")
+
+	close(release)
+	assert.Eventually(t, func() bool { return readDone.Load() && writeDone.Load() }, time.Second, time.Millisecond)
+}
+
+func TestEntry_WriteIsExclusive(t *testing.T) {
+	entry := &Entry{}
+	assertExclusive(t,
+		func(fn func()) { entry.Read(func(*Entry) { fn() }) },
+		func(fn func()) { entry.Write(func(*Entry) { fn() }) },
+	)
+
+	// Concurrent writes don't lose updates (and are race-free under -race)
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			entry.Write(func(e *Entry) { e.ResolvedURL += "x" })
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, entry.ResolvedURL, 50)
+}
+
+func TestStore_WriteIsExclusive(t *testing.T) {
+	s := NewStore(&Canyons{{ID: "LCC", Name: "LCC"}, {ID: "BCC", Name: "BCC"}})
+	assertExclusive(t,
+		func(fn func()) { s.Read(func(*Store) { fn() }) },
+		func(fn func()) { s.Write(func(*Store) { fn() }) },
+	)
+}
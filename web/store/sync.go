@@ -0,0 +1,142 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrSyncInProgress is returned by Sync when a FetchImages cycle is already
+// running
+var ErrSyncInProgress = errors.New("sync already in progress")
+
+// SyncStatus is the outcome of fetching a single camera during a sync
+type SyncStatus string
+
+const (
+	SyncChanged   SyncStatus = "changed"
+	SyncUnchanged SyncStatus = "unchanged"
+	SyncError     SyncStatus = "error"
+	// SyncCancelled means the fetch was abandoned because the sync's context
+	// was cancelled; it isn't counted as an error
+	SyncCancelled SyncStatus = "cancelled"
+)
+
+// CameraSyncResult is the outcome of fetching one camera
+type CameraSyncResult struct {
+	ID     string
+	Name   string
+	Canyon string
+	Status SyncStatus
+	// HTTPStatus is the origin's response to the GET, if it answered one
+	HTTPStatus int
+	// Err is why a SyncError fetch failed
+	Err error
+}
+
+// SyncResult summarizes a FetchImages cycle
+type SyncResult struct {
+	Duration  time.Duration
+	Changed   int
+	Unchanged int
+	Errors    int
+	Cameras   []CameraSyncResult // fetched cameras, in config order
+}
+
+type syncSubscriber struct {
+	id int
+	fn func(SyncResult)
+}
+
+// newSyncResult tallies per-camera results, dropping slots for cameras that
+// weren't fetched (iframes and disabled cameras)
+func newSyncResult(duration time.Duration, results []CameraSyncResult) SyncResult {
+	result := SyncResult{Duration: duration, Cameras: make([]CameraSyncResult, 0, len(results))}
+	for _, r := range results {
+		if r.ID == "" {
+			continue
+		}
+		switch r.Status {
+		case SyncChanged:
+			result.Changed++
+		case SyncUnchanged:
+			result.Unchanged++
+		case SyncError:
+			result.Errors++
+		}
+		result.Cameras = append(result.Cameras, r)
+	}
+	return result
+}
+
+// OnSync registers fn to be called after every FetchImages cycle, in
+// registration order. Subscribers run on the fetching goroutine, so slow
+// work should be handed off. The returned func unsubscribes.
+func (s *Store) OnSync(fn func(SyncResult)) (unsubscribe func()) {
+	s.syncSubscribersMu.Lock()
+	defer s.syncSubscribersMu.Unlock()
+
+	s.nextSubscriberID++
+	id := s.nextSubscriberID
+	s.syncSubscribers = append(s.syncSubscribers, syncSubscriber{id: id, fn: fn})
+
+	return func() {
+		s.syncSubscribersMu.Lock()
+		defer s.syncSubscribersMu.Unlock()
+		for i, sub := range s.syncSubscribers {
+			if sub.id == id {
+				s.syncSubscribers = append(s.syncSubscribers[:i:i], s.syncSubscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// SetSyncCallback sets a callback to be called after each sync, replacing
+// any callback previously set this way. Other OnSync subscribers are
+// unaffected.
+func (s *Store) SetSyncCallback(cb func(duration time.Duration, changed, unchanged, errors int)) {
+	var unsubscribe func()
+	if cb != nil {
+		unsubscribe = s.OnSync(func(r SyncResult) {
+			cb(r.Duration, r.Changed, r.Unchanged, r.Errors)
+		})
+	}
+
+	s.syncSubscribersMu.Lock()
+	previous := s.syncCallbackUnsubscribe
+	s.syncCallbackUnsubscribe = unsubscribe
+	s.syncSubscribersMu.Unlock()
+
+	if previous != nil {
+		previous()
+	}
+}
+
+// notifySync calls every subscriber with the result of a sync
+func (s *Store) notifySync(result SyncResult) {
+	// Copy so subscribers can (un)subscribe without deadlocking
+	s.syncSubscribersMu.Lock()
+	subscribers := append([]syncSubscriber(nil), s.syncSubscribers...)
+	s.syncSubscribersMu.Unlock()
+
+	for _, sub := range subscribers {
+		sub.fn(result)
+	}
+}
+
+// Sync runs a FetchImages cycle now, rather than waiting for the next tick,
+// and returns its result. It returns ErrSyncInProgress instead if a cycle is
+// already running, so forced syncs can't pile up behind the ticker.
+func (s *Store) Sync(ctx context.Context) (SyncResult, error) {
+	// Claim the cycle up front, so concurrent Syncs can't both pass the check
+	if !s.syncing.CompareAndSwap(false, true) {
+		return SyncResult{}, ErrSyncInProgress
+	}
+	defer s.syncing.Store(false)
+
+	result, _, _ := s.fetchGroup.Do(fetchCycleKey, func() (any, error) {
+		return s.fetchCycle(ctx), nil
+	})
+	return result.(SyncResult), nil
+}
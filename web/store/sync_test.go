@@ -0,0 +1,165 @@
+package store
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stefanpenner/lcc-live/web/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSyncTestStore(t *testing.T) *Store {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken.jpg" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage("image"))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return NewStore(&Canyons{
+		{
+			ID:   "LCC",
+			Name: "LCC",
+			Cameras: []Camera{
+				{Kind: "webcam", Src: server.URL + "/ok.jpg", Alt: "OK"},
+				{Kind: "webcam", Src: server.URL + "/broken.jpg", Alt: "Broken"},
+				{Kind: "iframe", Src: server.URL + "/embed.html", Alt: "Embed"},
+			},
+		},
+		{ID: "BCC", Name: "BCC"},
+	})
+}
+
+func TestStore_OnSync_MultipleSubscribers(t *testing.T) {
+	store := newSyncTestStore(t)
+
+	var first, second []SyncResult
+	store.OnSync(func(r SyncResult) { first = append(first, r) })
+	store.OnSync(func(r SyncResult) { second = append(second, r) })
+
+	store.FetchImages(context.Background())
+
+	require.Len(t, first, 1)
+	require.Len(t, second, 1)
+	result := first[0]
+	assert.Equal(t, result, second[0])
+
+	assert.Equal(t, 1, result.Changed)
+	assert.Equal(t, 0, result.Unchanged)
+	assert.Equal(t, 1, result.Errors)
+	assert.Positive(t, result.Duration)
+
+	require.Len(t, result.Cameras, 2, "iframes aren't fetched")
+	assert.Equal(t, "OK", result.Cameras[0].Name)
+	assert.Equal(t, SyncChanged, result.Cameras[0].Status)
+	assert.Equal(t, "LCC", result.Cameras[0].Canyon)
+	assert.Equal(t, "Broken", result.Cameras[1].Name)
+	assert.Equal(t, SyncError, result.Cameras[1].Status)
+}
+
+func TestStore_OnSync_Unsubscribe(t *testing.T) {
+	store := newSyncTestStore(t)
+
+	var kept, removed int
+	store.OnSync(func(SyncResult) { kept++ })
+	unsubscribe := store.OnSync(func(SyncResult) { removed++ })
+
+	store.FetchImages(context.Background())
+	unsubscribe()
+	unsubscribe() // idempotent
+	store.FetchImages(context.Background())
+
+	assert.Equal(t, 2, kept)
+	assert.Equal(t, 1, removed)
+}
+
+func TestStore_SetSyncCallback(t *testing.T) {
+	store := newSyncTestStore(t)
+
+	var subscriberCalls, oldCalls int
+	var gotChanged, gotErrors int
+	var gotDuration time.Duration
+	store.OnSync(func(SyncResult) { subscriberCalls++ })
+	store.SetSyncCallback(func(time.Duration, int, int, int) { oldCalls++ })
+	store.SetSyncCallback(func(d time.Duration, changed, _, errors int) {
+		gotDuration, gotChanged, gotErrors = d, changed, errors
+	})
+
+	store.FetchImages(context.Background())
+
+	assert.Equal(t, 0, oldCalls, "SetSyncCallback replaces the previous callback")
+	assert.Equal(t, 1, subscriberCalls, "other subscribers are unaffected")
+	assert.Equal(t, 1, gotChanged)
+	assert.Equal(t, 1, gotErrors)
+	assert.Positive(t, gotDuration)
+}
+
+func TestStore_FetchImages_SharesOverlappingCycles(t *testing.T) {
+	var heads atomic.Int32
+	arrived := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "HEAD" {
+			heads.Add(1)
+			select {
+			case arrived <- struct{}{}:
+			default:
+			}
+			<-release
+			return
+		}
+		w.Write(testImage("image"))
+	}))
+	t.Cleanup(server.Close)
+
+	store := NewStore(&Canyons{
+		{
+			ID:   "LCC",
+			Name: "LCC",
+			Cameras: []Camera{
+				{Kind: "webcam", Src: server.URL + "/a.jpg", Alt: "A"},
+				{Kind: "webcam", Src: server.URL + "/b.jpg", Alt: "B"},
+			},
+		},
+		{ID: "BCC", Name: "BCC"},
+	})
+	var syncs atomic.Int32
+	store.OnSync(func(SyncResult) { syncs.Add(1) })
+	cyclesBefore := testutil.ToFloat64(metrics.StoreFetchCyclesTotal)
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store.FetchImages(context.Background())
+		}()
+	}
+
+	// Hold the first cycle open until every caller has had time to join it
+	<-arrived
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(2), heads.Load(), "the origin sees one round of requests")
+	assert.Equal(t, int32(1), syncs.Load())
+	assert.Equal(t, cyclesBefore+1, testutil.ToFloat64(metrics.StoreFetchCyclesTotal))
+
+	// Once it's finished, the next call starts a fresh cycle
+	store.FetchImages(context.Background())
+	assert.Equal(t, int32(4), heads.Load())
+}
@@ -0,0 +1,90 @@
+package store
+
+import "time"
+
+// Frame is a historical image retained for timelapse playback
+type Frame struct {
+	Image       *Image
+	ContentType string
+	FetchedAt   time.Time
+}
+
+// frameRing is a fixed-capacity ring buffer of frames. It is not safe for
+// concurrent use; the owning Entry's mutex guards it.
+type frameRing struct {
+	frames []Frame
+	next   int
+	count  int
+}
+
+func newFrameRing(capacity int) *frameRing {
+	return &frameRing{frames: make([]Frame, capacity)}
+}
+
+// push appends a frame, overwriting the oldest once the ring is full
+func (r *frameRing) push(f Frame) {
+	r.frames[r.next] = f
+	r.next = (r.next + 1) % len(r.frames)
+	if r.count < len(r.frames) {
+		r.count++
+	}
+}
+
+// snapshot returns the retained frames ordered oldest to newest
+func (r *frameRing) snapshot() []Frame {
+	result := make([]Frame, 0, r.count)
+	start := (r.next - r.count + len(r.frames)) % len(r.frames)
+	for i := 0; i < r.count; i++ {
+		result = append(result, r.frames[(start+i)%len(r.frames)])
+	}
+	return result
+}
+
+// EnableTimelapse retains the last n changed frames for every fetchable
+// camera. It must be called before the first FetchImages; n <= 0 is a no-op.
+func (s *Store) EnableTimelapse(n int) {
+	if n <= 0 {
+		return
+	}
+	s.mu.Lock()
+	s.timelapseFrames = n
+	s.mu.Unlock()
+	for _, entry := range s.allEntries() {
+		if !entry.Camera.IsFetchable() {
+			continue
+		}
+		entry.Write(func(entry *Entry) {
+			entry.frames = newFrameRing(n)
+		})
+	}
+}
+
+// Frames returns the retained timelapse frames for a camera (by ID or slug),
+// ordered oldest to newest. The bool reports whether the camera exists.
+func (s *Store) Frames(cameraID string) ([]Frame, bool) {
+	entry, exists := s.lookup(cameraID)
+	if !exists {
+		return nil, false
+	}
+
+	var frames []Frame
+	entry.Read(func(entry *Entry) {
+		if entry.frames != nil {
+			frames = entry.frames.snapshot()
+		}
+	})
+	return frames, true
+}
+
+// Frame returns the retained frame fetched at the given unix timestamp, in
+// nanoseconds. Nanoseconds keep two frames fetched within the same second
+// apart, so a frame's timestamp always names the same bytes.
+func (s *Store) Frame(cameraID string, timestamp int64) (Frame, bool) {
+	frames, _ := s.Frames(cameraID)
+	for _, frame := range frames {
+		if frame.FetchedAt.UnixNano() == timestamp {
+			return frame, true
+		}
+	}
+	return Frame{}, false
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrameRing_RetainsAtMostN(t *testing.T) {
+	ring := newFrameRing(3)
+	assert.Empty(t, ring.snapshot())
+
+	base := time.Unix(1700000000, 0)
+	for i := 0; i < 5; i++ {
+		ring.push(Frame{FetchedAt: base.Add(time.Duration(i) * time.Second)})
+	}
+
+	frames := ring.snapshot()
+	require.Len(t, frames, 3)
+	// Oldest two were dropped, remaining are ordered oldest to newest
+	assert.Equal(t, base.Add(2*time.Second), frames[0].FetchedAt)
+	assert.Equal(t, base.Add(3*time.Second), frames[1].FetchedAt)
+	assert.Equal(t, base.Add(4*time.Second), frames[2].FetchedAt)
+}
+
+func TestFrameRing_PartiallyFilled(t *testing.T) {
+	ring := newFrameRing(4)
+	base := time.Unix(1700000000, 0)
+	ring.push(Frame{FetchedAt: base})
+	ring.push(Frame{FetchedAt: base.Add(time.Second)})
+
+	frames := ring.snapshot()
+	require.Len(t, frames, 2)
+	assert.Equal(t, base, frames[0].FetchedAt)
+	assert.Equal(t, base.Add(time.Second), frames[1].FetchedAt)
+}
+
+func TestStore_Timelapse_FetchImages(t *testing.T) {
+	var counter atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		if r.Method == "GET" {
+			w.Write(testImage(fmt.Sprintf("frame %d", counter.Add(1))))
+		}
+	}))
+	defer server.Close()
+
+	canyons := &Canyons{
+		{
+			ID:   "LCC",
+			Name: "LCC",
+			Cameras: []Camera{
+				{Kind: "webcam", Src: server.URL + "/test.jpg", Alt: "Test Camera"},
+				{Kind: "iframe", Src: "http://example.com/iframe.html", Alt: "Iframe Camera"},
+			},
+		},
+		{ID: "BCC", Name: "BCC"},
+	}
+
+	s := NewStore(canyons)
+	s.EnableTimelapse(2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		s.FetchImages(ctx)
+	}
+
+	frames, exists := s.Frames(s.entries[0].ID)
+	require.True(t, exists)
+	require.Len(t, frames, 2, "should retain at most N frames")
+	assert.Equal(t, testImage("frame 2"), frames[0].Image.Bytes)
+	assert.Equal(t, testImage("frame 3"), frames[1].Image.Bytes)
+	assert.Equal(t, "image/jpeg", frames[1].ContentType)
+
+	frame, ok := s.Frame(s.entries[0].ID, frames[1].FetchedAt.UnixNano())
+	require.True(t, ok)
+	assert.Equal(t, testImage("frame 3"), frame.Image.Bytes)
+	frame, ok = s.Frame(s.entries[0].ID, frames[0].FetchedAt.UnixNano())
+	require.True(t, ok)
+	assert.Equal(t, testImage("frame 2"), frame.Image.Bytes, "frames fetched within a second stay apart")
+
+	// iframe cameras don't get a buffer
+	frames, exists = s.Frames(s.entries[1].ID)
+	require.True(t, exists)
+	assert.Empty(t, frames)
+
+	_, exists = s.Frames("unknown")
+	assert.False(t, exists)
+}
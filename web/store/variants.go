@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/stefanpenner/lcc-live/web/metrics"
+)
+
+// ImageVariant is the latest image fetched from one of a camera's Sources
+type ImageVariant struct {
+	Src         string
+	MaxWidth    int
+	ContentType string
+	Image       *Image
+}
+
+// retainVariants drops variants whose source is no longer configured
+func retainVariants(variants []ImageVariant, sources []ImageSource) []ImageVariant {
+	var kept []ImageVariant
+	for _, variant := range variants {
+		if slices.ContainsFunc(sources, func(source ImageSource) bool { return source.Src == variant.Src }) {
+			kept = append(kept, variant)
+		}
+	}
+	return kept
+}
+
+// fetchVariants fetches each of camera's Sources. A source that fails keeps
+// its previous image, so a flaky thumbnail doesn't take the variant away.
+func (s *Store) fetchVariants(ctx context.Context, entry *Entry, camera *Camera) {
+	if len(camera.Sources) == 0 {
+		return
+	}
+	previous := entry.ShallowSnapshot().Variants
+
+	variants := make([]ImageVariant, 0, len(camera.Sources))
+	for _, source := range camera.Sources {
+		variant, err := s.fetchVariant(ctx, camera, source)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logFetchError(camera, source.Src, metrics.ExtractOrigin(source.Src), "variant", err)
+			if i := slices.IndexFunc(previous, func(v ImageVariant) bool { return v.Src == source.Src }); i >= 0 {
+				old := previous[i]
+				old.MaxWidth = source.MaxWidth
+				variants = append(variants, old)
+			}
+			continue
+		}
+		variants = append(variants, variant)
+	}
+
+	entry.Write(func(entry *Entry) {
+		entry.variants = variants
+	})
+}
+
+// fetchVariant fetches and validates the image at one alternate source
+func (s *Store) fetchVariant(ctx context.Context, camera *Camera, source ImageSource) (ImageVariant, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.options.GetRequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.Src, nil)
+	if err != nil {
+		return ImageVariant{}, err
+	}
+	s.setRequestHeaders(req, camera)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return ImageVariant{}, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return ImageVariant{}, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	imageBytes, err := readImageBody(resp, s.options.MaxImageSize)
+	if err != nil {
+		return ImageVariant{}, err
+	}
+	contentType, width, height, err := decodeImageHeader(imageBytes)
+	if err != nil {
+		return ImageVariant{}, err
+	}
+	return ImageVariant{
+		Src:         source.Src,
+		MaxWidth:    source.MaxWidth,
+		ContentType: contentType,
+		Image: &Image{
+			Bytes:  imageBytes,
+			ETag:   contentETag(imageBytes),
+			Src:    source.Src,
+			Width:  width,
+			Height: height,
+		},
+	}, nil
+}
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_FetchImages_Variants(t *testing.T) {
+	var thumbDown atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/thumb.jpg" && thumbDown.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.Method == "GET" {
+			w.Write(testImage(r.URL.Path))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	camera := Camera{Kind: "img", Src: server.URL + "/full.jpg", Alt: "Camera", Sources: []ImageSource{
+		{Src: server.URL + "/thumb.jpg", MaxWidth: 320},
+		{Src: server.URL + "/medium.jpg", MaxWidth: 800},
+	}}
+	store := NewStore(&Canyons{{ID: "LCC", Cameras: []Camera{camera}}})
+	store.FetchImages(context.Background())
+
+	entry, ok := store.Get(store.entries[0].ID)
+	require.True(t, ok)
+	require.Len(t, entry.Variants, 2)
+	assert.Equal(t, testImage("/thumb.jpg"), entry.Variants[0].Image.Bytes)
+	assert.Equal(t, 320, entry.Variants[0].MaxWidth)
+	assert.Equal(t, "image/jpeg", entry.Variants[0].ContentType)
+	assert.Equal(t, testImage("/medium.jpg"), entry.Variants[1].Image.Bytes)
+	assert.Equal(t, testImage("/full.jpg"), entry.Image.Bytes, "the main image is unchanged")
+
+	t.Run("a failing source keeps its previous image", func(t *testing.T) {
+		thumbDown.Store(true)
+		t.Cleanup(func() { thumbDown.Store(false) })
+		store.FetchImages(context.Background())
+
+		entry, _ := store.Get(store.entries[0].ID)
+		require.Len(t, entry.Variants, 2)
+		assert.Equal(t, testImage("/thumb.jpg"), entry.Variants[0].Image.Bytes)
+	})
+
+	t.Run("reload drops variants of removed sources", func(t *testing.T) {
+		camera.Sources = camera.Sources[1:]
+		require.NoError(t, store.Reload(&Canyons{{ID: "LCC", Cameras: []Camera{camera}}}))
+
+		entry, _ := store.Get(store.entries[0].ID)
+		require.Len(t, entry.Variants, 1)
+		assert.Equal(t, server.URL+"/medium.jpg", entry.Variants[0].Src)
+	})
+}
@@ -9,19 +9,23 @@ import (
 	"sync"
 	"time"
 
+	"github.com/stefanpenner/lcc-live/web/logger"
 	"github.com/stefanpenner/lcc-live/web/store"
 )
 
 const (
-	baseURL   = "https://www.udottraffic.utah.gov/api/v2"
-	userAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	defaultBaseURL = "https://www.udottraffic.utah.gov/api/v2"
+	// DefaultTimeout bounds each API request, including reading the body
+	DefaultTimeout = 30 * time.Second
 )
 
 // Client provides access to UDOT API endpoints
 type Client struct {
-	apiKey  string
-	client  *http.Client
-	timeout time.Duration
+	baseURL   string
+	apiKey    string
+	userAgent string
+	client    *http.Client
+	timeout   time.Duration
 	// ETags for conditional requests
 	etags   map[string]string // Maps endpoint -> ETag
 	etagsMu sync.RWMutex
@@ -33,13 +37,35 @@ func NewClient(apiKey string) *Client {
 		fmt.Printf("WARNING: UDOT_API_KEY seems too short (%d chars). Expecting ~32 characters.\n", len(apiKey))
 	}
 	return &Client{
-		apiKey:  apiKey,
-		client:  &http.Client{Timeout: 30 * time.Second},
-		timeout: 30 * time.Second,
-		etags:   make(map[string]string),
+		baseURL:   defaultBaseURL,
+		apiKey:    apiKey,
+		userAgent: store.DefaultUserAgent,
+		client:    &http.Client{Timeout: DefaultTimeout},
+		timeout:   DefaultTimeout,
+		etags:     make(map[string]string),
 	}
 }
 
+// SetUserAgent sets the User-Agent sent with API requests
+func (c *Client) SetUserAgent(userAgent string) {
+	c.userAgent = userAgent
+}
+
+// SetBaseURL points the client at a different API host (e.g. a test server)
+func (c *Client) SetBaseURL(baseURL string) {
+	c.baseURL = baseURL
+}
+
+// SetTimeout bounds each API request to d, so one slow endpoint can't stall
+// a poll cycle. Non-positive values are ignored.
+func (c *Client) SetTimeout(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	c.timeout = d
+	c.client.Timeout = d
+}
+
 // IsConfigured returns true if the client has an API key
 func (c *Client) IsConfigured() bool {
 	return c.apiKey != ""
@@ -51,7 +77,7 @@ func (c *Client) FetchRoadConditions(ctx context.Context) ([]store.RoadCondition
 		return nil, fmt.Errorf("UDOT_API_KEY not set")
 	}
 
-	url := fmt.Sprintf("%s/get/roadconditions?key=%s&format=json", baseURL, c.apiKey)
+	url := fmt.Sprintf("%s/get/roadconditions?key=%s&format=json", c.baseURL, c.apiKey)
 	return fetchJSON[store.RoadCondition](ctx, c, url, "roadconditions")
 }
 
@@ -61,7 +87,7 @@ func (c *Client) FetchWeatherStations(ctx context.Context) ([]store.WeatherStati
 		return nil, fmt.Errorf("UDOT_API_KEY not set")
 	}
 
-	url := fmt.Sprintf("%s/get/weatherstations?key=%s&format=json", baseURL, c.apiKey)
+	url := fmt.Sprintf("%s/get/weatherstations?key=%s&format=json", c.baseURL, c.apiKey)
 	return fetchJSON[store.WeatherStation](ctx, c, url, "weatherstations")
 }
 
@@ -71,18 +97,23 @@ func (c *Client) FetchEvents(ctx context.Context) ([]store.Event, error) {
 		return nil, fmt.Errorf("UDOT_API_KEY not set")
 	}
 
-	url := fmt.Sprintf("%s/get/event?key=%s&format=json", baseURL, c.apiKey)
+	url := fmt.Sprintf("%s/get/event?key=%s&format=json", c.baseURL, c.apiKey)
 	return fetchJSON[store.Event](ctx, c, url, "events")
 }
 
 // fetchJSON is a generic helper to fetch and decode JSON from the API
 // It respects ETags and caching headers for conditional requests
 func fetchJSON[T any](ctx context.Context, client *Client, url string, endpoint string) ([]T, error) {
+	// Bound the whole request, body included, while still honoring the
+	// caller's cancellation
+	ctx, cancel := context.WithTimeout(ctx, client.timeout)
+	defer cancel()
+
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
-	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("User-Agent", client.userAgent)
 
 	// Add If-None-Match header if we have a cached ETag
 	client.etagsMu.RLock()
@@ -120,10 +151,53 @@ func fetchJSON[T any](ctx context.Context, client *Client, url string, endpoint
 		client.etagsMu.Unlock()
 	}
 
-	var results []T
-	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+	results, skipped, err := decodeArray[T](resp.Body)
+	if err != nil {
 		return nil, fmt.Errorf("failed to decode JSON: %w", err)
 	}
+	if skipped > 0 {
+		if len(results) == 0 {
+			return nil, fmt.Errorf("failed to decode JSON: all %d %s entries were malformed", skipped, endpoint)
+		}
+		logger.Warn("Skipped %d malformed %s entries from the UDOT API, kept %d", skipped, endpoint, len(results))
+	}
 
 	return results, nil
 }
+
+// decodeArray decodes a JSON array one element at a time, skipping (and
+// counting) elements that don't fit T, so one bad entry doesn't discard the
+// rest. Only malformed JSON, which can't be resynchronized, is an error.
+func decodeArray[T any](r io.Reader) ([]T, int, error) {
+	decoder := json.NewDecoder(r)
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, 0, err
+	}
+	if token == nil {
+		return nil, 0, nil // null
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, 0, fmt.Errorf("expected an array, got %v", token)
+	}
+
+	// Non-nil even when empty: callers read nil as 304 Not Modified
+	results := []T{}
+	skipped := 0
+	for decoder.More() {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, 0, err
+		}
+		var item T
+		if err := json.Unmarshal(raw, &item); err != nil {
+			skipped++
+			continue
+		}
+		results = append(results, item)
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, 0, err
+	}
+	return results, skipped, nil
+}
@@ -0,0 +1,94 @@
+package udot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Send headers promptly, then stall the body
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("["))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	client := NewClient(strings.Repeat("k", 32))
+	client.SetBaseURL(server.URL)
+	client.SetTimeout(100 * time.Millisecond)
+
+	t.Run("client timeout", func(t *testing.T) {
+		start := time.Now()
+		_, err := client.FetchEvents(context.Background())
+		require.Error(t, err)
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
+
+	t.Run("caller context", func(t *testing.T) {
+		client.SetTimeout(time.Minute)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := client.FetchEvents(ctx)
+		require.Error(t, err)
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
+
+	t.Run("ignores non-positive", func(t *testing.T) {
+		client.SetTimeout(0)
+		assert.Equal(t, time.Minute, client.timeout)
+	})
+}
+
+func TestClient_FetchEvents_SkipsMalformedEntries(t *testing.T) {
+	body := `[
+		{"ID": "1", "RoadwayName": "SR-210", "Reported": 1700000000},
+		{"ID": "2", "RoadwayName": "SR-190", "Reported": "yesterday"},
+		"not an event",
+		{"ID": "3", "RoadwayName": "SR-210", "Restrictions": ["chains"]}
+	]`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(strings.Repeat("k", 32))
+	client.SetBaseURL(server.URL)
+
+	events, err := client.FetchEvents(context.Background())
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "1", events[0].ID)
+	assert.Equal(t, int64(1700000000), events[0].Reported)
+	assert.Equal(t, "3", events[1].ID)
+	assert.Equal(t, []string{"chains"}, events[1].Restrictions)
+
+	body = `[{"ID": "1", "Reported": "soon"}, {"ID": "2", "Reported": "later"}]`
+	_, err = client.FetchEvents(context.Background())
+	assert.ErrorContains(t, err, "all 2 events entries were malformed")
+
+	body = `[{"ID": "1"}, {"ID": `
+	_, err = client.FetchEvents(context.Background())
+	assert.Error(t, err, "truncated JSON can't be resynchronized")
+
+	body = `null`
+	events, err = client.FetchEvents(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
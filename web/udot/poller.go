@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/stefanpenner/lcc-live/web/logger"
+	"github.com/stefanpenner/lcc-live/web/metrics"
 	"github.com/stefanpenner/lcc-live/web/store"
 )
 
@@ -93,8 +94,27 @@ func (p *Poller) StartEvents(ctx context.Context) error {
 	}
 }
 
+// recordFetch records the outcome of polling a UDOT endpoint. A nil result
+// without an error is a 304, which still counts as the endpoint being up.
+func recordFetch(endpoint string, start time.Time, unchanged bool, err error) {
+	metrics.UDOTFetchDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+
+	status := "success"
+	if err != nil {
+		status = "error"
+	} else if unchanged {
+		status = "unchanged"
+	}
+	metrics.UDOTFetchTotal.WithLabelValues(endpoint, status).Inc()
+	if err == nil {
+		metrics.UDOTLastSuccessTimestamp.WithLabelValues(endpoint).SetToCurrentTime()
+	}
+}
+
 func (p *Poller) pollRoadConditions(ctx context.Context) {
+	start := time.Now()
 	conditions, err := p.client.FetchRoadConditions(ctx)
+	recordFetch("roadconditions", start, conditions == nil, err)
 	if err != nil {
 		logger.Error(err, "Failed to fetch road conditions: %v", err)
 		return
@@ -113,7 +133,9 @@ func (p *Poller) pollRoadConditions(ctx context.Context) {
 }
 
 func (p *Poller) pollWeatherStations(ctx context.Context) {
+	start := time.Now()
 	stations, err := p.client.FetchWeatherStations(ctx)
+	recordFetch("weatherstations", start, stations == nil, err)
 	if err != nil {
 		logger.Error(err, "Failed to fetch weather stations: %v", err)
 		return
@@ -129,7 +151,9 @@ func (p *Poller) pollWeatherStations(ctx context.Context) {
 }
 
 func (p *Poller) pollEvents(ctx context.Context) {
+	start := time.Now()
 	events, err := p.client.FetchEvents(ctx)
+	recordFetch("events", start, events == nil, err)
 	if err != nil {
 		logger.Error(err, "Failed to fetch events: %v", err)
 		return
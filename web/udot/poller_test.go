@@ -0,0 +1,74 @@
+package udot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stefanpenner/lcc-live/web/metrics"
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPoller(t *testing.T, handler http.HandlerFunc) *Poller {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := NewClient(strings.Repeat("k", 32))
+	client.SetBaseURL(server.URL)
+	s := store.NewStore(&store.Canyons{{ID: "LCC", Name: "LCC"}, {ID: "BCC", Name: "BCC"}})
+	return NewPoller(client, s, time.Minute)
+}
+
+func TestPoller_Metrics(t *testing.T) {
+	fail := false
+	p := newTestPoller(t, func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`[]`))
+	})
+	ctx := context.Background()
+
+	count := func(endpoint, status string) float64 {
+		return testutil.ToFloat64(metrics.UDOTFetchTotal.WithLabelValues(endpoint, status))
+	}
+	polls := map[string]func(context.Context){
+		"roadconditions":  p.pollRoadConditions,
+		"weatherstations": p.pollWeatherStations,
+		"events":          p.pollEvents,
+	}
+
+	for endpoint, poll := range polls {
+		t.Run(endpoint, func(t *testing.T) {
+			success, unchanged, errors := count(endpoint, "success"), count(endpoint, "unchanged"), count(endpoint, "error")
+
+			poll(ctx)
+			assert.Equal(t, success+1, count(endpoint, "success"))
+			lastSuccess := testutil.ToFloat64(metrics.UDOTLastSuccessTimestamp.WithLabelValues(endpoint))
+			assert.InDelta(t, float64(time.Now().Unix()), lastSuccess, 5)
+			assert.Positive(t, testutil.CollectAndCount(metrics.UDOTFetchDuration, "lcc_udot_fetch_duration_seconds"))
+
+			// The client now has an ETag, so the server answers 304
+			poll(ctx)
+			assert.Equal(t, unchanged+1, count(endpoint, "unchanged"))
+			assert.Equal(t, success+1, count(endpoint, "success"))
+
+			fail = true
+			defer func() { fail = false }()
+			poll(ctx)
+			assert.Equal(t, errors+1, count(endpoint, "error"))
+		})
+	}
+}
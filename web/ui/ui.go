@@ -57,9 +57,43 @@ type Stats struct {
 	GoroutineCount  int
 }
 
+// logRing keeps the most recent log lines in a fixed-size buffer, so memory
+// stays bounded and eviction doesn't copy the whole history
+type logRing struct {
+	lines []string
+	next  int
+	count int
+}
+
+func newLogRing(capacity int) *logRing {
+	return &logRing{lines: make([]string, capacity)}
+}
+
+// push appends a line, overwriting the oldest once the ring is full
+func (r *logRing) push(line string) {
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % len(r.lines)
+	if r.count < len(r.lines) {
+		r.count++
+	}
+}
+
+// len returns how many lines are retained
+func (r *logRing) len() int {
+	return r.count
+}
+
+// each calls fn with every retained line, oldest first
+func (r *logRing) each(fn func(i int, line string)) {
+	start := (r.next - r.count + len(r.lines)) % len(r.lines)
+	for i := 0; i < r.count; i++ {
+		fn(i, r.lines[(start+i)%len(r.lines)])
+	}
+}
+
 type model struct {
 	viewport  viewport.Model
-	logs      []string
+	logs      *logRing
 	stats     Stats
 	version   string
 	port      string
@@ -102,7 +136,7 @@ func Initialize(version, _ /* buildTime */, port string, _ /* syncInterval */ ti
 		port:      port,
 		startTime: time.Now(),
 		stats:     Stats{Cameras: cameras},
-		logs:      make([]string, 0, maxLogs),
+		logs:      newLogRing(maxLogs),
 	}
 
 	program = tea.NewProgram(globalModel, tea.WithAltScreen())
@@ -214,7 +248,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.viewport.Height = viewportHeight
 		}
 
-		if len(m.logs) > 0 && m.ready && m.viewport.Height > 0 {
+		if m.logs.len() > 0 && m.ready && m.viewport.Height > 0 {
 			m.viewport.SetContent(m.buildLogContent())
 			if m.viewport.TotalLineCount() > 0 {
 				m.viewport.GotoBottom()
@@ -222,11 +256,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case logMsg:
-		m.logs = append(m.logs, msg.msg)
-		if len(m.logs) > maxLogs {
-			copy(m.logs, m.logs[len(m.logs)-maxLogs:])
-			m.logs = m.logs[:maxLogs]
-		}
+		m.logs.push(msg.msg)
 		if m.ready && m.viewport.Height > 0 {
 			m.viewport.SetContent(m.buildLogContent())
 			if m.viewport.TotalLineCount() > 0 {
@@ -262,18 +292,18 @@ func (m *model) View() string {
 }
 
 func (m *model) buildLogContent() string {
-	if len(m.logs) == 0 {
+	if m.logs.len() == 0 {
 		return ""
 	}
 
 	var b strings.Builder
-	b.Grow(len(m.logs) * avgLogChars)
-	for i, log := range m.logs {
+	b.Grow(m.logs.len() * avgLogChars)
+	m.logs.each(func(i int, log string) {
 		if i > 0 {
 			b.WriteByte('\n')
 		}
 		b.WriteString(log)
-	}
+	})
 	return b.String()
 }
 
@@ -339,7 +369,7 @@ func (m *model) renderPerfMetrics() string {
 
 func (m *model) renderFooter() string {
 	scrollPos := ""
-	if len(m.logs) > 0 && m.viewport.TotalLineCount() > m.viewport.Height {
+	if m.logs.len() > 0 && m.viewport.TotalLineCount() > m.viewport.Height {
 		pct := int(float64(m.viewport.YOffset) / float64(m.viewport.TotalLineCount()-m.viewport.Height) * 100)
 		if pct > 100 {
 			pct = 100
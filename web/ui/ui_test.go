@@ -0,0 +1,35 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModel_LogBufferIsBounded(t *testing.T) {
+	m := &model{logs: newLogRing(maxLogs)}
+	const total = maxLogs*3 + 7
+	for i := range total {
+		m.Update(logMsg{fmt.Sprintf("line %d", i)})
+	}
+
+	assert.Equal(t, maxLogs, m.logs.len())
+	assert.Equal(t, maxLogs, cap(m.logs.lines), "the buffer never grows past maxLogs")
+
+	lines := strings.Split(m.buildLogContent(), "\n")
+	assert.Len(t, lines, maxLogs)
+	assert.Equal(t, fmt.Sprintf("line %d", total-maxLogs), lines[0], "the oldest retained line")
+	assert.Equal(t, fmt.Sprintf("line %d", total-1), lines[maxLogs-1], "the newest line")
+}
+
+func TestLogRing_PartiallyFull(t *testing.T) {
+	r := newLogRing(4)
+	r.push("a")
+	r.push("b")
+
+	var lines []string
+	r.each(func(_ int, line string) { lines = append(lines, line) })
+	assert.Equal(t, []string{"a", "b"}, lines)
+}
@@ -0,0 +1,149 @@
+// Package webhook notifies an external endpoint when cameras go down or recover
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/stefanpenner/lcc-live/web/logger"
+	"github.com/stefanpenner/lcc-live/web/store"
+)
+
+// deliveryTimeout bounds each webhook POST so a slow receiver can't pile up
+// goroutines
+const deliveryTimeout = 5 * time.Second
+
+// State is a camera's availability
+type State string
+
+const (
+	StateUp   State = "up"
+	StateDown State = "down"
+)
+
+// Payload is the JSON body POSTed for each transition
+type Payload struct {
+	CameraID      string    `json:"camera_id"`
+	CameraName    string    `json:"camera_name"`
+	Canyon        string    `json:"canyon"`
+	State         State     `json:"state"`
+	PreviousState State     `json:"previous_state"`
+	Since         time.Time `json:"since"` // when the camera entered State
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// cameraState tracks the last reported state and any pending transition
+type cameraState struct {
+	reported     State
+	pending      State
+	pendingSince time.Time
+}
+
+// Notifier watches sync results and POSTs a Payload to a URL whenever a
+// camera's availability changes and stays changed for at least MinDuration
+type Notifier struct {
+	url         string
+	minDuration time.Duration
+	client      *http.Client
+	now         func() time.Time
+
+	mu      sync.Mutex
+	cameras map[string]*cameraState
+	wg      sync.WaitGroup
+}
+
+// NewNotifier creates a notifier posting to url. Transitions must persist for
+// minDuration before they fire, which debounces flapping cameras.
+func NewNotifier(url string, minDuration time.Duration) *Notifier {
+	return &Notifier{
+		url:         url,
+		minDuration: minDuration,
+		client:      &http.Client{Timeout: deliveryTimeout},
+		now:         time.Now,
+		cameras:     make(map[string]*cameraState),
+	}
+}
+
+// Observe records a sync cycle, delivering webhooks for any transitions.
+// It's meant to be registered with store.OnSync and never blocks on delivery.
+func (n *Notifier) Observe(result store.SyncResult) {
+	now := n.now()
+
+	n.mu.Lock()
+	var payloads []Payload
+	for _, camera := range result.Cameras {
+		var observed State
+		switch camera.Status {
+		case store.SyncChanged, store.SyncUnchanged:
+			observed = StateUp
+		case store.SyncError:
+			observed = StateDown
+		default:
+			continue // cancelled fetches say nothing about the camera
+		}
+
+		state, exists := n.cameras[camera.ID]
+		if !exists {
+			// First observation establishes a baseline without firing
+			n.cameras[camera.ID] = &cameraState{reported: observed, pending: observed, pendingSince: now}
+			continue
+		}
+
+		if observed != state.pending {
+			state.pending = observed
+			state.pendingSince = now
+		}
+		if state.pending == state.reported || now.Sub(state.pendingSince) < n.minDuration {
+			continue
+		}
+
+		payloads = append(payloads, Payload{
+			CameraID:      camera.ID,
+			CameraName:    camera.Name,
+			Canyon:        camera.Canyon,
+			State:         state.pending,
+			PreviousState: state.reported,
+			Since:         state.pendingSince,
+			Timestamp:     now,
+		})
+		state.reported = state.pending
+	}
+	n.mu.Unlock()
+
+	for _, payload := range payloads {
+		n.wg.Add(1)
+		go func(payload Payload) {
+			defer n.wg.Done()
+			if err := n.deliver(payload); err != nil {
+				logger.Error(err, "Webhook delivery failed for %s: %v", payload.CameraName, err)
+			}
+		}(payload)
+	}
+}
+
+// Wait blocks until all in-flight deliveries have finished
+func (n *Notifier) Wait() {
+	n.wg.Wait()
+}
+
+func (n *Notifier) deliver(payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stefanpenner/lcc-live/web/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type receiver struct {
+	mu       sync.Mutex
+	payloads []Payload
+}
+
+func (r *receiver) received() []Payload {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Payload(nil), r.payloads...)
+}
+
+func newReceiver(t *testing.T) (*receiver, *httptest.Server) {
+	r := &receiver{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		var payload Payload
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+		r.mu.Lock()
+		r.payloads = append(r.payloads, payload)
+		r.mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+	return r, server
+}
+
+func syncResult(status store.SyncStatus) store.SyncResult {
+	return store.SyncResult{Cameras: []store.CameraSyncResult{
+		{ID: "cam1", Name: "Camera 1", Canyon: "LCC", Status: status},
+	}}
+}
+
+func TestNotifier_Transitions(t *testing.T) {
+	r, server := newReceiver(t)
+	n := NewNotifier(server.URL, 0)
+
+	// Steady state never fires
+	n.Observe(syncResult(store.SyncChanged))
+	n.Observe(syncResult(store.SyncUnchanged))
+	n.Wait()
+	assert.Empty(t, r.received())
+
+	// up -> down
+	n.Observe(syncResult(store.SyncError))
+	n.Observe(syncResult(store.SyncError))
+	n.Wait()
+	require.Len(t, r.received(), 1)
+	assert.Equal(t, "cam1", r.received()[0].CameraID)
+	assert.Equal(t, "Camera 1", r.received()[0].CameraName)
+	assert.Equal(t, StateDown, r.received()[0].State)
+	assert.Equal(t, StateUp, r.received()[0].PreviousState)
+
+	// Cancelled fetches are ignored; down -> up
+	n.Observe(syncResult(store.SyncCancelled))
+	n.Observe(syncResult(store.SyncChanged))
+	n.Wait()
+	require.Len(t, r.received(), 2)
+	assert.Equal(t, StateUp, r.received()[1].State)
+	assert.Equal(t, StateDown, r.received()[1].PreviousState)
+}
+
+func TestNotifier_Debounce(t *testing.T) {
+	r, server := newReceiver(t)
+	n := NewNotifier(server.URL, time.Minute)
+	now := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	n.now = func() time.Time { return now }
+
+	n.Observe(syncResult(store.SyncChanged))
+
+	// A brief blip doesn't fire
+	now = now.Add(10 * time.Second)
+	n.Observe(syncResult(store.SyncError))
+	now = now.Add(10 * time.Second)
+	n.Observe(syncResult(store.SyncChanged))
+	n.Wait()
+	assert.Empty(t, r.received())
+
+	// Staying down for the minimum duration does
+	now = now.Add(10 * time.Second)
+	downSince := now
+	n.Observe(syncResult(store.SyncError))
+	now = now.Add(30 * time.Second)
+	n.Observe(syncResult(store.SyncError))
+	n.Wait()
+	assert.Empty(t, r.received())
+
+	now = now.Add(30 * time.Second)
+	n.Observe(syncResult(store.SyncError))
+	n.Wait()
+	require.Len(t, r.received(), 1)
+	assert.Equal(t, StateDown, r.received()[0].State)
+	assert.True(t, downSince.Equal(r.received()[0].Since))
+}